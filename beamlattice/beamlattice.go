@@ -4,6 +4,7 @@
 package beamlattice
 
 import (
+	"encoding/xml"
 	"errors"
 
 	"github.com/hpinc/go3mf"
@@ -98,6 +99,11 @@ type BeamLattice struct {
 	CapMode              CapMode
 }
 
+// XMLName returns the xml identifier of the element.
+func (BeamLattice) XMLName() xml.Name {
+	return xml.Name{Space: Namespace, Local: attrBeamLattice}
+}
+
 type Beams struct {
 	Beam []Beam
 }
@@ -0,0 +1,58 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package beamlattice
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hpinc/go3mf"
+	"github.com/hpinc/go3mf/spec"
+)
+
+func TestOptimize(t *testing.T) {
+	newTetra := func(id uint32) *go3mf.Object {
+		return &go3mf.Object{ID: id, Mesh: &go3mf.Mesh{
+			Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+			Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+				{V1: 0, V2: 2, V3: 1}, {V1: 0, V2: 1, V3: 3}, {V1: 0, V2: 3, V3: 2}, {V1: 1, V2: 2, V3: 3},
+			}},
+		}}
+	}
+	// Object 2 is identical to object 1 but it is referenced by the lattice.
+	m := &go3mf.Model{
+		Extensions: []go3mf.Extension{DefaultExtension},
+		Resources: go3mf.Resources{Objects: []*go3mf.Object{
+			newTetra(1),
+			newTetra(2),
+			{ID: 3, Mesh: &go3mf.Mesh{
+				Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+				Any: spec.Any{&BeamLattice{
+					ClipMode: ClipInside, ClippingMeshID: 2, RepresentationMeshID: 2, MinLength: 0.1, Radius: 1,
+					Beams: Beams{Beam: []Beam{{Indices: [2]uint32{0, 1}}, {Indices: [2]uint32{1, 2}}}},
+				}},
+			}},
+		}},
+		Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 1}, {ObjectID: 3}}},
+	}
+	var in bytes.Buffer
+	if err := go3mf.NewEncoder(&in).Encode(m); err != nil {
+		t.Fatalf("go3mf.Encoder.Encode() error = %v", err)
+	}
+	var out bytes.Buffer
+	got, err := go3mf.Optimize(bytes.NewReader(in.Bytes()), int64(in.Len()), &out, go3mf.OptimizeOptions{})
+	if err != nil {
+		t.Fatalf("go3mf.Optimize() error = %v", err)
+	}
+	if got.OutputObjects != 3 {
+		t.Errorf("go3mf.Optimize() objects = %d, want 3", got.OutputObjects)
+	}
+	var dec go3mf.Model
+	if err := go3mf.NewDecoder(bytes.NewReader(out.Bytes()), int64(out.Len())).Decode(&dec); err != nil {
+		t.Fatalf("go3mf.Decoder.Decode() error = %v", err)
+	}
+	if err := dec.Validate(); err != nil {
+		t.Errorf("go3mf.Model.Validate() error = %v", err)
+	}
+}
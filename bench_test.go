@@ -5,6 +5,7 @@ package go3mf
 
 import (
 	"fmt"
+	"io/ioutil"
 	"strings"
 	"testing"
 )
@@ -25,6 +26,47 @@ func BenchmarkMarshalModel(b *testing.B) {
 	}
 }
 
+func BenchmarkEncodeLargeMesh(b *testing.B) {
+	m := &Model{Resources: Resources{Objects: []*Object{{ID: 1, PID: 2, Mesh: largeMesh(500000)}}}}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := NewEncoder(nil).writeModel(newXMLEncoder(ioutil.Discard, defaultFloatPrecision), m); err != nil {
+			b.Errorf("writeModel err = %v", err)
+		}
+	}
+}
+
+func BenchmarkMesh_Faces(b *testing.B) {
+	m := largeMesh(500000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sum float32
+		m.Faces(func(_ uint32, v1, v2, v3 Point3D) bool {
+			sum += v1.X() + v2.Y() + v3.Z()
+			return true
+		})
+	}
+}
+
+func BenchmarkEncoder_Encode_ChildModels(b *testing.B) {
+	part := new(Model)
+	if err := UnmarshalModel([]byte(benchModel(1000)), part); err != nil {
+		b.Errorf("Encode err = %v", err)
+	}
+	m := &Model{Childs: make(map[string]*ChildModel, 50)}
+	for i := 0; i < 50; i++ {
+		m.Childs[fmt.Sprintf("/3D/part%d.model", i)] = &ChildModel{Resources: part.Resources}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := NewEncoder(ioutil.Discard).Encode(m); err != nil {
+			b.Errorf("Encode err = %v", err)
+		}
+	}
+}
+
 func BenchmarkUnmarshalModel(b *testing.B) {
 	bt := []byte(benchModel(1000))
 	b.ResetTimer()
@@ -53,6 +95,27 @@ func BenchmarkModel_Validate(b *testing.B) {
 	}
 }
 
+// largeMesh returns a mesh with n triangles mixing
+// the three triangle attribute layouts.
+func largeMesh(n int) *Mesh {
+	mesh := &Mesh{
+		Vertices:  Vertices{Vertex: make([]Point3D, n+2)},
+		Triangles: Triangles{Triangle: make([]Triangle, n)},
+	}
+	for i := range mesh.Triangles.Triangle {
+		v := uint32(i)
+		t := Triangle{V1: v, V2: v + 1, V3: v + 2}
+		switch i % 3 {
+		case 1:
+			t.PID, t.P1, t.P2, t.P3 = 2, v%7, v%7, v%7
+		case 2:
+			t.PID, t.P1, t.P2, t.P3 = 2, 0, 1, v%5
+		}
+		mesh.Triangles.Triangle[i] = t
+	}
+	return mesh
+}
+
 func benchModel(n int) string {
 	vertex := []byte(`<vertex x="100.000" y="100.000" z="100.000"/>`)
 	triangle := []byte(`<triangle v1="0" v2="1" v3="2" pid="1" p1="1" p2="1" p3="1"/>`)
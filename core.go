@@ -4,18 +4,30 @@
 package go3mf
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
+	"fmt"
 	"image/color"
 	"io"
+	"io/ioutil"
+	"math"
 	"sort"
+	"strings"
 	"sync"
 
+	specerr "github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 )
 
 const (
 	// Namespace is the canonical name of this extension.
 	Namespace = "http://schemas.microsoft.com/3dmanufacturing/core/2015/02"
+	// NamespaceDraft is the core namespace of the 0.93 draft spec,
+	// still written by some old tools. Every 1.x revision uses Namespace.
+	// It is accepted when decoding and treated as Namespace.
+	NamespaceDraft = "http://schemas.microsoft.com/3dmanufacturing/2013/01"
 
 	// RelType3DModel is the canonical 3D model relationship type.
 	RelType3DModel = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"
@@ -23,6 +35,8 @@ const (
 	RelTypeThumbnail = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail"
 	// RelTypePrintTicket is the canonical print ticket relationship type.
 	RelTypePrintTicket = "http://schemas.microsoft.com/3dmanufacturing/2013/01/printticket"
+	// RelTypeTexture3D is the canonical 3D texture relationship type.
+	RelTypeTexture3D = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dtexture"
 	// RelTypeMustPreserve is the canonical must preserve relationship type.
 	RelTypeMustPreserve = "http://schemas.openxmlformats.org/package/2006/relationships/mustpreserve"
 
@@ -37,6 +51,9 @@ const (
 	// DefaultMetadataDir is the recommended directory for standard metadata.
 	DefaultMetadataDir = "/Metadata/"
 
+	// ContentType3MF is the media type of a 3MF package,
+	// suitable for the Content-Type header when serving it over HTTP.
+	ContentType3MF = "model/3mf"
 	// ContentType3DModel is the 3D model content type.
 	ContentType3DModel = "application/vnd.ms-package.3dmanufacturing-3dmodel+xml"
 	// ContentTypePrintTicket is the print ticket content type.
@@ -56,6 +73,26 @@ const (
 	UnitMeter
 )
 
+// ConversionFactor returns the factor that converts
+// a length expressed in u to the units to.
+// It returns 1 if any of the units is not supported.
+func (u Units) ConversionFactor(to Units) float32 {
+	meters := map[Units]float64{
+		UnitMillimeter: 0.001,
+		UnitMicrometer: 0.000001,
+		UnitCentimeter: 0.01,
+		UnitInch:       0.0254,
+		UnitFoot:       0.3048,
+		UnitMeter:      1,
+	}
+	from, ok1 := meters[u]
+	dst, ok2 := meters[to]
+	if !ok1 || !ok2 {
+		return 1
+	}
+	return float32(from / dst)
+}
+
 func (u Units) String() string {
 	return map[Units]string{
 		UnitMillimeter: "millimeter",
@@ -105,10 +142,16 @@ type Metadata struct {
 }
 
 // Attachment defines the Model Attachment.
+//
+// Relationships contains the OPC relationships whose source is the attachment part.
+// When decoding, only the attachment own relationships are read, the target parts
+// are not traversed and are only available as attachments if another
+// model part or the package itself also references them.
 type Attachment struct {
-	Stream      io.Reader
-	Path        string
-	ContentType string
+	Stream        io.Reader
+	Path          string
+	ContentType   string
+	Relationships []Relationship
 }
 
 // Relationship defines a dependency between
@@ -122,6 +165,9 @@ type Relationship struct {
 }
 
 // Build contains one or more items to manufacture as part of processing the job.
+//
+// A build without items is encoded as an empty build element and passes validation,
+// which is useful for packages that only act as resource libraries.
 type Build struct {
 	Items   []*Item
 	AnyAttr spec.AnyAttr
@@ -129,6 +175,10 @@ type Build struct {
 
 // The Resources element acts as the root element of a library of constituent
 // pieces of the overall 3D object definition.
+//
+// The decoder keeps Assets and Objects in document order and the encoder
+// writes them in slice order, assets before objects, so the relative order
+// of each slice survives a round-trip. Nothing in this package sorts them.
 type Resources struct {
 	Assets  []Asset
 	Objects []*Object
@@ -183,6 +233,111 @@ func (rs *Resources) FindAsset(id uint32) (Asset, bool) {
 	return nil, false
 }
 
+// AddBaseMaterials appends a new BaseMaterials asset with
+// an unused ID and the given materials.
+// The returned pointer can be used to reference the new asset.
+func (rs *Resources) AddBaseMaterials(materials ...Base) *BaseMaterials {
+	r := &BaseMaterials{ID: rs.UnusedID(), Materials: materials}
+	rs.Assets = append(rs.Assets, r)
+	return r
+}
+
+// AssetReferencer is implemented by the assets that reference
+// other assets of the same model part, such as a texture group
+// referencing its texture.
+type AssetReferencer interface {
+	References() []uint32
+}
+
+// TopologicalOrder returns the assets and objects sorted so every resource
+// appears after the resources it references: the property groups of
+// objects and triangles, the objects of components in the same model part
+// and the assets returned by AssetReferencer.
+// The elements are either an Asset or an *Object.
+//
+// The declaration order is kept as long as it does not break a dependency,
+// assets being visited before objects.
+// References to missing resources or to other model parts are ignored.
+// An error wrapping errors.ErrRecursion is returned if there is a cycle.
+func (rs *Resources) TopologicalOrder() ([]interface{}, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	byID := make(map[uint32]interface{}, len(rs.Assets)+len(rs.Objects))
+	for _, a := range rs.Assets {
+		byID[a.Identify()] = a
+	}
+	for _, o := range rs.Objects {
+		byID[o.ID] = o
+	}
+	state := make(map[uint32]int, len(byID))
+	order := make([]interface{}, 0, len(byID))
+	var visit func(id uint32, r interface{}) error
+	visit = func(id uint32, r interface{}) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("%w: resource %d", specerr.ErrRecursion, id)
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		for _, ref := range resourceReferences(r) {
+			if dep, ok := byID[ref]; ok && ref != 0 {
+				if err := visit(ref, dep); err != nil {
+					return err
+				}
+			}
+		}
+		state[id] = done
+		order = append(order, r)
+		return nil
+	}
+	for _, a := range rs.Assets {
+		if err := visit(a.Identify(), a); err != nil {
+			return nil, err
+		}
+	}
+	for _, o := range rs.Objects {
+		if err := visit(o.ID, o); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func resourceReferences(r interface{}) []uint32 {
+	o, ok := r.(*Object)
+	if !ok {
+		if a, ok := r.(AssetReferencer); ok {
+			return a.References()
+		}
+		return nil
+	}
+	var refs []uint32
+	if o.PID != 0 {
+		refs = append(refs, o.PID)
+	}
+	if o.Mesh != nil {
+		for _, t := range o.Mesh.Triangles.Triangle {
+			if t.PID != 0 && (len(refs) == 0 || refs[len(refs)-1] != t.PID) {
+				refs = append(refs, t.PID)
+			}
+		}
+	}
+	if o.Components != nil {
+		for _, c := range o.Components.Component {
+			if c.ObjectPath("") == "" {
+				refs = append(refs, c.ObjectID)
+			}
+		}
+	}
+	return refs
+}
+
+// Extension defines a spec declared by the model.
+// The LocalName of the required extensions is read from and
+// written to the model requiredextensions attribute.
 type Extension struct {
 	Namespace  string
 	LocalName  string
@@ -203,13 +358,28 @@ type ChildModel struct {
 
 // A Model is an in memory representation of the 3MF file.
 //
+// Path is the root model part name. The decoder sets it from the package
+// root relationship and it can be modified before encoding, in which case the
+// root relationship and the part content type are written for the new name.
 // If path is empty, the default path '/3D/3dmodel.model' will be used.
 // The relationships are usually managed by the extensions themself,
 // but they are usefull to reference custom attachments.
 // Childs keys cannot be an empty string.
 // RootRelationships are the OPC root relationships.
+// CoreNamespace is the core namespace found in the root model part
+// when decoding, empty meaning Namespace. It is ignored when encoding,
+// which always uses Namespace.
+// The Extensions namespaces are declared after the core one,
+// sorted by prefix, regardless of their order in the slice.
+//
+// The lookup and walk methods, such as FindResources, FindObject, FindAsset,
+// WalkObjects, WalkAssets, Instances and BoundingBox, never modify the model,
+// so they can be called concurrently as long as no goroutine modifies it.
+// ThumbnailData is also safe if the attachment streams are *bytes.Buffer,
+// as set by the Decoder, else it has to read them.
 type Model struct {
 	Path              string
+	CoreNamespace     string
 	Language          string
 	Units             Units
 	Thumbnail         string
@@ -233,6 +403,18 @@ func (m *Model) PathOrDefault() string {
 	return m.Path
 }
 
+// IsMultiPart returns true if the model has child model parts,
+// as production packages splitting the geometry across several parts do.
+func (m *Model) IsMultiPart() bool {
+	return len(m.Childs) > 0
+}
+
+// PartPaths returns the path of the root model part
+// followed by the child model paths in lexical order.
+func (m *Model) PartPaths() []string {
+	return append([]string{m.PathOrDefault()}, m.sortedChilds()...)
+}
+
 // BoundingBox returns the bounding box of the model.
 func (m *Model) BoundingBox() Box {
 	if len(m.Build.Items) == 0 {
@@ -260,6 +442,358 @@ func (m *Model) BoundingBox() Box {
 	return box
 }
 
+// Overlaps returns the pairs of build item indices whose world space
+// axis aligned bounding boxes intersect. Items whose object cannot be
+// resolved or has no geometry are ignored.
+//
+// The check is conservative: the boxes of rotated parts can intersect
+// even if the parts themselves do not overlap.
+func (b *Build) Overlaps(m *Model) [][2]int {
+	boxes := make([]Box, len(b.Items))
+	valid := make([]bool, len(b.Items))
+	for i, item := range b.Items {
+		box := item.BoundingBox(m)
+		if box != emptyBox && box != newLimitBox() {
+			boxes[i], valid[i] = item.Transform.MulBox(box), true
+		}
+	}
+	var pairs [][2]int
+	for i := range boxes {
+		if !valid[i] {
+			continue
+		}
+		for j := i + 1; j < len(boxes); j++ {
+			if valid[j] && boxes[i].intersects(boxes[j]) {
+				pairs = append(pairs, [2]int{i, j})
+			}
+		}
+	}
+	return pairs
+}
+
+// An Instance is a mesh object placed in the build with its
+// world transform, as resolved by Model.Instances.
+type Instance struct {
+	Object    *Object
+	Transform Matrix
+	Item      *Item
+}
+
+type instanceKey struct {
+	path string
+	id   uint32
+}
+
+// Instances returns the flattened list of mesh objects placed in the build.
+// Components are expanded and their transforms are composed with the
+// build item transform, so every Instance references a mesh object.
+// Each referenced object is resolved only once, regardless of how many
+// build items or components reference it.
+//
+// Unresolved references and recursive components are skipped.
+func (m *Model) Instances() []Instance {
+	cache := make(map[instanceKey][]Instance)
+	var instances []Instance
+	for _, item := range m.Build.Items {
+		transform := item.WorldTransform()
+		for _, leaf := range m.leafInstances(cache, nil, item.ObjectPath(), item.ObjectID) {
+			instances = append(instances, Instance{
+				Object:    leaf.Object,
+				Transform: transform.Mul(leaf.Transform),
+				Item:      item,
+			})
+		}
+	}
+	return instances
+}
+
+// TotalVolume returns the volume enclosed by all the meshes placed in the build,
+// counting each Instance once, in the model units cubed.
+//
+// The volume of each mesh is computed from its triangles assuming it is closed,
+// manifold and consistently oriented, otherwise the result is meaningless.
+// It is scaled by the absolute determinant of the instance transform, which is
+// exact for any affine transform: a non-uniform scale multiplies the volume by
+// the product of its factors and a mirroring does not change it.
+// All the mesh objects are included regardless of their type.
+func (m *Model) TotalVolume() float64 {
+	volumes := make(map[*Mesh]float64)
+	var total float64
+	for _, inst := range m.Instances() {
+		mesh := inst.Object.Mesh
+		v, ok := volumes[mesh]
+		if !ok {
+			v = mesh.Volume()
+			volumes[mesh] = v
+		}
+		total += math.Abs(v * inst.Transform.determinant())
+	}
+	return total
+}
+
+// leafInstances returns the mesh objects referenced by an object
+// with their transforms relative to it.
+func (m *Model) leafInstances(cache map[instanceKey][]Instance, visiting []instanceKey, path string, id uint32) []Instance {
+	if path == "" {
+		path = m.PathOrDefault()
+	}
+	key := instanceKey{path, id}
+	if leaves, ok := cache[key]; ok {
+		return leaves
+	}
+	for _, k := range visiting {
+		if k == key {
+			return nil
+		}
+	}
+	o, ok := m.FindObject(path, id)
+	if !ok {
+		return nil
+	}
+	var leaves []Instance
+	if o.Mesh != nil {
+		leaves = []Instance{{Object: o, Transform: Identity()}}
+	} else if o.Components != nil {
+		visiting = append(visiting, key)
+		for _, c := range o.Components.Component {
+			transform := c.Transform
+			if transform == (Matrix{}) {
+				transform = Identity()
+			}
+			for _, leaf := range m.leafInstances(cache, visiting, c.ObjectPath(path), c.ObjectID) {
+				leaves = append(leaves, Instance{Object: leaf.Object, Transform: transform.Mul(leaf.Transform)})
+			}
+		}
+	}
+	cache[key] = leaves
+	return leaves
+}
+
+// A BuildNode is an object of the build hierarchy, as returned by Model.BuildTree.
+// Transform is relative to the parent node, or to the build for the root nodes,
+// and is never the zero matrix.
+type BuildNode struct {
+	ObjectID  uint32
+	Path      string
+	Name      string
+	Type      ObjectType
+	Transform Matrix
+	Children  []BuildNode
+}
+
+// BuildTree returns one node per build item with the components of the
+// referenced objects expanded recursively, without inspecting any mesh.
+//
+// Unresolved references produce a node with only the ObjectID, Path and Transform set,
+// and a component that references one of its ancestors produces a node without children.
+func (m *Model) BuildTree() []BuildNode {
+	nodes := make([]BuildNode, len(m.Build.Items))
+	for i, item := range m.Build.Items {
+		path := item.ObjectPath()
+		if path == "" {
+			path = m.PathOrDefault()
+		}
+		nodes[i] = m.buildNode(nil, path, item.ObjectID, item.WorldTransform())
+	}
+	return nodes
+}
+
+func (m *Model) buildNode(visiting []instanceKey, path string, id uint32, transform Matrix) BuildNode {
+	node := BuildNode{ObjectID: id, Path: path, Transform: transform}
+	o, ok := m.FindObject(path, id)
+	if !ok {
+		return node
+	}
+	node.Name, node.Type = o.Name, o.Type
+	key := instanceKey{path, id}
+	for _, k := range visiting {
+		if k == key {
+			return node
+		}
+	}
+	if o.Components != nil && len(o.Components.Component) > 0 {
+		visiting = append(visiting, key)
+		node.Children = make([]BuildNode, len(o.Components.Component))
+		for i, c := range o.Components.Component {
+			transform := c.Transform
+			if transform == (Matrix{}) {
+				transform = Identity()
+			}
+			node.Children[i] = m.buildNode(visiting, c.ObjectPath(path), c.ObjectID, transform)
+		}
+	}
+	return node
+}
+
+// checkComponentDepth walks the component graph of all the objects
+// and fails if any of them nests components deeper than limit
+// or if there is a recursive reference.
+// It uses an explicit stack so arbitrarily deep graphs cannot exhaust the goroutine stack.
+func (m *Model) checkComponentDepth(limit int) error {
+	const visiting = -1
+	type frame struct {
+		key  instanceKey
+		obj  *Object
+		next int
+	}
+	depths := make(map[instanceKey]int)
+	return m.WalkObjects(func(path string, o *Object) error {
+		if path == "" {
+			path = m.PathOrDefault()
+		}
+		key := instanceKey{path, o.ID}
+		if _, ok := depths[key]; ok {
+			return nil
+		}
+		depths[key] = visiting
+		stack := []frame{{key: key, obj: o}}
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.obj.Components != nil && f.next < len(f.obj.Components.Component) {
+				c := f.obj.Components.Component[f.next]
+				f.next++
+				ckey := instanceKey{c.ObjectPath(f.key.path), c.ObjectID}
+				if d, ok := depths[ckey]; ok {
+					if d == visiting {
+						return fmt.Errorf("%w: object %d in %s", specerr.ErrRecursion, f.key.id, f.key.path)
+					}
+					continue
+				}
+				cobj, ok := m.FindObject(ckey.path, ckey.id)
+				if !ok {
+					continue
+				}
+				depths[ckey] = visiting
+				stack = append(stack, frame{key: ckey, obj: cobj})
+				if len(stack)-1 > limit {
+					return fmt.Errorf("%w: object %d in %s", ErrMaxComponentDepth, key.id, key.path)
+				}
+				continue
+			}
+			var depth int
+			if f.obj.Components != nil && len(f.obj.Components.Component) > 0 {
+				depth = 1
+				for _, c := range f.obj.Components.Component {
+					if d := depths[instanceKey{c.ObjectPath(f.key.path), c.ObjectID}]; d+1 > depth {
+						depth = d + 1
+					}
+				}
+			}
+			if depth > limit {
+				return fmt.Errorf("%w: object %d in %s", ErrMaxComponentDepth, f.key.id, f.key.path)
+			}
+			depths[f.key] = depth
+			stack = stack[:len(stack)-1]
+		}
+		return nil
+	})
+}
+
+// ComponentWorldTransform returns the world transform of the root model object
+// objectID at its first placement in the build, searching the build items in order
+// and then their components depth first.
+// Transforms are composed from the build item down to the object, so a point p
+// of the object is placed at item.Transform.Mul(c1.Transform).Mul(c2.Transform).Mul3D(p).
+// An unset transform is treated as the identity.
+//
+// The identity is returned if the object is not reachable from the build.
+func (m *Model) ComponentWorldTransform(objectID uint32) Matrix {
+	target := instanceKey{m.PathOrDefault(), objectID}
+	seen := make(map[instanceKey]struct{})
+	for _, item := range m.Build.Items {
+		if transform, ok := m.placement(seen, target, item.ObjectPath(), item.ObjectID, item.WorldTransform()); ok {
+			return transform
+		}
+	}
+	return Identity()
+}
+
+// placement searches target starting from the object id
+// placed with transform.
+func (m *Model) placement(seen map[instanceKey]struct{}, target instanceKey, path string, id uint32, transform Matrix) (Matrix, bool) {
+	if path == "" {
+		path = m.PathOrDefault()
+	}
+	key := instanceKey{path, id}
+	if key == target {
+		return transform, true
+	}
+	if _, ok := seen[key]; ok {
+		return Matrix{}, false
+	}
+	seen[key] = struct{}{}
+	o, ok := m.FindObject(path, id)
+	if !ok || o.Components == nil {
+		return Matrix{}, false
+	}
+	for _, c := range o.Components.Component {
+		ct := c.Transform
+		if ct == (Matrix{}) {
+			ct = Identity()
+		}
+		if t, ok := m.placement(seen, target, c.ObjectPath(path), c.ObjectID, transform.Mul(ct)); ok {
+			return t, true
+		}
+	}
+	return Matrix{}, false
+}
+
+// AddRelationship adds r to the relationships of the root model part,
+// unless there is already one with the same type and path.
+// The relationship type, path and ID are written as is when encoding,
+// so unknown types round-trip. Package level relationships,
+// such as the package thumbnail, belong to RootRelationships.
+func (m *Model) AddRelationship(r Relationship) {
+	for _, ro := range m.Relationships {
+		if ro.Type == r.Type && ro.Path == r.Path {
+			return
+		}
+	}
+	m.Relationships = append(m.Relationships, r)
+}
+
+// AddAttachmentHashed adds data as a texture attachment whose part name is
+// derived from the SHA-256 hash of its content, '/3D/Textures/<sha256>.<ext>',
+// and references it from the root model part.
+// The extension is only set for PNG and JPEG content types.
+// If an attachment with the same content already exists it is returned instead.
+//
+// The returned pointer is only valid until Attachments is modified.
+func (m *Model) AddAttachmentHashed(contentType string, data []byte) *Attachment {
+	sum := sha256.Sum256(data)
+	path := Default3DTexturesDir + hex.EncodeToString(sum[:])
+	switch contentType {
+	case "image/png":
+		path += ".png"
+	case "image/jpeg":
+		path += ".jpeg"
+	}
+	for i := range m.Attachments {
+		if m.Attachments[i].Path == path {
+			return &m.Attachments[i]
+		}
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Path:        path,
+		ContentType: contentType,
+		Stream:      bytes.NewReader(data),
+	})
+	m.Relationships = append(m.Relationships, Relationship{Path: path, Type: RelTypeTexture3D})
+	return &m.Attachments[len(m.Attachments)-1]
+}
+
+// Bounds returns the minimum and maximum corners of the model bounding box
+// in world space, combining the build items with their transforms.
+// Objects not referenced by any build item are ignored.
+// ok is false if the build does not contain any item with geometry.
+func (m *Model) Bounds() (min, max Point3D, ok bool) {
+	box := m.BoundingBox()
+	if box == emptyBox || box == newLimitBox() {
+		return
+	}
+	return box.Min, box.Max, true
+}
+
 func (i *Item) BoundingBox(m *Model) Box {
 	if o, ok := m.FindObject(i.ObjectPath(), i.ObjectID); ok {
 		ibox := o.boundingBox(m, i.ObjectPath())
@@ -269,6 +803,131 @@ func (i *Item) BoundingBox(m *Model) Box {
 	return Box{}
 }
 
+// ObjectBoundingBox returns the bounding box of the object of the root model
+// identified by objectID, in the object coordinate system.
+// Components are expanded recursively applying their transforms.
+// A zero Box is returned if the object does not exist or has no geometry.
+// The components must not be recursive, see Model.Validate.
+func (m *Model) ObjectBoundingBox(objectID uint32) Box {
+	if o, ok := m.Resources.FindObject(objectID); ok {
+		return o.boundingBox(m, m.PathOrDefault())
+	}
+	return Box{}
+}
+
+// ObjectMesh returns a new mesh with the geometry of the object of the root model
+// identified by objectID, in the object coordinate system.
+// Components are expanded recursively, baking their transforms into the vertices.
+// Only the vertices and the triangle vertex indices are copied,
+// triangles referencing out of range vertices are skipped.
+// It returns false if the object does not exist.
+func (m *Model) ObjectMesh(objectID uint32) (*Mesh, bool) {
+	if _, ok := m.Resources.FindObject(objectID); !ok {
+		return nil, false
+	}
+	mesh := new(Mesh)
+	for _, leaf := range m.leafInstances(make(map[instanceKey][]Instance), nil, m.PathOrDefault(), objectID) {
+		src := leaf.Object.Mesh
+		part := &Mesh{Vertices: Vertices{Vertex: append([]Point3D(nil), src.Vertices.Vertex...)}}
+		offset := uint32(len(mesh.Vertices.Vertex))
+		nodeCount := uint32(len(src.Vertices.Vertex))
+		for _, t := range src.Triangles.Triangle {
+			if t.V1 < nodeCount && t.V2 < nodeCount && t.V3 < nodeCount {
+				part.Triangles.Triangle = append(part.Triangles.Triangle, Triangle{V1: t.V1 + offset, V2: t.V2 + offset, V3: t.V3 + offset})
+			}
+		}
+		part.Transform(leaf.Transform)
+		mesh.Vertices.Vertex = append(mesh.Vertices.Vertex, part.Vertices.Vertex...)
+		mesh.Triangles.Triangle = append(mesh.Triangles.Triangle, part.Triangles.Triangle...)
+	}
+	return mesh, true
+}
+
+// FindAttachment returns the attachment whose path is path.
+// An exact match is preferred, otherwise the paths are compared
+// case-insensitively as OPC part names are.
+// The returned pointer is only valid until Attachments is modified.
+func (m *Model) FindAttachment(path string) (*Attachment, bool) {
+	for i := range m.Attachments {
+		if m.Attachments[i].Path == path {
+			return &m.Attachments[i], true
+		}
+	}
+	for i := range m.Attachments {
+		if strings.EqualFold(m.Attachments[i].Path, path) {
+			return &m.Attachments[i], true
+		}
+	}
+	return nil, false
+}
+
+// ThumbnailData returns the content and content type of the model thumbnail,
+// looking for the attachment referenced by Thumbnail or, if empty,
+// by the package thumbnail relationship.
+// ok is false if there is no thumbnail or its attachment cannot be read.
+func (m *Model) ThumbnailData() (data []byte, contentType string, ok bool) {
+	path := m.Thumbnail
+	if path == "" {
+		for _, r := range m.RootRelationships {
+			if r.Type == RelTypeThumbnail {
+				path = r.Path
+				break
+			}
+		}
+	}
+	return m.attachmentData(path)
+}
+
+// ThumbnailData returns the content and content type of the object thumbnail
+// stored in the attachments of m.
+// ok is false if there is no thumbnail or its attachment cannot be read.
+func (o *Object) ThumbnailData(m *Model) (data []byte, contentType string, ok bool) {
+	return m.attachmentData(o.Thumbnail)
+}
+
+// attachmentData reads the attachment whose path is path
+// leaving its stream ready to be encoded.
+// A *bytes.Buffer, as set by the Decoder, is copied without reading it,
+// an io.Seeker is rewound after reading it and any other stream is replaced
+// by a reader over the read data followed by the unread data.
+func (m *Model) attachmentData(path string) ([]byte, string, bool) {
+	if path == "" {
+		return nil, "", false
+	}
+	a, ok := m.FindAttachment(path)
+	if !ok || a.Stream == nil {
+		return nil, "", false
+	}
+	var (
+		data []byte
+		err  error
+	)
+	switch s := a.Stream.(type) {
+	case *bytes.Buffer:
+		data = append([]byte(nil), s.Bytes()...)
+	case io.Seeker:
+		var offset int64
+		if offset, err = s.Seek(0, io.SeekCurrent); err != nil {
+			return nil, "", false
+		}
+		data, err = ioutil.ReadAll(a.Stream)
+		if _, serr := s.Seek(offset, io.SeekStart); err == nil {
+			err = serr
+		}
+	default:
+		data, err = ioutil.ReadAll(a.Stream)
+		if err != nil {
+			a.Stream = io.MultiReader(bytes.NewReader(data), a.Stream)
+		} else {
+			a.Stream = bytes.NewReader(data)
+		}
+	}
+	if err != nil {
+		return nil, "", false
+	}
+	return data, a.ContentType, true
+}
+
 // FindResources returns the resource associated with path.
 func (m *Model) FindResources(path string) (*Resources, bool) {
 	if path == "" || path == m.Path || (m.Path == "" && path == DefaultModelPath) {
@@ -345,6 +1004,8 @@ func (m *Model) WalkObjects(fn func(string, *Object) error) error {
 // Base defines the Model Base Material Resource.
 // A model material resource is an in memory representation of the 3MF
 // material resource object.
+//
+// Name may be empty when decoding, although Validate reports it as missing.
 type Base struct {
 	Name    string
 	Color   color.RGBA
@@ -407,6 +1068,17 @@ func (b *Item) HasTransform() bool {
 	return b.Transform != Matrix{} && b.Transform != Identity()
 }
 
+// WorldTransform returns the transform that places the item in the build,
+// or the identity if Transform is not set.
+// Build items are top-level elements, so there is no ancestor transform to compose;
+// see Model.ComponentWorldTransform for objects nested in components.
+func (b *Item) WorldTransform() Matrix {
+	if b.Transform == (Matrix{}) {
+		return Identity()
+	}
+	return b.Transform
+}
+
 // An Object is an in memory representation of the 3MF model object.
 type Object struct {
 	ID         uint32
@@ -422,6 +1094,66 @@ type Object struct {
 	AnyAttr    spec.AnyAttr
 }
 
+// HasGeometry returns true if the object has a non-empty mesh
+// or if any of its components resolves, recursively, to an object with a non-empty mesh.
+// The components are resolved through m.FindObject, path being the path of the
+// model that owns the object, an empty path means the root model.
+// Unresolved and recursive references do not contribute any geometry.
+func (o *Object) HasGeometry(m *Model, path string) bool {
+	if path == "" {
+		path = m.PathOrDefault()
+	}
+	visited := map[instanceKey]struct{}{{path, o.ID}: {}}
+	type frame struct {
+		path string
+		obj  *Object
+	}
+	stack := []frame{{path, o}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !f.obj.Mesh.IsEmpty() {
+			return true
+		}
+		if f.obj.Components == nil {
+			continue
+		}
+		for _, c := range f.obj.Components.Component {
+			key := instanceKey{c.ObjectPath(f.path), c.ObjectID}
+			if _, ok := visited[key]; ok {
+				continue
+			}
+			visited[key] = struct{}{}
+			if cobj, ok := m.FindObject(key.path, key.id); ok {
+				stack = append(stack, frame{key.path, cobj})
+			}
+		}
+	}
+	return false
+}
+
+// IsPrintable returns true if the object is part of the final product,
+// that is if its type is model or surface.
+// Objects of type other are never fabricated and support objects
+// are removed after printing, so they are reported as not printable.
+func (o *Object) IsPrintable() bool {
+	return o.Type == ObjectTypeModel || o.Type == ObjectTypeSurface
+}
+
+// SetPrintable changes the object type so IsPrintable returns printable.
+// Non printable objects become of type model and printable objects of type other,
+// the type is kept if it already matches.
+func (o *Object) SetPrintable(printable bool) {
+	if o.IsPrintable() == printable {
+		return
+	}
+	if printable {
+		o.Type = ObjectTypeModel
+	} else {
+		o.Type = ObjectTypeOther
+	}
+}
+
 func (o *Object) boundingBox(m *Model, path string) Box {
 	if o.Mesh != nil {
 		return o.Mesh.BoundingBox()
@@ -432,7 +1164,7 @@ func (o *Object) boundingBox(m *Model, path string) Box {
 	box := newLimitBox()
 	for _, c := range o.Components.Component {
 		if obj, ok := m.FindObject(c.ObjectPath(path), c.ObjectID); ok {
-			cbox := obj.boundingBox(m, path)
+			cbox := obj.boundingBox(m, c.ObjectPath(path))
 			if cbox != emptyBox {
 				box = box.extend(c.Transform.MulBox(cbox))
 			}
@@ -505,14 +1237,27 @@ type Triangles struct {
 	AnyAttr  spec.AnyAttr
 }
 
+// IsEmpty returns true if the mesh is nil or
+// has neither vertices nor triangles.
+func (m *Mesh) IsEmpty() bool {
+	return m == nil || (len(m.Vertices.Vertex) == 0 && len(m.Triangles.Triangle) == 0)
+}
+
 // BoundingBox returns the bounding box of the mesh.
+// Vertices with a NaN coordinate are ignored, and a zero Box is returned
+// if there are no other vertices.
 func (m *Mesh) BoundingBox() Box {
-	if len(m.Vertices.Vertex) == 0 {
-		return Box{}
-	}
 	box := newLimitBox()
+	var found bool
 	for _, v := range m.Vertices.Vertex {
+		if v.isNaN() {
+			continue
+		}
 		box = box.extendPoint(v)
+		found = true
+	}
+	if !found {
+		return Box{}
 	}
 	return box
 }
@@ -4,9 +4,19 @@
 package go3mf
 
 import (
+	"bytes"
+	"errors"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 
+	specerr "github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 )
 
@@ -84,6 +94,181 @@ func TestModel_FindAsset(t *testing.T) {
 	}
 }
 
+func TestModel_FindAttachment(t *testing.T) {
+	m := &Model{Attachments: []Attachment{{Path: "/a.png"}, {Path: "/B.png"}, {Path: "/b.png"}}}
+	tests := []struct {
+		name string
+		path string
+		want *Attachment
+	}{
+		{"exact", "/b.png", &m.Attachments[2]},
+		{"case", "/A.PNG", &m.Attachments[0]},
+		{"notfound", "/c.png", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.FindAttachment(tt.path)
+			if got != tt.want || ok != (tt.want != nil) {
+				t.Errorf("Model.FindAttachment() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_ThumbnailData(t *testing.T) {
+	newModel := func() *Model {
+		return &Model{Attachments: []Attachment{
+			{Path: "/thumb.png", ContentType: "image/png", Stream: bytes.NewBufferString("model")},
+			{Path: "/3D/obj.jpeg", ContentType: "image/jpeg", Stream: bytes.NewBufferString("object")},
+		}}
+	}
+	tests := []struct {
+		name     string
+		m        *Model
+		want     string
+		wantType string
+		wantOk   bool
+	}{
+		{"empty", newModel(), "", "", false},
+		{"attr", func() *Model { m := newModel(); m.Thumbnail = "/THUMB.png"; return m }(), "model", "image/png", true},
+		{"rel", func() *Model {
+			m := newModel()
+			m.RootRelationships = []Relationship{{Path: "/thumb.png", Type: RelTypeThumbnail}}
+			return m
+		}(), "model", "image/png", true},
+		{"missing", func() *Model { m := newModel(); m.Thumbnail = "/other.png"; return m }(), "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				got, gotType, ok := tt.m.ThumbnailData()
+				if string(got) != tt.want || gotType != tt.wantType || ok != tt.wantOk {
+					t.Errorf("Model.ThumbnailData() = %s, %s, %v, want %s, %s, %v", got, gotType, ok, tt.want, tt.wantType, tt.wantOk)
+				}
+			}
+		})
+	}
+	m := newModel()
+	o := &Object{Thumbnail: "/3D/obj.jpeg"}
+	if got, gotType, ok := o.ThumbnailData(m); string(got) != "object" || gotType != "image/jpeg" || !ok {
+		t.Errorf("Object.ThumbnailData() = %s, %s, %v", got, gotType, ok)
+	}
+	if _, _, ok := new(Object).ThumbnailData(m); ok {
+		t.Error("Object.ThumbnailData() ok = true, want false")
+	}
+	if n := m.Attachments[1].Stream.(*bytes.Buffer).Len(); n != len("object") {
+		t.Errorf("Object.ThumbnailData() consumed the attachment, %d bytes left", n)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestModel_ThumbnailData_Streams(t *testing.T) {
+	errRead := errors.New("read error")
+	tests := []struct {
+		name   string
+		stream io.Reader
+		wantOk bool
+	}{
+		{"seeker", strings.NewReader("thumb"), true},
+		{"reader", iotest.HalfReader(strings.NewReader("thumb")), true},
+		{"error", io.MultiReader(strings.NewReader("thumb"), errReader{errRead}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Model{Thumbnail: "/thumb.png", Attachments: []Attachment{
+				{Path: "/thumb.png", ContentType: "image/png", Stream: tt.stream},
+			}}
+			for i := 0; i < 2; i++ {
+				if got, _, ok := m.ThumbnailData(); ok != tt.wantOk || (ok && string(got) != "thumb") {
+					t.Errorf("Model.ThumbnailData() = %s, %v, want thumb, %v", got, ok, tt.wantOk)
+				}
+			}
+			// The attachment must still be fully readable when encoding.
+			got, err := ioutil.ReadAll(m.Attachments[0].Stream)
+			if string(got) != "thumb" || (err != nil) != !tt.wantOk {
+				t.Errorf("Attachment.Stream = %s, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestModel_ObjectMesh(t *testing.T) {
+	tri := &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 1, P2: 1, P3: 1}, {V1: 0, V2: 1, V3: 5}}},
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: tri},
+			{ID: 2, Components: &Components{Component: []*Component{
+				{ObjectID: 1},
+				{ObjectID: 3, Transform: Matrix{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, -1, 0, 0, 0, 2, 1}},
+			}}},
+			{ID: 3, Mesh: tri},
+		}},
+	}
+	got, ok := m.ObjectMesh(2)
+	if !ok {
+		t.Fatal("Model.ObjectMesh() ok = false")
+	}
+	want := &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 2}, {1, 0, 2}, {0, 1, 2}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 3, V2: 5, V3: 4}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Model.ObjectMesh() = %v, want %v", got, want)
+	}
+	if len(tri.Vertices.Vertex) != 3 || tri.Vertices.Vertex[1] != (Point3D{1, 0, 0}) {
+		t.Errorf("Model.ObjectMesh() modified the source mesh")
+	}
+	if _, ok := m.ObjectMesh(4); ok {
+		t.Error("Model.ObjectMesh() ok = true, want false")
+	}
+}
+
+func TestModel_ConcurrentReads(t *testing.T) {
+	m := &Model{
+		Resources: Resources{
+			Assets:  []Asset{&BaseMaterials{ID: 5}},
+			Objects: []*Object{{ID: 1, Mesh: newGridCube(2, 1)}, {ID: 2, Components: &Components{Component: []*Component{{ObjectID: 1}}}}},
+		},
+		Build:  Build{Items: []*Item{{ObjectID: 2}}},
+		Childs: map[string]*ChildModel{"/other.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: newGridCube(1, 2)}}}}},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := m.FindResources("/other.model"); !ok {
+				t.Error("Model.FindResources() ok = false")
+			}
+			if _, ok := m.FindObject("", 2); !ok {
+				t.Error("Model.FindObject() ok = false")
+			}
+			if _, ok := m.FindAsset("", 5); !ok {
+				t.Error("Model.FindAsset() ok = false")
+			}
+			var objects int
+			m.WalkObjects(func(string, *Object) error {
+				objects++
+				return nil
+			})
+			if objects != 3 {
+				t.Errorf("Model.WalkObjects() objects = %d, want 3", objects)
+			}
+			if len(m.Instances()) != 1 {
+				t.Error("Model.Instances() want 1 instance")
+			}
+			m.BoundingBox()
+		}()
+	}
+	wg.Wait()
+}
+
 func TestModel_FindObject(t *testing.T) {
 	model := &Model{Path: "/3D/model.model"}
 	id1 := &Object{ID: 0}
@@ -206,6 +391,22 @@ func TestResources_UnusedID(t *testing.T) {
 	}
 }
 
+func TestResources_AddBaseMaterials(t *testing.T) {
+	rs := &Resources{Objects: []*Object{{ID: 1}}}
+	base := Base{Name: "Blue PLA", Color: color.RGBA{0, 0, 255, 255}}
+	got1 := rs.AddBaseMaterials(base)
+	got2 := rs.AddBaseMaterials()
+	if got1.ID == got2.ID {
+		t.Errorf("Resources.AddBaseMaterials() duplicated ID %d", got1.ID)
+	}
+	if want := (&BaseMaterials{ID: 2, Materials: []Base{base}}); !reflect.DeepEqual(got1, want) {
+		t.Errorf("Resources.AddBaseMaterials() = %v, want %v", got1, want)
+	}
+	if want := []Asset{got1, got2}; !reflect.DeepEqual(rs.Assets, want) {
+		t.Errorf("Resources.AddBaseMaterials() assets = %v, want %v", rs.Assets, want)
+	}
+}
+
 func TestObjectType_String(t *testing.T) {
 	tests := []struct {
 		name string
@@ -445,6 +646,9 @@ func TestMesh_BoundingBox(t *testing.T) {
 	}{
 		{"empty", new(Mesh), Box{}},
 		{"base", &Mesh{Vertices: Vertices{Vertex: []Point3D{{1, 1, 1}, {2, 2, 2}, {-1, 0, 3}}}}, Box{Min: Point3D{-1, 0, 1}, Max: Point3D{2, 2, 3}}},
+		{"single", &Mesh{Vertices: Vertices{Vertex: []Point3D{{1, 2, 3}}}}, Box{Min: Point3D{1, 2, 3}, Max: Point3D{1, 2, 3}}},
+		{"nan", &Mesh{Vertices: Vertices{Vertex: []Point3D{{1, 1, 1}, {float32(math.NaN()), -5, 5}, {2, 0, 2}}}}, Box{Min: Point3D{1, 0, 1}, Max: Point3D{2, 1, 2}}},
+		{"allNaN", &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, float32(math.NaN()), 0}}}}, Box{}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -455,6 +659,473 @@ func TestMesh_BoundingBox(t *testing.T) {
 	}
 }
 
+func TestModel_ObjectBoundingBox(t *testing.T) {
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 1, 1}}}}},
+			{ID: 2, Components: &Components{Component: []*Component{
+				{ObjectID: 1, Transform: Identity().Translate(10, 0, 0)},
+				{ObjectID: 1},
+			}}},
+			{ID: 3, Components: &Components{Component: []*Component{
+				{ObjectID: 2, Transform: Identity().Translate(0, 0, -5)},
+				{ObjectID: 1, AnyAttr: spec.AnyAttr{&fakeAttr{Value: "/other.model"}}},
+			}}},
+		}},
+		Childs: map[string]*ChildModel{"/other.model": {Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 20, 0}}}}},
+		}}}},
+	}
+	tests := []struct {
+		name string
+		id   uint32
+		want Box
+	}{
+		{"missing", 4, Box{}},
+		{"mesh", 1, Box{Min: Point3D{0, 0, 0}, Max: Point3D{1, 1, 1}}},
+		{"components", 2, Box{Min: Point3D{0, 0, 0}, Max: Point3D{11, 1, 1}}},
+		{"nested", 3, Box{Min: Point3D{0, 0, -5}, Max: Point3D{11, 20, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.ObjectBoundingBox(tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Model.ObjectBoundingBox() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_PartPaths(t *testing.T) {
+	tests := []struct {
+		name      string
+		m         *Model
+		want      []string
+		wantMulti bool
+	}{
+		{"empty", new(Model), []string{DefaultModelPath}, false},
+		{"path", &Model{Path: "/3D/other.model"}, []string{"/3D/other.model"}, false},
+		{"childs", &Model{Childs: map[string]*ChildModel{"/3D/b.model": {}, "/3D/a.model": {}}},
+			[]string{DefaultModelPath, "/3D/a.model", "/3D/b.model"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.PartPaths(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Model.PartPaths() = %v, want %v", got, tt.want)
+			}
+			if got := tt.m.IsMultiPart(); got != tt.wantMulti {
+				t.Errorf("Model.IsMultiPart() = %v, want %v", got, tt.wantMulti)
+			}
+		})
+	}
+}
+
+func TestMesh_IsEmpty(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *Mesh
+		want bool
+	}{
+		{"nil", nil, true},
+		{"empty", new(Mesh), true},
+		{"vertices", &Mesh{Vertices: Vertices{Vertex: []Point3D{{}}}}, false},
+		{"triangles", &Mesh{Triangles: Triangles{Triangle: []Triangle{{}}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.IsEmpty(); got != tt.want {
+				t.Errorf("Mesh.IsEmpty() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObject_HasGeometry(t *testing.T) {
+	mesh := &Mesh{Vertices: Vertices{Vertex: []Point3D{{}}}}
+	components := func(refs ...*Component) *Components {
+		return &Components{Component: refs}
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: mesh},
+			{ID: 2, Mesh: new(Mesh)},
+			{ID: 3, Components: components(&Component{ObjectID: 4})},
+			{ID: 4, Components: components(&Component{ObjectID: 3})},
+			{ID: 5, Components: components(&Component{ObjectID: 2}, &Component{ObjectID: 6})},
+			{ID: 6, Components: components(&Component{ObjectID: 1})},
+		}},
+		Childs: map[string]*ChildModel{"/other.model": {Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: mesh},
+			{ID: 2, Components: components(&Component{ObjectID: 2})},
+		}}}},
+	}
+	tests := []struct {
+		name string
+		o    *Object
+		path string
+		want bool
+	}{
+		{"nil", new(Object), "", false},
+		{"emptyMesh", &Object{Mesh: new(Mesh)}, "", false},
+		{"emptyComponents", &Object{Components: new(Components)}, "", false},
+		{"mesh", &Object{Mesh: mesh}, "", true},
+		{"missing", &Object{ID: 10, Components: components(&Component{ObjectID: 100})}, "", false},
+		{"emptyMeshComponent", &Object{ID: 10, Components: components(&Component{ObjectID: 2})}, "", false},
+		{"cycle", m.Resources.Objects[2], "", false},
+		{"selfReference", m.Childs["/other.model"].Resources.Objects[1], "/other.model", false},
+		{"components", &Object{ID: 10, Components: components(&Component{ObjectID: 1})}, "", true},
+		{"nested", m.Resources.Objects[4], "", true},
+		{"childPath", &Object{ID: 10, Components: components(&Component{ObjectID: 2})}, "/other.model", false},
+		{"childMesh", &Object{ID: 10, Components: components(&Component{ObjectID: 1})}, "/other.model", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.o.HasGeometry(m, tt.path); got != tt.want {
+				t.Errorf("Object.HasGeometry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResources_TopologicalOrder(t *testing.T) {
+	base := &BaseMaterials{ID: 1}
+	mesh := &Object{ID: 2, Mesh: &Mesh{Triangles: Triangles{Triangle: []Triangle{{PID: 1}}}}}
+	colored := &Object{ID: 3, PID: 1, Mesh: new(Mesh)}
+	components := func(id uint32, refs ...uint32) *Object {
+		o := &Object{ID: id, Components: new(Components)}
+		for _, ref := range refs {
+			o.Components.Component = append(o.Components.Component, &Component{ObjectID: ref})
+		}
+		return o
+	}
+	external := components(10, 2)
+	external.Components.Component[0].AnyAttr = spec.AnyAttr{&fakeAttr{Value: "/other.model"}}
+	tests := []struct {
+		name    string
+		rs      *Resources
+		want    []interface{}
+		wantErr bool
+	}{
+		{"empty", new(Resources), []interface{}{}, false},
+		{"sorted", &Resources{Assets: []Asset{base}, Objects: []*Object{mesh, colored}}, []interface{}{base, mesh, colored}, false},
+		{"forward", &Resources{Objects: []*Object{components(4, 5, 2), components(5, 3), mesh, colored}, Assets: []Asset{base}},
+			[]interface{}{base, colored, components(5, 3), mesh, components(4, 5, 2)}, false},
+		{"missing", &Resources{Objects: []*Object{components(4, 8), external}}, []interface{}{components(4, 8), external}, false},
+		{"self", &Resources{Objects: []*Object{components(4, 4)}}, nil, true},
+		{"cycle", &Resources{Objects: []*Object{components(4, 5), components(5, 6), components(6, 4)}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rs.TopologicalOrder()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resources.TopologicalOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, specerr.ErrRecursion) {
+				t.Errorf("Resources.TopologicalOrder() error = %v, want %v", err, specerr.ErrRecursion)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resources.TopologicalOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObject_IsPrintable(t *testing.T) {
+	tests := []struct {
+		typ  ObjectType
+		want bool
+	}{
+		{ObjectTypeModel, true},
+		{ObjectTypeOther, false},
+		{ObjectTypeSupport, false},
+		{ObjectTypeSolidSupport, false},
+		{ObjectTypeSurface, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ.String(), func(t *testing.T) {
+			if got := (&Object{Type: tt.typ}).IsPrintable(); got != tt.want {
+				t.Errorf("Object.IsPrintable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObject_SetPrintable(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       ObjectType
+		printable bool
+		want      ObjectType
+	}{
+		{"model", ObjectTypeModel, true, ObjectTypeModel},
+		{"surface", ObjectTypeSurface, true, ObjectTypeSurface},
+		{"other", ObjectTypeOther, false, ObjectTypeOther},
+		{"support", ObjectTypeSupport, false, ObjectTypeSupport},
+		{"solidsupport", ObjectTypeSolidSupport, false, ObjectTypeSolidSupport},
+		{"toPrintable", ObjectTypeSupport, true, ObjectTypeModel},
+		{"toNotPrintable", ObjectTypeSurface, false, ObjectTypeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Object{Type: tt.typ}
+			o.SetPrintable(tt.printable)
+			if o.Type != tt.want || o.IsPrintable() != tt.printable {
+				t.Errorf("Object.SetPrintable() = %v, want %v", o.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild_Overlaps(t *testing.T) {
+	cube := &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {10, 10, 10}}}}
+	m := &Model{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: cube}}}}
+	tests := []struct {
+		name  string
+		items []*Item
+		want  [][2]int
+	}{
+		{"empty", nil, nil},
+		{"base", []*Item{
+			{ObjectID: 1},
+			{ObjectID: 1, Transform: Identity().Translate(5, 5, 0)},
+			{ObjectID: 1, Transform: Identity().Translate(10, 0, 0)},
+			{ObjectID: 1, Transform: Identity().Translate(50, 50, 0)},
+			{ObjectID: 2},
+		}, [][2]int{{0, 1}, {1, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Build{Items: tt.items}
+			if got := b.Overlaps(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build.Overlaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_Instances(t *testing.T) {
+	mesh := &Object{ID: 1, Mesh: new(Mesh)}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			mesh,
+			{ID: 2, Components: &Components{Component: []*Component{
+				{ObjectID: 1, Transform: Identity().Translate(1, 0, 0)},
+				{ObjectID: 1, Transform: Identity().Translate(0, 2, 0)},
+				{ObjectID: 3},
+			}}},
+			{ID: 4, Components: &Components{Component: []*Component{{ObjectID: 4}, {ObjectID: 1}}}},
+		}},
+		Build: Build{Items: []*Item{
+			{ObjectID: 1, Transform: Identity().Translate(0, 0, 3)},
+			{ObjectID: 2, Transform: Identity().Translate(10, 0, 0)},
+			{ObjectID: 3},
+			{ObjectID: 4},
+		}},
+	}
+	want := []Instance{
+		{Object: mesh, Transform: Identity().Translate(0, 0, 3), Item: m.Build.Items[0]},
+		{Object: mesh, Transform: Identity().Translate(11, 0, 0), Item: m.Build.Items[1]},
+		{Object: mesh, Transform: Identity().Translate(10, 2, 0), Item: m.Build.Items[1]},
+		{Object: mesh, Transform: Identity(), Item: m.Build.Items[3]},
+	}
+	if got := m.Instances(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Model.Instances() = %v, want %v", got, want)
+	}
+	if got := new(Model).Instances(); got != nil {
+		t.Errorf("Model.Instances() = %v, want nil", got)
+	}
+}
+
+func TestModel_BuildTree(t *testing.T) {
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Name: "box", Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{}}}}},
+			{ID: 2, Name: "group", Type: ObjectTypeSupport, Components: &Components{Component: []*Component{
+				{ObjectID: 1, Transform: Identity().Translate(1, 0, 0)},
+				{ObjectID: 3},
+				{ObjectID: 1, AnyAttr: spec.AnyAttr{&fakeAttr{Value: "/other.model"}}},
+			}}},
+			{ID: 4, Name: "loop", Components: &Components{Component: []*Component{{ObjectID: 4}}}},
+		}},
+		Build: Build{Items: []*Item{
+			{ObjectID: 2, Transform: Identity().Translate(10, 0, 0)},
+			{ObjectID: 4},
+		}},
+		Childs: map[string]*ChildModel{"/other.model": {Resources: Resources{Objects: []*Object{
+			{ID: 1, Name: "other", Mesh: new(Mesh)},
+		}}}},
+	}
+	want := []BuildNode{
+		{ObjectID: 2, Path: DefaultModelPath, Name: "group", Type: ObjectTypeSupport, Transform: Identity().Translate(10, 0, 0), Children: []BuildNode{
+			{ObjectID: 1, Path: DefaultModelPath, Name: "box", Transform: Identity().Translate(1, 0, 0)},
+			{ObjectID: 3, Path: DefaultModelPath, Transform: Identity()},
+			{ObjectID: 1, Path: "/other.model", Name: "other", Transform: Identity()},
+		}},
+		{ObjectID: 4, Path: DefaultModelPath, Name: "loop", Transform: Identity(), Children: []BuildNode{
+			{ObjectID: 4, Path: DefaultModelPath, Name: "loop", Transform: Identity()},
+		}},
+	}
+	if got := m.BuildTree(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Model.BuildTree() = %v, want %v", got, want)
+	}
+	if got := new(Model).BuildTree(); len(got) != 0 {
+		t.Errorf("Model.BuildTree() = %v, want empty", got)
+	}
+}
+
+func TestModel_TotalVolume(t *testing.T) {
+	scale := func(x, y, z float32) Matrix {
+		return Matrix{x, 0, 0, 0, 0, y, 0, 0, 0, 0, z, 0, 0, 0, 0, 1}
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: newGridCube(2, 2)},
+			{ID: 2, Components: &Components{Component: []*Component{
+				{ObjectID: 1},
+				{ObjectID: 1, Transform: scale(0.5, 0.5, 0.5).Translate(5, 0, 0)},
+			}}},
+		}},
+	}
+	tests := []struct {
+		name  string
+		items []*Item
+		want  float64
+	}{
+		{"empty", nil, 0},
+		{"identity", []*Item{{ObjectID: 1}}, 8},
+		{"nonUniform", []*Item{{ObjectID: 1, Transform: scale(2, 3, 1)}}, 48},
+		{"mirror", []*Item{{ObjectID: 1, Transform: scale(-1, 1, 1).Translate(3, 0, 0)}}, 8},
+		{"components", []*Item{{ObjectID: 2, Transform: scale(2, 2, 2)}}, 72},
+		{"instances", []*Item{{ObjectID: 1}, {ObjectID: 1}, {ObjectID: 3}}, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.Build.Items = tt.items
+			if got := m.TotalVolume(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Model.TotalVolume() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItem_WorldTransform(t *testing.T) {
+	tests := []struct {
+		name string
+		i    *Item
+		want Matrix
+	}{
+		{"unset", new(Item), Identity()},
+		{"base", &Item{Transform: Identity().Translate(1, 2, 3)}, Identity().Translate(1, 2, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.i.WorldTransform(); got != tt.want {
+				t.Errorf("Item.WorldTransform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_ComponentWorldTransform(t *testing.T) {
+	scale := Matrix{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: new(Mesh)},
+			{ID: 2, Components: &Components{Component: []*Component{
+				{ObjectID: 2},
+				{ObjectID: 1, Transform: Identity().Translate(1, 0, 0)},
+			}}},
+			{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 2, Transform: scale}}}},
+			{ID: 4, Mesh: new(Mesh)},
+		}},
+		Build: Build{Items: []*Item{
+			{ObjectID: 3, Transform: Identity().Translate(0, 0, 5)},
+		}},
+	}
+	tests := []struct {
+		name string
+		id   uint32
+		want Matrix
+	}{
+		{"item", 3, Identity().Translate(0, 0, 5)},
+		{"component", 2, Identity().Translate(0, 0, 5).Mul(scale)},
+		{"nested", 1, Identity().Translate(0, 0, 5).Mul(scale).Mul(Identity().Translate(1, 0, 0))},
+		{"notPlaced", 4, Identity()},
+		{"notFound", 100, Identity()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.ComponentWorldTransform(tt.id); got != tt.want {
+				t.Errorf("Model.ComponentWorldTransform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := m.ComponentWorldTransform(1).Mul3D(Point3D{1, 1, 1}); got != (Point3D{4, 2, 7}) {
+		t.Errorf("Model.ComponentWorldTransform() point = %v", got)
+	}
+}
+
+func TestModel_AddAttachmentHashed(t *testing.T) {
+	m := new(Model)
+	a := m.AddAttachmentHashed("image/png", []byte("fake"))
+	want := "/3D/Textures/b5d54c39e66671c9731b9f471e585d8262cd4f54963f0c93082d8dcf334d4c78.png"
+	if a.Path != want || a.ContentType != "image/png" {
+		t.Errorf("Model.AddAttachmentHashed() = %s %s, want %s", a.Path, a.ContentType, want)
+	}
+	if got := m.AddAttachmentHashed("image/png", []byte("fake")); got.Path != want {
+		t.Errorf("Model.AddAttachmentHashed() = %s, want %s", got.Path, want)
+	}
+	m.AddAttachmentHashed("application/octet-stream", []byte("other"))
+	if len(m.Attachments) != 2 || len(m.Relationships) != 2 {
+		t.Fatalf("Model.AddAttachmentHashed() attachments = %d, relationships = %d", len(m.Attachments), len(m.Relationships))
+	}
+	if m.Relationships[0] != (Relationship{Path: want, Type: RelTypeTexture3D}) {
+		t.Errorf("Model.AddAttachmentHashed() relationship = %v", m.Relationships[0])
+	}
+	if strings.Contains(m.Attachments[1].Path, ".") {
+		t.Errorf("Model.AddAttachmentHashed() unexpected extension = %s", m.Attachments[1].Path)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Model.AddAttachmentHashed() validate = %v", err)
+	}
+}
+
+func TestModel_Bounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *Model
+		wantMin Point3D
+		wantMax Point3D
+		wantOk  bool
+	}{
+		{"empty", new(Model), Point3D{}, Point3D{}, false},
+		{"missingObject", &Model{Build: Build{Items: []*Item{{ObjectID: 1}}}}, Point3D{}, Point3D{}, false},
+		{"base", &Model{
+			Build: Build{Items: []*Item{
+				{ObjectID: 1, Transform: Identity().Translate(-10, 0, 0)},
+				{ObjectID: 2},
+			}},
+			Resources: Resources{Objects: []*Object{
+				{ID: 1, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {10, 20, 30}}}}},
+				{ID: 2, Components: &Components{Component: []*Component{
+					{ObjectID: 1, Transform: Identity().Translate(100, 100, 100)},
+				}}},
+				{ID: 3, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{-500, -500, -500}}}}},
+			}},
+		}, Point3D{-10, 0, 0}, Point3D{110, 120, 130}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMin, gotMax, gotOk := tt.m.Bounds()
+			if gotOk != tt.wantOk {
+				t.Errorf("Model.Bounds() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("Model.Bounds() = %v %v, want %v %v", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
 func TestModel_BoundingBox(t *testing.T) {
 	tests := []struct {
 		name string
@@ -535,3 +1206,65 @@ func TestModel_BoundingBox(t *testing.T) {
 		})
 	}
 }
+
+func TestUnits_ConversionFactor(t *testing.T) {
+	tests := []struct {
+		name string
+		u    Units
+		to   Units
+		want float32
+	}{
+		{"same", UnitInch, UnitInch, 1},
+		{"inchToMillimeter", UnitInch, UnitMillimeter, 25.4},
+		{"meterToCentimeter", UnitMeter, UnitCentimeter, 100},
+		{"micronToMillimeter", UnitMicrometer, UnitMillimeter, 0.001},
+		{"unknown", Units(100), UnitMillimeter, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.u.ConversionFactor(tt.to); got != tt.want {
+				t.Errorf("Units.ConversionFactor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_checkComponentDepth(t *testing.T) {
+	chain := func(n int) *Model {
+		m := &Model{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: new(Mesh)}}}}
+		for i := 2; i <= n+1; i++ {
+			m.Resources.Objects = append(m.Resources.Objects, &Object{ID: uint32(i), Components: &Components{
+				Component: []*Component{{ObjectID: uint32(i - 1)}, {ObjectID: 1}, {ObjectID: 1 << 31}},
+			}})
+		}
+		return m
+	}
+	reversed := chain(100000)
+	objs := reversed.Resources.Objects
+	for i, j := 0, len(objs)-1; i < j; i, j = i+1, j-1 {
+		objs[i], objs[j] = objs[j], objs[i]
+	}
+	cycle := chain(3)
+	cycle.Resources.Objects[1].Components.Component[0].ObjectID = 4
+	tests := []struct {
+		name    string
+		m       *Model
+		limit   int
+		wantErr error
+	}{
+		{"empty", new(Model), 1, nil},
+		{"mesh", chain(0), 1, nil},
+		{"limit", chain(10), 10, nil},
+		{"exceeded", chain(11), 10, ErrMaxComponentDepth},
+		{"deep", chain(100000), DefaultMaxComponentDepth, ErrMaxComponentDepth},
+		{"deepReversed", reversed, DefaultMaxComponentDepth, ErrMaxComponentDepth},
+		{"cycle", cycle, DefaultMaxComponentDepth, specerr.ErrRecursion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.m.checkComponentDepth(tt.limit); !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Model.checkComponentDepth() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -14,11 +14,24 @@ import (
 	"github.com/hpinc/go3mf/spec"
 )
 
+// A warning is an element decoder error that does not prevent using
+// the decoded element. It is reported in Decoder.Warnings instead of
+// failing the decoding, unless failStrict is set and the Decoder is Strict.
+type warning struct {
+	error
+	failStrict bool
+}
+
 type modelDecoder struct {
 	baseDecoder
-	model  *Model
-	isRoot bool
-	path   string
+	model         *Model
+	isRoot        bool
+	path          string
+	units         *Units
+	vertexSink    VertexSink
+	maxBuildItems int
+	skipGeometry  bool
+	stream        *StreamHandlers
 }
 
 func (d *modelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
@@ -26,16 +39,18 @@ func (d *modelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 		switch name.Local {
 		case attrResources:
 			resources, _ := d.model.FindResources(d.path)
-			child = &resourceDecoder{resources: resources, model: d.model}
+			child = &resourceDecoder{
+				resources: resources, model: d.model, vertexSink: d.vertexSink, skipGeometry: d.skipGeometry, stream: d.stream,
+			}
 			i = -1
 		case attrBuild:
 			if d.isRoot {
-				child = &buildDecoder{build: &d.model.Build, model: d.model}
+				child = &buildDecoder{build: &d.model.Build, model: d.model, maxItems: d.maxBuildItems}
 				i = -1
 			}
 		case attrMetadata:
 			if d.isRoot {
-				child = &metadataDecoder{metadatas: &d.model.Metadata, model: d.model}
+				child = &metadataDecoder{metadatas: &d.model.Metadata, model: d.model, stream: d.stream}
 				i = len(d.model.Metadata)
 			}
 		}
@@ -52,6 +67,14 @@ func (d *modelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 
 func (d *modelDecoder) Start(attrs []spec.XMLAttr) (err error) {
 	if !d.isRoot {
+		for _, a := range attrs {
+			if a.Name.Space == "" && a.Name.Local == attrUnit {
+				var ok bool
+				if *d.units, ok = newUnits(string(a.Value)); !ok {
+					err = specerr.Append(err, specerr.NewParseAttrError(a.Name.Local, false))
+				}
+			}
+		}
 		return
 	}
 	var requiredExts []string
@@ -60,7 +83,7 @@ func (d *modelDecoder) Start(attrs []spec.XMLAttr) (err error) {
 			switch a.Name.Local {
 			case attrUnit:
 				var ok bool
-				if d.model.Units, ok = newUnits(string(a.Value)); !ok {
+				if *d.units, ok = newUnits(string(a.Value)); !ok {
 					err = specerr.Append(err, specerr.NewParseAttrError(a.Name.Local, false))
 				}
 			case attrThumbnail:
@@ -140,6 +163,7 @@ type metadataDecoder struct {
 	model     *Model
 	metadatas *[]Metadata
 	metadata  Metadata
+	stream    *StreamHandlers
 }
 
 func (d *metadataDecoder) namespace(local string) (string, bool) {
@@ -177,21 +201,30 @@ func (d *metadataDecoder) Start(attrs []spec.XMLAttr) error {
 	return nil
 }
 
+// CharData appends txt to the metadata value, as the text content
+// can be split in several tokens, i.e. around comments.
 func (d *metadataDecoder) CharData(txt []byte) {
-	d.metadata.Value = string(txt)
+	d.metadata.Value += string(txt)
 }
 
 func (d *metadataDecoder) End() {
+	if d.stream != nil && d.stream.OnMetadata != nil {
+		d.stream.OnMetadata(d.metadata)
+	}
 	*d.metadatas = append(*d.metadatas, d.metadata)
 }
 
 type buildDecoder struct {
 	baseDecoder
-	model *Model
-	build *Build
+	model    *Model
+	build    *Build
+	maxItems int
 }
 
 func (d *buildDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
+	if d.maxItems > 0 && len(d.build.Items) >= d.maxItems {
+		return
+	}
 	if name.Space == Namespace && name.Local == attrItem {
 		child = &buildItemDecoder{build: d.build, model: d.model}
 		i = len(d.build.Items)
@@ -232,9 +265,15 @@ func (d *buildItemDecoder) Child(name xml.Name) (i int, child spec.ElementDecode
 }
 
 func (d *buildItemDecoder) Start(attrs []spec.XMLAttr) error {
-	var errs error
+	var (
+		errs        error
+		hasObjectID bool
+	)
 	for _, a := range attrs {
 		if a.Name.Space == "" {
+			if a.Name.Local == attrObjectID {
+				hasObjectID = true
+			}
 			errs = specerr.Append(errs, d.parseCoreAttr(a))
 		} else {
 			var attr spec.AttrGroup
@@ -245,6 +284,9 @@ func (d *buildItemDecoder) Start(attrs []spec.XMLAttr) error {
 			errs = specerr.Append(errs, attr.Unmarshal3MFAttr(a))
 		}
 	}
+	if !hasObjectID {
+		errs = specerr.Append(errs, specerr.NewMissingFieldError(attrObjectID))
+	}
 	return errs
 }
 
@@ -262,7 +304,7 @@ func (d *buildItemDecoder) parseCoreAttr(a spec.XMLAttr) (errs error) {
 		var ok bool
 		d.item.Transform, ok = spec.ParseMatrix(string(a.Value))
 		if !ok {
-			errs = specerr.Append(errs, specerr.NewParseAttrError(a.Name.Local, false))
+			errs = specerr.Append(errs, specerr.NewParseMatrixError(string(a.Value)))
 		}
 	}
 	return
@@ -270,8 +312,11 @@ func (d *buildItemDecoder) parseCoreAttr(a spec.XMLAttr) (errs error) {
 
 type resourceDecoder struct {
 	baseDecoder
-	model     *Model
-	resources *Resources
+	model        *Model
+	resources    *Resources
+	vertexSink   VertexSink
+	skipGeometry bool
+	stream       *StreamHandlers
 }
 
 func (d *resourceDecoder) Start(attrs []spec.XMLAttr) error {
@@ -291,7 +336,9 @@ func (d *resourceDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder
 	if name.Space == Namespace {
 		switch name.Local {
 		case attrObject:
-			child = &objectDecoder{resources: d.resources, model: d.model}
+			child = &objectDecoder{
+				resources: d.resources, model: d.model, vertexSink: d.vertexSink, skipGeometry: d.skipGeometry, stream: d.stream,
+			}
 			i = len(d.resources.Objects)
 		case attrBaseMaterials:
 			child = &baseMaterialsDecoder{resources: d.resources}
@@ -359,10 +406,14 @@ type baseMaterialDecoder struct {
 	resource *BaseMaterials
 }
 
+// Start decodes a base material.
+// A missing name is accepted as it does not prevent using the material,
+// but a missing displaycolor is reported as a warning as it decodes as black.
 func (d *baseMaterialDecoder) Start(attrs []spec.XMLAttr) error {
 	var (
-		base Base
-		errs error
+		base     Base
+		errs     error
+		hasColor bool
 	)
 	for _, a := range attrs {
 		if a.Name.Space == "" {
@@ -370,6 +421,7 @@ func (d *baseMaterialDecoder) Start(attrs []spec.XMLAttr) error {
 			case attrName:
 				base.Name = string(a.Value)
 			case attrDisplayColor:
+				hasColor = true
 				var err error
 				base.Color, err = spec.ParseRGBA(string(a.Value))
 				if err != nil {
@@ -385,13 +437,19 @@ func (d *baseMaterialDecoder) Start(attrs []spec.XMLAttr) error {
 			errs = specerr.Append(errs, attr.Unmarshal3MFAttr(a))
 		}
 	}
+	if !hasColor {
+		errs = specerr.Append(errs, warning{error: specerr.NewMissingFieldError(attrDisplayColor)})
+	}
 	d.resource.Materials = append(d.resource.Materials, base)
 	return errs
 }
 
 type meshDecoder struct {
 	baseDecoder
-	resource *Object
+	resource     *Object
+	vertexSink   VertexSink
+	skipGeometry bool
+	stream       *StreamHandlers
 }
 
 func (d *meshDecoder) Start(attrs []spec.XMLAttr) error {
@@ -410,11 +468,14 @@ func (d *meshDecoder) Start(attrs []spec.XMLAttr) error {
 
 func (d *meshDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace {
+		if d.skipGeometry {
+			return
+		}
 		if name.Local == attrVertices {
-			child = &verticesDecoder{mesh: d.resource.Mesh}
+			child = &verticesDecoder{mesh: d.resource.Mesh, vertexSink: d.vertexSink}
 			i = -1
 		} else if name.Local == attrTriangles {
-			child = &trianglesDecoder{resource: d.resource}
+			child = &trianglesDecoder{resource: d.resource, vertexSink: d.vertexSink, stream: d.stream}
 			i = -1
 		}
 	} else {
@@ -431,11 +492,13 @@ func (d *meshDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 type verticesDecoder struct {
 	baseDecoder
 	mesh          *Mesh
+	vertexSink    VertexSink
 	vertexDecoder vertexDecoder
 }
 
 func (d *verticesDecoder) Start(attrs []spec.XMLAttr) error {
 	d.vertexDecoder.mesh = d.mesh
+	d.vertexDecoder.vertexSink = d.vertexSink
 	var errs error
 	for _, a := range attrs {
 		var attr spec.AttrGroup
@@ -451,14 +514,15 @@ func (d *verticesDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *verticesDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace && name.Local == attrVertex {
 		child = &d.vertexDecoder
-		i = len(d.mesh.Vertices.Vertex)
+		i = d.vertexSink.Len(d.mesh)
 	}
 	return
 }
 
 type vertexDecoder struct {
 	baseDecoder
-	mesh *Mesh
+	mesh       *Mesh
+	vertexSink VertexSink
 }
 
 func (d *vertexDecoder) Start(attrs []spec.XMLAttr) error {
@@ -483,20 +547,28 @@ func (d *vertexDecoder) Start(attrs []spec.XMLAttr) error {
 			z = float32(val)
 		}
 	}
-	d.mesh.Vertices.Vertex = append(d.mesh.Vertices.Vertex, Point3D{x, y, z})
-	return errs
+	return specerr.Append(errs, d.vertexSink.AddVertex(d.mesh, Point3D{x, y, z}))
 }
 
 type trianglesDecoder struct {
 	baseDecoder
 	resource        *Object
+	vertexSink      VertexSink
+	stream          *StreamHandlers
 	triangleDecoder triangleDecoder
 }
 
 func (d *trianglesDecoder) Start(attrs []spec.XMLAttr) error {
 	d.triangleDecoder.mesh = d.resource.Mesh
+	if d.stream != nil {
+		d.triangleDecoder.onTriangle = d.stream.OnTriangle
+		if d.triangleDecoder.onTriangle == nil {
+			d.triangleDecoder.onTriangle = func(Triangle) {}
+		}
+	}
 	d.triangleDecoder.defaultPropertyID = d.resource.PID
 	d.triangleDecoder.defaultPropertyIndex = d.resource.PIndex
+	d.triangleDecoder.nodeCount = uint32(d.vertexSink.Len(d.resource.Mesh))
 
 	if len(d.resource.Mesh.Triangles.Triangle) == 0 && len(d.resource.Mesh.Vertices.Vertex) > 0 {
 		d.resource.Mesh.Triangles.Triangle = make([]Triangle, 0, len(d.resource.Mesh.Vertices.Vertex)*2)
@@ -516,7 +588,7 @@ func (d *trianglesDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *trianglesDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace && name.Local == attrTriangle {
 		child = &d.triangleDecoder
-		i = len(d.resource.Mesh.Triangles.Triangle)
+		i = len(d.resource.Mesh.Triangles.Triangle) + d.triangleDecoder.streamed
 	}
 	return
 }
@@ -525,6 +597,13 @@ type triangleDecoder struct {
 	baseDecoder
 	mesh                                    *Mesh
 	defaultPropertyIndex, defaultPropertyID uint32
+	// nodeCount is the number of vertices decoded before the triangles.
+	// It is zero if the vertices element is empty or missing,
+	// so every index is out of range.
+	nodeCount uint32
+	// onTriangle receives the triangles instead of the mesh when streaming.
+	onTriangle func(Triangle)
+	streamed   int
 }
 
 func (d *triangleDecoder) Start(attrs []spec.XMLAttr) error {
@@ -576,13 +655,27 @@ func (d *triangleDecoder) Start(attrs []spec.XMLAttr) error {
 		}
 	}
 
+	if t.V1 >= d.nodeCount || t.V2 >= d.nodeCount || t.V3 >= d.nodeCount {
+		errs = specerr.Append(errs, warning{error: specerr.ErrIndexOutOfBounds, failStrict: true})
+	}
+
+	// Without p1 the triangle properties are not defined,
+	// so pid, p2 and p3 are ignored and the object ones are used.
+	if !hasP1 {
+		hasPID, hasP2, hasP3 = false, false, false
+	}
 	p1 = applyDefault(p1, d.defaultPropertyIndex, hasP1)
 	p2 = applyDefault(p2, p1, hasP2)
 	p3 = applyDefault(p3, p1, hasP3)
 	pid = applyDefault(pid, d.defaultPropertyID, hasPID)
 	t.PID = pid
 	t.P1, t.P2, t.P3 = p1, p2, p3
-	d.mesh.Triangles.Triangle = append(d.mesh.Triangles.Triangle, t)
+	if d.onTriangle != nil {
+		d.onTriangle(t)
+		d.streamed++
+	} else {
+		d.mesh.Triangles.Triangle = append(d.mesh.Triangles.Triangle, t)
+	}
 	return errs
 }
 
@@ -595,12 +688,18 @@ func applyDefault(val, defVal uint32, noDef bool) uint32 {
 
 type objectDecoder struct {
 	baseDecoder
-	model     *Model
-	resources *Resources
-	resource  Object
+	model        *Model
+	resources    *Resources
+	resource     Object
+	vertexSink   VertexSink
+	skipGeometry bool
+	stream       *StreamHandlers
 }
 
 func (d *objectDecoder) End() {
+	if d.stream != nil && d.stream.OnObject != nil {
+		d.stream.OnObject(&d.resource)
+	}
 	d.resources.Objects = append(d.resources.Objects, &d.resource)
 }
 
@@ -624,7 +723,7 @@ func (d *objectDecoder) Start(attrs []spec.XMLAttr) error {
 func (d *objectDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	if name.Space == Namespace {
 		if name.Local == attrMesh {
-			child = &meshDecoder{resource: &d.resource}
+			child = &meshDecoder{resource: &d.resource, vertexSink: d.vertexSink, skipGeometry: d.skipGeometry, stream: d.stream}
 			i = -1
 		} else if name.Local == attrComponents {
 			child = &componentsDecoder{resource: &d.resource}
@@ -726,7 +825,7 @@ func (d *componentDecoder) Start(attrs []spec.XMLAttr) error {
 				var ok bool
 				component.Transform, ok = spec.ParseMatrix(string(a.Value))
 				if !ok {
-					errs = specerr.Append(errs, specerr.NewParseAttrError(a.Name.Local, false))
+					errs = specerr.Append(errs, specerr.NewParseMatrixError(string(a.Value)))
 				}
 			}
 		} else {
@@ -750,15 +849,23 @@ func (d *baseDecoder) End()                       {}
 
 type topLevelDecoder struct {
 	baseDecoder
-	model  *Model
-	isRoot bool
-	path   string
+	model         *Model
+	isRoot        bool
+	path          string
+	units         *Units
+	vertexSink    VertexSink
+	maxBuildItems int
+	skipGeometry  bool
+	stream        *StreamHandlers
 }
 
 func (d *topLevelDecoder) Child(name xml.Name) (i int, child spec.ElementDecoder) {
 	modelName := xml.Name{Space: Namespace, Local: attrModel}
 	if name == modelName {
-		child = &modelDecoder{model: d.model, isRoot: d.isRoot, path: d.path}
+		child = &modelDecoder{
+			model: d.model, isRoot: d.isRoot, path: d.path, units: d.units,
+			vertexSink: d.vertexSink, maxBuildItems: d.maxBuildItems, skipGeometry: d.skipGeometry, stream: d.stream,
+		}
 		i = -1
 	}
 	return
@@ -0,0 +1,167 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// DeduplicateObjects merges the mesh objects of each model part that have
+// the same type, properties, vertices and triangles, and returns
+// the number of removed objects.
+// The first object of each group is kept and the build items and components
+// referencing the others are updated to reference it.
+// The name, part number, thumbnail and metadata of the removed objects are lost.
+//
+// Objects with extension attributes or extension elements, in the object
+// or in its mesh, are never merged, as the meaning of that data is unknown.
+// Model parts containing meshes with extension elements are not deduplicated
+// at all, as the elements may reference other objects, such as the beam lattice
+// clipping meshes, and those references are not updated.
+func (m *Model) DeduplicateObjects() (merged int) {
+	rootPath := m.PathOrDefault()
+	remaps := make(map[string]map[uint32]uint32)
+	if remap := m.Resources.deduplicateObjects(); len(remap) > 0 {
+		remaps[rootPath] = remap
+		merged += len(remap)
+	}
+	for _, path := range m.sortedChilds() {
+		if remap := m.Childs[path].Resources.deduplicateObjects(); len(remap) > 0 {
+			remaps[path] = remap
+			merged += len(remap)
+		}
+	}
+	if merged == 0 {
+		return
+	}
+	resolve := func(path string, id uint32) uint32 {
+		if survivor, ok := remaps[path][id]; ok {
+			return survivor
+		}
+		return id
+	}
+	for _, item := range m.Build.Items {
+		path := item.ObjectPath()
+		if path == "" {
+			path = rootPath
+		}
+		item.ObjectID = resolve(path, item.ObjectID)
+	}
+	remapComponents := func(path string, rs *Resources) {
+		for _, o := range rs.Objects {
+			if o.Components == nil {
+				continue
+			}
+			for _, c := range o.Components.Component {
+				c.ObjectID = resolve(c.ObjectPath(path), c.ObjectID)
+			}
+		}
+	}
+	remapComponents(rootPath, &m.Resources)
+	for path, c := range m.Childs {
+		remapComponents(path, &c.Resources)
+	}
+	return
+}
+
+// deduplicateObjects removes the objects with the same geometry
+// as a previous one and returns the removed ID to kept ID mapping.
+func (rs *Resources) deduplicateObjects() map[uint32]uint32 {
+	for _, o := range rs.Objects {
+		if o.Mesh != nil && len(o.Mesh.Any) != 0 {
+			return nil
+		}
+	}
+	groups := make(map[uint64][]*Object)
+	remap := make(map[uint32]uint32)
+	objects := rs.Objects[:0]
+	for _, o := range rs.Objects {
+		if o.isMergeable() {
+			key := o.geometryHash()
+			var survivor *Object
+			for _, s := range groups[key] {
+				if s.sameGeometry(o) {
+					survivor = s
+					break
+				}
+			}
+			if survivor != nil {
+				remap[o.ID] = survivor.ID
+				continue
+			}
+			groups[key] = append(groups[key], o)
+		}
+		objects = append(objects, o)
+	}
+	for i := len(objects); i < len(rs.Objects); i++ {
+		rs.Objects[i] = nil
+	}
+	rs.Objects = objects
+	return remap
+}
+
+func (o *Object) isMergeable() bool {
+	if o.Mesh == nil || (o.Components != nil && len(o.Components.Component) > 0) {
+		return false
+	}
+	mesh := o.Mesh
+	if len(o.AnyAttr) != 0 || len(mesh.AnyAttr) != 0 || len(mesh.Any) != 0 ||
+		len(mesh.Vertices.AnyAttr) != 0 || len(mesh.Triangles.AnyAttr) != 0 {
+		return false
+	}
+	for _, t := range mesh.Triangles.Triangle {
+		if len(t.AnyAttr) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Object) geometryHash() uint64 {
+	h := fnv.New64a()
+	var buf [7 * 4]byte
+	binary.LittleEndian.PutUint32(buf[0:], uint32(o.Type))
+	binary.LittleEndian.PutUint32(buf[4:], o.PID)
+	binary.LittleEndian.PutUint32(buf[8:], o.PIndex)
+	h.Write(buf[:12])
+	for _, v := range o.Mesh.Vertices.Vertex {
+		binary.LittleEndian.PutUint32(buf[0:], math.Float32bits(v[0]))
+		binary.LittleEndian.PutUint32(buf[4:], math.Float32bits(v[1]))
+		binary.LittleEndian.PutUint32(buf[8:], math.Float32bits(v[2]))
+		h.Write(buf[:12])
+	}
+	for _, t := range o.Mesh.Triangles.Triangle {
+		for i, v := range [7]uint32{t.V1, t.V2, t.V3, t.PID, t.P1, t.P2, t.P3} {
+			binary.LittleEndian.PutUint32(buf[i*4:], v)
+		}
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func (o *Object) sameGeometry(other *Object) bool {
+	if o.Type != other.Type || o.PID != other.PID || o.PIndex != other.PIndex {
+		return false
+	}
+	v1, v2 := o.Mesh.Vertices.Vertex, other.Mesh.Vertices.Vertex
+	t1, t2 := o.Mesh.Triangles.Triangle, other.Mesh.Triangles.Triangle
+	if len(v1) != len(v2) || len(t1) != len(t2) {
+		return false
+	}
+	for i := range v1 {
+		if v1[i] != v2[i] {
+			return false
+		}
+	}
+	for i := range t1 {
+		a, b := t1[i], t2[i]
+		if a.V1 != b.V1 || a.V2 != b.V2 || a.V3 != b.V3 ||
+			a.PID != b.PID || a.P1 != b.P1 || a.P2 != b.P2 || a.P3 != b.P3 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf/spec"
+)
+
+func TestModel_DeduplicateObjects(t *testing.T) {
+	other := "/3D/other.model"
+	newModel := func() *Model {
+		return &Model{
+			Resources: Resources{Objects: []*Object{
+				{ID: 1, Name: "a", Mesh: newGridCube(1, 1)},
+				{ID: 2, Name: "b", Mesh: newGridCube(1, 1)},
+				{ID: 3, PID: 5, Mesh: newGridCube(1, 1)},
+				{ID: 4, AnyAttr: spec.AnyAttr{&fakeAttr{}}, Mesh: newGridCube(1, 1)},
+				{ID: 6, Type: ObjectTypeSupport, Mesh: newGridCube(1, 1)},
+				{ID: 7, Mesh: newGridCube(1, 2)},
+				{ID: 5, Components: &Components{Component: []*Component{
+					{ObjectID: 2}, {ObjectID: 1}, {ObjectID: 2, AnyAttr: spec.AnyAttr{&fakeAttr{Value: other}}},
+				}}},
+			}},
+			Build: Build{Items: []*Item{
+				{ObjectID: 2}, {ObjectID: 5}, {ObjectID: 2, AnyAttr: spec.AnyAttr{&fakeAttr{Value: other}}},
+			}},
+			Childs: map[string]*ChildModel{
+				other: {Resources: Resources{Objects: []*Object{
+					{ID: 1, Mesh: newGridCube(1, 1)},
+					{ID: 2, Mesh: newGridCube(1, 1)},
+				}}},
+				// The extension element could reference object 2, so nothing is merged.
+				"/3D/lattice.model": {Resources: Resources{Objects: []*Object{
+					{ID: 1, Mesh: newGridCube(1, 1)},
+					{ID: 2, Mesh: newGridCube(1, 1)},
+					{ID: 3, Mesh: &Mesh{Any: spec.Any{&specAsset{}}}},
+				}}},
+			},
+		}
+	}
+	want := newModel()
+	want.Resources.Objects = append(want.Resources.Objects[:1], want.Resources.Objects[2:]...)
+	want.Resources.Objects[5].Components.Component[0].ObjectID = 1
+	want.Resources.Objects[5].Components.Component[2].ObjectID = 1
+	want.Build.Items[0].ObjectID = 1
+	want.Build.Items[2].ObjectID = 1
+	want.Childs[other].Resources.Objects = want.Childs[other].Resources.Objects[:1]
+
+	m := newModel()
+	if got := m.DeduplicateObjects(); got != 2 {
+		t.Errorf("Model.DeduplicateObjects() = %v, want 2", got)
+	}
+	if diff := deep.Equal(m, want); diff != nil {
+		t.Errorf("Model.DeduplicateObjects() = %v", diff)
+	}
+	if got := m.DeduplicateObjects(); got != 0 {
+		t.Errorf("Model.DeduplicateObjects() second call = %v, want 0", got)
+	}
+}
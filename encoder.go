@@ -7,12 +7,17 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	specerr "github.com/hpinc/go3mf/errors"
 	xml3mf "github.com/hpinc/go3mf/internal/xml"
 	"github.com/hpinc/go3mf/spec"
 )
@@ -23,6 +28,7 @@ type xmlEncoder struct {
 	floatPresicion int
 	relationships  []Relationship
 	p              xml3mf.Printer
+	scratch        []byte
 }
 
 // newXMLEncoder returns a new encoder that writes to w.
@@ -55,9 +61,35 @@ func (enc *xmlEncoder) EncodeToken(t xml.Token) {
 		p.WriteEnd(t.Name)
 	case xml.CharData:
 		xml.EscapeText(p, t)
+	case xml.Comment:
+		p.WriteString("<!--")
+		p.Write(t)
+		p.WriteString("-->")
 	}
 }
 
+var triangleAttrs = [...]string{attrV1, attrV2, attrV3, attrPID, attrP1, attrP2, attrP3}
+
+// writeTriangle writes a self closed triangle element whose attributes
+// are the first len(values) of triangleAttrs.
+// It is the hot loop of the encoder, so the integers are appended to a reused
+// buffer instead of being formatted as strings and passed to EncodeToken,
+// which would produce the same output.
+func (enc *xmlEncoder) writeTriangle(values []uint32) {
+	b := append(enc.scratch[:0], '<')
+	b = append(b, attrTriangle...)
+	for i, v := range values {
+		b = append(b, ' ')
+		b = append(b, triangleAttrs[i]...)
+		b = append(b, '=', '"')
+		b = strconv.AppendUint(b, uint64(v), 10)
+		b = append(b, '"')
+	}
+	b = append(b, '/', '>')
+	enc.p.Write(b)
+	enc.scratch = b
+}
+
 // Flush flushes any buffered XML to the underlying writer.
 func (enc *xmlEncoder) Flush() error {
 	return enc.p.Flush()
@@ -94,6 +126,42 @@ func MarshalModel(m *Model) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// MarshalObject returns the XML encoding of the object of the root model part
+// identified by objectID, including its mesh or components and its extension attributes.
+// It is not a valid model part, only the object element, which declares the core
+// and the model extensions namespaces so it can be parsed standalone.
+// The relationships required by the object, such as its thumbnail, are not encoded.
+func MarshalObject(m *Model, objectID uint32) ([]byte, error) {
+	obj, ok := m.Resources.FindObject(objectID)
+	if !ok {
+		return nil, fmt.Errorf("go3mf: object %d: %w", objectID, specerr.ErrMissingResource)
+	}
+	var b bytes.Buffer
+	xmlns := append([]xml.Attr{{Name: xml.Name{Local: attrXmlns}, Value: Namespace}}, extensionsXmlns(m.Extensions)...)
+	x := &namespacedEncoder{Encoder: newXMLEncoder(&b, defaultFloatPrecision), xmlns: xmlns}
+	new(Encoder).writeObject(x, obj)
+	if err := x.Flush(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// namespacedEncoder adds the xmlns attributes
+// to the first start element it encodes.
+type namespacedEncoder struct {
+	spec.Encoder
+	xmlns []xml.Attr
+}
+
+func (enc *namespacedEncoder) EncodeToken(t xml.Token) {
+	if start, ok := t.(xml.StartElement); ok && enc.xmlns != nil {
+		start.Attr = append(enc.xmlns, start.Attr...)
+		enc.xmlns = nil
+		t = start
+	}
+	enc.Encoder.EncodeToken(t)
+}
+
 // WriteCloser wrapps an Encoder than can be closed.
 type WriteCloser struct {
 	Encoder
@@ -120,12 +188,56 @@ func CreateWriter(name string) (*WriteCloser, error) {
 // An Encoder writes Model data to an output stream.
 //
 // See the documentation for strconv.FormatFloat for details about the FloatPrecision behaviour.
+//
+// If Comment is not empty it is written as an XML comment
+// just before the root element of the root model part.
+// It is usually used to identify the tool that generated the file.
+// Encode returns an error before writing anything if it contains "--" or ends with "-".
+//
+// If ModTime is not zero it is used as the modification time of every package entry,
+// so encoding the same model twice produces identical bytes. The package is then
+// buffered in memory until it is complete. If zero, the current time is used.
+//
+// If ValidatePartNames is true, Encode returns the error reported by
+// Model.ValidateOPC, if any, before writing anything.
+//
+// Compression defines how the model parts and attachments are deflated.
 type Encoder struct {
-	FloatPrecision int
-	w              packageWriter
+	FloatPrecision    int
+	Comment           string
+	ModTime           time.Time
+	ValidatePartNames bool
+	Compression       Compression
+	w                 packageWriter
+}
+
+// Compression defines the deflate compression of the package entries.
+type Compression int
+
+// Supported compressions.
+const (
+	// CompressionNormal is optimized for a reasonable compromise between size and performance.
+	CompressionNormal Compression = iota
+	// CompressionMaximum is optimized for size.
+	CompressionMaximum
+	// CompressionFast is optimized for performance.
+	CompressionFast
+	// CompressionNone stores the entries without compressing them.
+	CompressionNone
+)
+
+// checkComment reports whether Comment can be written as an XML comment.
+func (e *Encoder) checkComment() error {
+	if strings.Contains(e.Comment, "--") || strings.HasSuffix(e.Comment, "-") {
+		return errors.New("go3mf: comment must not contain \"--\" nor end with \"-\"")
+	}
+	return nil
 }
 
 // NewEncoder returns a new encoder that writes to w.
+//
+// The package is written sequentially as it is encoded, so w does not need
+// to support seeking and can be a network stream such as an http.ResponseWriter.
 func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{
 		FloatPrecision: defaultFloatPrecision,
@@ -135,6 +247,20 @@ func NewEncoder(w io.Writer) *Encoder {
 
 // Encode writes the XML encoding of m to the stream.
 func (e *Encoder) Encode(m *Model) error {
+	if err := e.checkComment(); err != nil {
+		return err
+	}
+	if e.ValidatePartNames {
+		if err := m.ValidateOPC(); err != nil {
+			return err
+		}
+	}
+	if w, ok := e.w.(*opcWriter); ok {
+		w.compression = e.Compression
+		if !e.ModTime.IsZero() {
+			w.setModTime(e.ModTime)
+		}
+	}
 	if err := e.writeAttachements(m.Attachments); err != nil {
 		return err
 	}
@@ -154,7 +280,7 @@ func (e *Encoder) Encode(m *Model) error {
 	enc := newXMLEncoder(w, e.FloatPrecision)
 	enc.relationships = make([]Relationship, len(m.Relationships))
 	copy(enc.relationships, m.Relationships)
-	for path := range m.Childs {
+	for _, path := range m.sortedChilds() {
 		enc.AddRelationship(spec.Relationship{Type: RelType3DModel, Path: path})
 	}
 	if err = e.writeModel(enc, m); err != nil {
@@ -170,31 +296,95 @@ func (e *Encoder) Encode(m *Model) error {
 	return e.w.Close()
 }
 
+// EncodeToMemory encodes m as Encode does but returns the package bytes
+// instead of writing them to the encoder stream, which is left untouched.
+func (e *Encoder) EncodeToMemory(m *Model) ([]byte, error) {
+	pw := e.w
+	defer func() { e.w = pw }()
+	var buf bytes.Buffer
+	e.w = newOpcWriter(&buf)
+	if err := e.Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// childModelPart holds the encoded content of a child model part.
+type childModelPart struct {
+	buff bytes.Buffer
+	rels []Relationship
+	err  error
+	done chan struct{}
+}
+
+// writeChildModels encodes the child model parts concurrently using
+// a pool of workers and writes them to the package sequentially,
+// sorted by path, as soon as each one is ready.
 func (e *Encoder) writeChildModels(m *Model) error {
-	for path, child := range m.Childs {
-		var (
-			w   packagePart
-			err error
-		)
-		path = resolveRelationship(m.PathOrDefault(), path)
-		if w, err = e.w.Create(path, ContentType3DModel); err != nil {
+	paths := m.sortedChilds()
+	if len(paths) == 0 {
+		return nil
+	}
+	parts := make([]childModelPart, len(paths))
+	for i := range parts {
+		parts[i].done = make(chan struct{})
+	}
+	jobs := make(chan int)
+	workers := runtime.NumCPU()
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	for j := 0; j < workers; j++ {
+		go func() {
+			for i := range jobs {
+				e.encodeChildModel(m, m.Childs[paths[i]], &parts[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	// Do not return while workers are still reading the model.
+	defer func() {
+		for i := range parts {
+			<-parts[i].done
+		}
+	}()
+	for i, path := range paths {
+		part := &parts[i]
+		<-part.done
+		if part.err != nil {
+			return part.err
+		}
+		w, err := e.w.Create(resolveRelationship(m.PathOrDefault(), path), ContentType3DModel)
+		if err != nil {
 			return err
 		}
 		if _, err = w.Write([]byte(xml.Header)); err != nil {
 			return err
 		}
-		enc := newXMLEncoder(w, e.FloatPrecision)
-		enc.relationships = child.Relationships
-		if err = e.writeChildModel(enc, m, child); err != nil {
+		if _, err = part.buff.WriteTo(w); err != nil {
 			return err
 		}
-		for _, r := range enc.relationships {
+		for _, r := range part.rels {
 			w.AddRelationship(r)
 		}
 	}
 	return nil
 }
 
+func (e *Encoder) encodeChildModel(m *Model, child *ChildModel, part *childModelPart) {
+	defer close(part.done)
+	enc := newXMLEncoder(&part.buff, e.FloatPrecision)
+	enc.relationships = make([]Relationship, len(child.Relationships))
+	copy(enc.relationships, child.Relationships)
+	part.err = e.writeChildModel(enc, m, child)
+	part.rels = enc.relationships
+}
+
 func (e *Encoder) writeAttachements(att []Attachment) error {
 	for _, a := range att {
 		w, err := e.w.Create(a.Path, a.ContentType)
@@ -204,6 +394,9 @@ func (e *Encoder) writeAttachements(att []Attachment) error {
 		if err != nil {
 			return err
 		}
+		for _, r := range a.Relationships {
+			w.AddRelationship(r)
+		}
 	}
 	return nil
 }
@@ -220,9 +413,7 @@ func (e *Encoder) modelToken(x spec.Encoder, m *Model, isRoot bool) (xml.StartEl
 		}
 		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: attrThumbnail}, Value: m.Thumbnail})
 	}
-	for _, ext := range m.Extensions {
-		attrs = append(attrs, xml.Attr{Name: xml.Name{Space: attrXmlns, Local: ext.LocalName}, Value: ext.Namespace})
-	}
+	attrs = append(attrs, extensionsXmlns(m.Extensions)...)
 	var exts []string
 	for _, ext := range m.Extensions {
 		if ext.IsRequired {
@@ -238,6 +429,24 @@ func (e *Encoder) modelToken(x spec.Encoder, m *Model, isRoot bool) (xml.StartEl
 	return tm, nil
 }
 
+// extensionsXmlns returns the namespace declarations of exts
+// sorted by prefix and namespace, so the output does not depend on their order.
+func extensionsXmlns(exts []Extension) []xml.Attr {
+	sorted := make([]Extension, len(exts))
+	copy(sorted, exts)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].LocalName != sorted[j].LocalName {
+			return sorted[i].LocalName < sorted[j].LocalName
+		}
+		return sorted[i].Namespace < sorted[j].Namespace
+	})
+	attrs := make([]xml.Attr, len(sorted))
+	for i, ext := range sorted {
+		attrs[i] = xml.Attr{Name: xml.Name{Space: attrXmlns, Local: ext.LocalName}, Value: ext.Namespace}
+	}
+	return attrs
+}
+
 func (e *Encoder) writeChildModel(x spec.Encoder, m *Model, child *ChildModel) error {
 	tm, _ := e.modelToken(x, m, false) // error already checked before
 	x.EncodeToken(tm)
@@ -259,6 +468,12 @@ func (e *Encoder) writeModel(x spec.Encoder, m *Model) error {
 	if err != nil {
 		return err
 	}
+	if e.Comment != "" {
+		if err := e.checkComment(); err != nil {
+			return err
+		}
+		x.EncodeToken(xml.Comment(e.Comment))
+	}
 	x.EncodeToken(tm)
 
 	e.writeMetadata(x, m.Metadata)
@@ -290,7 +505,7 @@ func (e *Encoder) writeBuild(x spec.Encoder, m *Model) {
 		}}
 		if item.HasTransform() {
 			xi.Attr = append(xi.Attr, xml.Attr{
-				Name: xml.Name{Local: attrTransform}, Value: item.Transform.String(),
+				Name: xml.Name{Local: attrTransform}, Value: spec.FormatMatrix(item.Transform),
 			})
 		}
 		if item.PartNumber != "" {
@@ -419,7 +634,7 @@ func (e *Encoder) writeComponents(x spec.Encoder, comps *Components) {
 			},
 		}
 		if c.HasTransform() {
-			xt.Attr = append(xt.Attr, xml.Attr{Name: xml.Name{Local: attrTransform}, Value: c.Transform.String()})
+			xt.Attr = append(xt.Attr, xml.Attr{Name: xml.Name{Local: attrTransform}, Value: spec.FormatMatrix(c.Transform)})
 		}
 		c.AnyAttr.Marshal3MF(x, &xt)
 		x.EncodeToken(xt)
@@ -461,35 +676,32 @@ func (e *Encoder) writeTriangles(x spec.Encoder, r *Object, m *Mesh) {
 	start := xml.StartElement{
 		Name: xml.Name{Local: attrTriangle},
 	}
-	attrs := []xml.Attr{
-		{Name: xml.Name{Local: attrV1}},
-		{Name: xml.Name{Local: attrV2}},
-		{Name: xml.Name{Local: attrV3}},
-		{Name: xml.Name{Local: attrPID}},
-		{Name: xml.Name{Local: attrP1}},
-		{Name: xml.Name{Local: attrP2}},
-		{Name: xml.Name{Local: attrP3}},
+	attrs := make([]xml.Attr, len(triangleAttrs))
+	for i, name := range triangleAttrs {
+		attrs[i].Name.Local = name
 	}
+	xe, fast := x.(*xmlEncoder)
 	x.SetAutoClose(true)
 	x.SetSkipAttrEscape(true)
 	for _, t := range m.Triangles.Triangle {
-		attrs[0].Value = strconv.FormatUint(uint64(t.V1), 10)
-		attrs[1].Value = strconv.FormatUint(uint64(t.V2), 10)
-		attrs[2].Value = strconv.FormatUint(uint64(t.V3), 10)
-		start.Attr = attrs[:3]
+		values := [...]uint32{t.V1, t.V2, t.V3, t.PID, t.P1, t.P2, t.P3}
+		n := 3
 		if t.PID != 0 {
 			if (t.P1 != t.P2) || (t.P1 != t.P3) {
-				attrs[3].Value = strconv.FormatUint(uint64(t.PID), 10)
-				attrs[4].Value = strconv.FormatUint(uint64(t.P1), 10)
-				attrs[5].Value = strconv.FormatUint(uint64(t.P2), 10)
-				attrs[6].Value = strconv.FormatUint(uint64(t.P3), 10)
-				start.Attr = attrs[:7]
+				n = 7
 			} else if (t.PID != r.PID) || (t.P1 != r.PIndex) {
-				attrs[3].Value = strconv.FormatUint(uint64(t.PID), 10)
-				attrs[4].Value = strconv.FormatUint(uint64(t.P1), 10)
-				start.Attr = attrs[:5]
+				n = 5
 			}
 		}
+		if fast && len(t.AnyAttr) == 0 {
+			xe.writeTriangle(values[:n])
+			continue
+		}
+		for i := 0; i < n; i++ {
+			attrs[i].Value = strconv.FormatUint(uint64(values[i]), 10)
+		}
+		// Cap the slice so extension attributes never overwrite the core ones.
+		start.Attr = attrs[:n:n]
 		t.AnyAttr.Marshal3MF(x, &start)
 		x.EncodeToken(start)
 	}
@@ -4,16 +4,24 @@
 package go3mf
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/xml"
 	"errors"
 	"image/color"
+	"io"
+	"math/rand"
+	"net/http/httptest"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
+	specerr "github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
+	"github.com/qmuntal/opc"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -44,7 +52,7 @@ func TestMarshalModel(t *testing.T) {
 	spec.Register(fakeSpec.Namespace, new(qmExtension))
 	m := &Model{
 		Units: UnitMillimeter, Language: "en-US", Path: "/3D/3dmodel.model", Thumbnail: "/thumbnail.png",
-		Extensions: []Extension{fakeSpec, fooSpec},
+		Extensions: []Extension{fooSpec, fakeSpec},
 		AnyAttr:    spec.AnyAttr{&fakeAttr{Value: "model_fake"}, &spec.UnknownAttrs{Space: fooSpace, Attr: []xml.Attr{{Name: fooName, Value: "foo1"}}}},
 		Any: spec.Any{&spec.UnknownTokens{Token: []xml.Token{
 			xml.StartElement{Name: fooName},
@@ -275,6 +283,16 @@ func TestEncoder_Encode_Roundtrip(t *testing.T) {
 				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
 			}}},
 		},
+		{"withAttachmentRel", args{&Model{
+			RootRelationships: []Relationship{
+				{Path: "/Metadata/thumbnail.png", Type: "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail", ID: "1"},
+			},
+			Attachments: []Attachment{
+				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake"), Relationships: []Relationship{
+					{Path: "/Metadata/profile.icc", Type: "http://fake.com/colorprofile", ID: "1"},
+				}},
+			}}},
+		},
 		{"withChildModel", args{&Model{
 			Attachments: []Attachment{
 				{ContentType: "application/vnd.ms-printing.printticket+xml", Path: "/3D/Metadata/pt.xml", Stream: bytes.NewBufferString("other")},
@@ -309,3 +327,387 @@ func TestEncoder_Encode_Roundtrip(t *testing.T) {
 		})
 	}
 }
+
+func TestEncoder_Encode_Path(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"default", "", DefaultModelPath},
+		{"custom", "/3D/printer.model", "/3D/printer.model"},
+		{"relative", "parts/root.model", "/parts/root.model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buff := new(bytes.Buffer)
+			m := &Model{Path: tt.path, Resources: Resources{Assets: []Asset{&BaseMaterials{ID: 1}}}}
+			if err := NewEncoder(buff).Encode(m); err != nil {
+				t.Fatalf("Encoder.Encode() error = %v", err)
+			}
+			r, err := opc.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+			if err != nil {
+				t.Fatalf("Encoder.Encode() malformed = %v", err)
+			}
+			var target string
+			for _, rel := range r.Relationships {
+				if rel.Type == RelType3DModel {
+					target = opc.ResolveRelationship("/", rel.TargetURI)
+				}
+			}
+			if target != tt.want {
+				t.Errorf("Encoder.Encode() root relationship = %s, want %s", target, tt.want)
+			}
+			var contentType string
+			for _, f := range r.Files {
+				if f.Name == tt.want {
+					contentType = f.ContentType
+				}
+			}
+			if contentType != ContentType3DModel {
+				t.Errorf("Encoder.Encode() content type = %s, want %s", contentType, ContentType3DModel)
+			}
+			got := new(Model)
+			if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(got); err != nil {
+				t.Fatalf("Encoder.Encode() malformed = %v", err)
+			}
+			if got.Path != tt.want {
+				t.Errorf("Encoder.Encode() path = %s, want %s", got.Path, tt.want)
+			}
+			if _, ok := got.FindAsset(tt.want, 1); !ok {
+				t.Error("Encoder.Encode() resources not found in root model")
+			}
+		})
+	}
+}
+
+func TestEncoder_Encode_EmptyBuild(t *testing.T) {
+	m := &Model{Resources: Resources{
+		Assets: []Asset{&BaseMaterials{ID: 1, Materials: []Base{{Name: "a", Color: color.RGBA{R: 255, A: 255}}}}},
+		Objects: []*Object{{ID: 2, Mesh: &Mesh{
+			Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+			Triangles: Triangles{Triangle: []Triangle{
+				{V1: 0, V2: 2, V3: 1}, {V1: 0, V2: 1, V3: 3}, {V1: 0, V2: 3, V3: 2}, {V1: 1, V2: 2, V3: 3},
+			}},
+		}}},
+	}}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Model.Validate() error = %v", err)
+	}
+	buff := new(bytes.Buffer)
+	if err := NewEncoder(buff).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if len(got.Build.Items) != 0 || len(got.Resources.Objects) != 1 || len(got.Resources.Assets) != 1 {
+		t.Errorf("Encoder.Encode() = %d items %d objects %d assets", len(got.Build.Items), len(got.Resources.Objects), len(got.Resources.Assets))
+	}
+	var root bytes.Buffer
+	if err := NewEncoder(nil).writeModel(newXMLEncoder(&root, defaultFloatPrecision), m); err != nil {
+		t.Fatalf("Encoder.writeModel() error = %v", err)
+	}
+	if !strings.Contains(root.String(), "<build></build>") {
+		t.Errorf("Encoder.writeModel() missing empty build: %s", root.String())
+	}
+}
+
+func TestEncoder_Encode_ValidatePartNames(t *testing.T) {
+	m := &Model{Attachments: []Attachment{{Path: "/Metadata/my thumbnail.png", ContentType: "image/png", Stream: new(bytes.Buffer)}}}
+	buff := new(bytes.Buffer)
+	enc := NewEncoder(buff)
+	enc.ValidatePartNames = true
+	if err := enc.Encode(m); !errors.Is(err, specerr.ErrOPCPartName) {
+		t.Fatalf("Encoder.Encode() error = %v, want %v", err, specerr.ErrOPCPartName)
+	}
+	if buff.Len() != 0 {
+		t.Errorf("Encoder.Encode() wrote %d bytes", buff.Len())
+	}
+	enc.ValidatePartNames = false
+	if err := enc.Encode(m); err != nil {
+		t.Errorf("Encoder.Encode() error = %v", err)
+	}
+}
+
+// genericEncoder hides the concrete xmlEncoder type
+// so the encoder cannot take its fast paths.
+type genericEncoder struct {
+	*xmlEncoder
+}
+
+func TestEncoder_writeTriangles_FastPath(t *testing.T) {
+	mesh := largeMesh(30)
+	mesh.Triangles.Triangle[4].AnyAttr = spec.AnyAttr{&fakeAttr{Value: "a"}}
+	mesh.Triangles.Triangle[5].AnyAttr = spec.AnyAttr{&fakeAttr{Value: "b"}}
+	obj := &Object{ID: 1, PID: 2, PIndex: 3}
+	var fast, generic bytes.Buffer
+	xe := newXMLEncoder(&fast, defaultFloatPrecision)
+	NewEncoder(nil).writeTriangles(xe, obj, mesh)
+	xe.Flush()
+	xg := newXMLEncoder(&generic, defaultFloatPrecision)
+	NewEncoder(nil).writeTriangles(genericEncoder{xg}, obj, mesh)
+	xg.Flush()
+	if fast.String() != generic.String() {
+		t.Errorf("Encoder.writeTriangles() = %s, want %s", fast.String(), generic.String())
+	}
+	if !strings.Contains(fast.String(), `<triangle v1="2" v2="3" v3="4" pid="2" p1="0" p2="1" p3="2"/>`) {
+		t.Errorf("Encoder.writeTriangles() = %s", fast.String())
+	}
+}
+
+func TestEncoder_Encode_ModTime(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)
+	encode := func() []byte {
+		m := &Model{
+			Attachments: []Attachment{
+				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+			},
+			RootRelationships: []Relationship{{Path: "/Metadata/thumbnail.png", Type: RelTypeThumbnail, ID: "1"}},
+			Childs:            map[string]*ChildModel{"/3D/other.model": {}, "/3D/b.model": {}, "/3D/c.model": {}},
+		}
+		buff := new(bytes.Buffer)
+		enc := NewEncoder(buff)
+		enc.ModTime = modTime
+		if err := enc.Encode(m); err != nil {
+			t.Fatalf("Encoder.Encode() error = %v", err)
+		}
+		return buff.Bytes()
+	}
+	b := encode()
+	for i := 0; i < 10; i++ {
+		if !bytes.Equal(b, encode()) {
+			t.Fatal("Encoder.Encode() is not reproducible")
+		}
+	}
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Encoder.Encode() malformed = %v", err)
+	}
+	for _, f := range r.File {
+		if !f.Modified.Equal(modTime) {
+			t.Errorf("Encoder.Encode() %s modified = %v, want %v", f.Name, f.Modified, modTime)
+		}
+	}
+	if err := NewDecoder(bytes.NewReader(b), int64(len(b))).Decode(new(Model)); err != nil {
+		t.Errorf("Encoder.Encode() malformed = %v", err)
+	}
+}
+
+func TestEncoder_Encode_Compression(t *testing.T) {
+	mesh := new(Mesh)
+	for i := 0; i < 1000; i++ {
+		mesh.Vertices.Vertex = append(mesh.Vertices.Vertex, Point3D{float32(i), 1, 2})
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{{ID: 1, Mesh: mesh}}},
+		Build:     Build{Items: []*Item{{ObjectID: 1}}},
+	}
+	for _, modTime := range []time.Time{{}, time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC)} {
+		sizes := make(map[Compression]uint64)
+		for _, c := range []Compression{CompressionNormal, CompressionMaximum, CompressionFast, CompressionNone} {
+			buff := new(bytes.Buffer)
+			enc := NewEncoder(buff)
+			enc.ModTime = modTime
+			enc.Compression = c
+			if err := enc.Encode(m); err != nil {
+				t.Fatalf("Encoder.Encode() error = %v", err)
+			}
+			r, err := zip.NewReader(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+			if err != nil {
+				t.Fatalf("Encoder.Encode() malformed = %v", err)
+			}
+			for _, f := range r.File {
+				if f.Name == "3D/3dmodel.model" {
+					sizes[c] = f.CompressedSize64
+				}
+			}
+			if err := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len())).Decode(new(Model)); err != nil {
+				t.Errorf("Encoder.Encode() malformed = %v", err)
+			}
+		}
+		if sizes[CompressionNone] <= sizes[CompressionFast] || sizes[CompressionNone] <= sizes[CompressionMaximum] ||
+			sizes[CompressionFast] == sizes[CompressionNormal] {
+			t.Errorf("Encoder.Encode() compressed sizes = %v", sizes)
+		}
+	}
+}
+
+func TestEncoder_Encode_Stream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", ContentType3MF)
+	// Hide every method but Write to ensure the encoder does not need to seek.
+	w := struct{ io.Writer }{rec}
+	m := &Model{Resources: Resources{Assets: []Asset{&BaseMaterials{ID: 1}}}}
+	if err := NewEncoder(w).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	b := rec.Body.Bytes()
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(b), int64(len(b))).Decode(got); err != nil {
+		t.Fatalf("Encoder.Encode() malformed = %v", err)
+	}
+	if _, ok := got.FindAsset("", 1); !ok {
+		t.Error("Encoder.Encode() resources not found")
+	}
+	if ct := rec.Result().Header.Get("Content-Type"); ct != "model/3mf" {
+		t.Errorf("Content-Type = %s, want model/3mf", ct)
+	}
+}
+
+func TestEncoder_EncodeToMemory(t *testing.T) {
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: largeMesh(4)},
+			{ID: 2, Components: &Components{Component: []*Component{{ObjectID: 1, Transform: Identity().Translate(5, 0, 0)}}}},
+		}},
+		Build: Build{Items: []*Item{{ObjectID: 2}, {ObjectID: 1, Transform: Identity().Translate(0, 5, 0)}}},
+	}
+	out := new(bytes.Buffer)
+	enc := NewEncoder(out)
+	b, err := enc.EncodeToMemory(m)
+	if err != nil {
+		t.Fatalf("Encoder.EncodeToMemory() error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Encoder.EncodeToMemory() wrote %d bytes to the stream", out.Len())
+	}
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(b), int64(len(b))).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(got.Resources.Objects, m.Resources.Objects); diff != nil {
+		t.Errorf("Encoder.EncodeToMemory() objects = %v", diff)
+	}
+	if diff := deep.Equal(got.Build.Items, m.Build.Items); diff != nil {
+		t.Errorf("Encoder.EncodeToMemory() items = %v", diff)
+	}
+	if err := enc.Encode(m); err != nil || out.Len() == 0 {
+		t.Errorf("Encoder.Encode() after EncodeToMemory error = %v", err)
+	}
+}
+
+func TestModel_AddRelationship_Roundtrip(t *testing.T) {
+	m := &Model{
+		Attachments: []Attachment{
+			{Path: "/Metadata/ticket.xml", ContentType: "application/xml", Stream: bytes.NewBufferString("<ticket/>")},
+		},
+		RootRelationships: []Relationship{{Path: "/Metadata/ticket.xml", Type: "http://example.com/package", ID: "rel9"}},
+	}
+	m.AddRelationship(Relationship{Path: "/Metadata/ticket.xml", Type: RelTypePrintTicket, ID: "ticket1"})
+	m.AddRelationship(Relationship{Path: "/Metadata/ticket.xml", Type: RelTypePrintTicket, ID: "ticket2"})
+	m.AddRelationship(Relationship{Path: "/Metadata/ticket.xml", Type: "http://example.com/custom", ID: "custom1"})
+	if len(m.Relationships) != 2 {
+		t.Fatalf("Model.AddRelationship() = %v, want 2 relationships", m.Relationships)
+	}
+	b, err := NewEncoder(new(bytes.Buffer)).EncodeToMemory(m)
+	if err != nil {
+		t.Fatalf("Encoder.EncodeToMemory() error = %v", err)
+	}
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(b), int64(len(b))).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(got.Relationships, m.Relationships); diff != nil {
+		t.Errorf("Model.Relationships = %v", diff)
+	}
+	if diff := deep.Equal(got.RootRelationships, m.RootRelationships); diff != nil {
+		t.Errorf("Model.RootRelationships = %v", diff)
+	}
+}
+
+func TestEncoder_writeModel_Comment(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    string
+		wantErr bool
+	}{
+		{"empty", "", "", false},
+		{"base", " generated by go3mf ", "<!-- generated by go3mf --><model", false},
+		{"doubleHyphen", "generated -- by go3mf", "", true},
+		{"endHyphen", "generated by go3mf-", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			e := &Encoder{Comment: tt.comment}
+			m := &Model{Path: DefaultModelPath}
+			if err := e.writeModel(newXMLEncoder(&b, defaultFloatPrecision), m); (err != nil) != tt.wantErr {
+				t.Errorf("Encoder.writeModel() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				var out bytes.Buffer
+				enc := NewEncoder(&out)
+				enc.Comment = tt.comment
+				// Big enough to reach the writer if the attachments were written.
+				data := make([]byte, 64<<10)
+				rand.New(rand.NewSource(1)).Read(data)
+				m.Attachments = []Attachment{{Path: "/thumb.png", ContentType: "image/png", Stream: bytes.NewBuffer(data)}}
+				if err := enc.Encode(m); err == nil || out.Len() != 0 {
+					t.Errorf("Encoder.Encode() error = %v, wrote %d bytes", err, out.Len())
+				}
+				return
+			}
+			if !bytes.Contains(b.Bytes(), []byte(tt.want)) {
+				t.Errorf("Encoder.writeModel() = %s, want %s", b.String(), tt.want)
+				return
+			}
+			newModel := new(Model)
+			newModel.Path = m.Path
+			if err := UnmarshalModel(b.Bytes(), newModel); err != nil {
+				t.Errorf("Encoder.writeModel() malformed = %v", err)
+				return
+			}
+			if diff := deep.Equal(newModel, m); diff != nil {
+				t.Errorf("Encoder.writeModel() = %v", diff)
+			}
+		})
+	}
+}
+
+func TestMarshalObject(t *testing.T) {
+	spec.Register(fakeSpec.Namespace, new(qmExtension))
+	obj := &Object{ID: 2, Name: "box", PID: 1, PIndex: 1, AnyAttr: spec.AnyAttr{&fakeAttr{Value: "obj_fake"}}, Mesh: &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1.5, 0, 0}, {0, 1, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 1, P2: 1, P3: 1}}},
+	}}
+	m := &Model{Extensions: []Extension{fakeSpec}, Resources: Resources{
+		Assets:  []Asset{&BaseMaterials{ID: 1, Materials: []Base{{Name: "a"}, {Name: "b"}}}},
+		Objects: []*Object{{ID: 1, Mesh: new(Mesh)}, obj},
+	}}
+	b, err := MarshalObject(m, 2)
+	if err != nil {
+		t.Fatalf("MarshalObject() error = %v", err)
+	}
+	want := `<object xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" xmlns:qm="http://dummy.com/fake_ext" ` +
+		`id="2" name="box" pid="1" pindex="1" qm:value="obj_fake"><mesh><vertices>` +
+		`<vertex x="0.0000" y="0.0000" z="0.0000"/><vertex x="1.5000" y="0.0000" z="0.0000"/><vertex x="0.0000" y="1.0000" z="0.0000"/>` +
+		`</vertices><triangles><triangle v1="0" v2="1" v3="2"/></triangles></mesh></object>`
+	if string(b) != want {
+		t.Errorf("MarshalObject() = %s, want %s", b, want)
+	}
+	if err := xml.Unmarshal(b, new(struct{})); err != nil {
+		t.Errorf("MarshalObject() is not well formed: %v", err)
+	}
+	if _, err := MarshalObject(m, 3); !errors.Is(err, specerr.ErrMissingResource) {
+		t.Errorf("MarshalObject() error = %v, want %v", err, specerr.ErrMissingResource)
+	}
+}
+
+func TestMarshalModel_NamespaceOrder(t *testing.T) {
+	barSpec := Extension{Namespace: "http://dummy.com/bar", LocalName: "bar"}
+	want := `<model xmlns="` + Namespace + `" unit="millimeter" xml:lang="" ` +
+		`xmlns:bar="http://dummy.com/bar" xmlns:foo="http://dummy.com/foo" xmlns:qm="http://dummy.com/fake_ext" requiredextensions="qm">`
+	for _, exts := range [][]Extension{{fakeSpec, fooSpec, barSpec}, {barSpec, fakeSpec, fooSpec}, {fooSpec, barSpec, fakeSpec}} {
+		b, err := MarshalModel(&Model{Extensions: exts})
+		if err != nil {
+			t.Fatalf("MarshalModel() error = %v", err)
+		}
+		if got := string(b); !strings.HasPrefix(got, want) {
+			t.Errorf("MarshalModel() = %s, want prefix %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/hpinc/go3mf/spec"
+)
+
+// EqualOptions configures how Model.Equal compares two models.
+// The zero value compares every field exactly.
+//
+// The resource assets and the extension elements, such as Model.Any and Mesh.Any,
+// are always compared exactly, IgnoreUUIDs and IgnoreAttrOrder do not apply to them.
+type EqualOptions struct {
+	// Tolerance is the maximum absolute difference allowed
+	// between vertex coordinates and transform components.
+	Tolerance float32
+	// IgnoreUUIDs ignores the UUID field of the extension attributes,
+	// such as the ones generated by the production extension.
+	IgnoreUUIDs bool
+	// IgnoreAttrOrder compares the extension attributes and
+	// the model extensions regardless of their order.
+	IgnoreAttrOrder bool
+	// IgnoreAttachmentOrder compares the attachments regardless of their order.
+	IgnoreAttachmentOrder bool
+}
+
+// Equal reports whether m and other describe the same model according to opts.
+//
+// The element counts are compared before any other field,
+// so models with different sizes are rejected without visiting their geometry.
+// An unset transform is equal to the identity.
+// Attachment streams are not read, only their path, content type
+// and relationships are compared.
+func (m *Model) Equal(other *Model, opts EqualOptions) bool {
+	if m == other {
+		return true
+	}
+	if m == nil || other == nil || !m.sameCounts(other) {
+		return false
+	}
+	e := equaler(opts)
+	return m.Path == other.Path && m.CoreNamespace == other.CoreNamespace && m.Language == other.Language &&
+		m.Units == other.Units && m.Thumbnail == other.Thumbnail &&
+		e.extensions(m.Extensions, other.Extensions) &&
+		reflect.DeepEqual(m.Metadata, other.Metadata) &&
+		reflect.DeepEqual(m.RootRelationships, other.RootRelationships) &&
+		reflect.DeepEqual(m.Relationships, other.Relationships) &&
+		reflect.DeepEqual(m.Any, other.Any) &&
+		e.attrs(m.AnyAttr, other.AnyAttr) &&
+		e.attachments(m.Attachments, other.Attachments) &&
+		e.resources(&m.Resources, &other.Resources) &&
+		e.build(&m.Build, &other.Build) &&
+		e.childs(m.Childs, other.Childs)
+}
+
+func (m *Model) sameCounts(other *Model) bool {
+	if len(m.Attachments) != len(other.Attachments) ||
+		len(m.Extensions) != len(other.Extensions) ||
+		len(m.Metadata) != len(other.Metadata) ||
+		len(m.Build.Items) != len(other.Build.Items) ||
+		len(m.Childs) != len(other.Childs) ||
+		!m.Resources.sameCounts(&other.Resources) {
+		return false
+	}
+	for path, c := range m.Childs {
+		oc, ok := other.Childs[path]
+		if !ok || !c.Resources.sameCounts(&oc.Resources) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rs *Resources) sameCounts(other *Resources) bool {
+	if len(rs.Assets) != len(other.Assets) || len(rs.Objects) != len(other.Objects) {
+		return false
+	}
+	for i, o := range rs.Objects {
+		oo := other.Objects[i]
+		if (o.Mesh == nil) != (oo.Mesh == nil) || (o.Components == nil) != (oo.Components == nil) {
+			return false
+		}
+		if o.Mesh != nil && (len(o.Mesh.Vertices.Vertex) != len(oo.Mesh.Vertices.Vertex) ||
+			len(o.Mesh.Triangles.Triangle) != len(oo.Mesh.Triangles.Triangle)) {
+			return false
+		}
+		if o.Components != nil && len(o.Components.Component) != len(oo.Components.Component) {
+			return false
+		}
+	}
+	return true
+}
+
+type equaler EqualOptions
+
+func (e equaler) float(a, b float32) bool {
+	d := a - b
+	return d <= e.Tolerance && d >= -e.Tolerance
+}
+
+func (e equaler) transform(a, b Matrix) bool {
+	if a == (Matrix{}) {
+		a = Identity()
+	}
+	if b == (Matrix{}) {
+		b = Identity()
+	}
+	for i := range a {
+		if !e.float(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e equaler) extensions(a, b []Extension) bool {
+	if e.IgnoreAttrOrder {
+		a, b = sortedExtensions(a), sortedExtensions(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedExtensions(exts []Extension) []Extension {
+	s := append([]Extension(nil), exts...)
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Namespace != s[j].Namespace {
+			return s[i].Namespace < s[j].Namespace
+		}
+		if s[i].LocalName != s[j].LocalName {
+			return s[i].LocalName < s[j].LocalName
+		}
+		return !s[i].IsRequired && s[j].IsRequired
+	})
+	return s
+}
+
+func (e equaler) attrs(a, b spec.AnyAttr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if !e.IgnoreAttrOrder {
+		for i := range a {
+			if !e.attr(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	// Match the groups by namespace in both directions,
+	// so repeated groups in one side cannot hide a missing one.
+	for _, att := range a {
+		if oatt := b.Get(att.Namespace()); oatt == nil || !e.attr(att, oatt) {
+			return false
+		}
+	}
+	for _, oatt := range b {
+		if att := a.Get(oatt.Namespace()); att == nil || !e.attr(att, oatt) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e equaler) attr(a, b spec.AttrGroup) bool {
+	if e.IgnoreUUIDs {
+		return reflect.DeepEqual(withoutUUID(a), withoutUUID(b))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// withoutUUID returns a copy of the struct pointed by v
+// with the UUID field cleared.
+func withoutUUID(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return v
+	}
+	cp := reflect.New(rv.Elem().Type()).Elem()
+	cp.Set(rv.Elem())
+	if f := cp.FieldByName("UUID"); f.IsValid() && f.Kind() == reflect.String && f.CanSet() {
+		f.SetString("")
+	}
+	return cp.Interface()
+}
+
+func (e equaler) attachments(a, b []Attachment) bool {
+	if e.IgnoreAttachmentOrder {
+		a, b = sortedAttachments(a), sortedAttachments(b)
+	}
+	for i := range a {
+		if a[i].Path != b[i].Path || a[i].ContentType != b[i].ContentType ||
+			!reflect.DeepEqual(a[i].Relationships, b[i].Relationships) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedAttachments(atts []Attachment) []Attachment {
+	s := append([]Attachment(nil), atts...)
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Path < s[j].Path
+	})
+	return s
+}
+
+func (e equaler) resources(a, b *Resources) bool {
+	if !e.attrs(a.AnyAttr, b.AnyAttr) {
+		return false
+	}
+	for i := range a.Assets {
+		if !reflect.DeepEqual(a.Assets[i], b.Assets[i]) {
+			return false
+		}
+	}
+	for i := range a.Objects {
+		if !e.object(a.Objects[i], b.Objects[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e equaler) object(a, b *Object) bool {
+	if a.ID != b.ID || a.Name != b.Name || a.PartNumber != b.PartNumber ||
+		a.Thumbnail != b.Thumbnail || a.PID != b.PID || a.PIndex != b.PIndex ||
+		a.Type != b.Type || !e.metadata(&a.Metadata, &b.Metadata) || !e.attrs(a.AnyAttr, b.AnyAttr) {
+		return false
+	}
+	if a.Components != nil {
+		if !e.attrs(a.Components.AnyAttr, b.Components.AnyAttr) {
+			return false
+		}
+		for i, c := range a.Components.Component {
+			oc := b.Components.Component[i]
+			if c.ObjectID != oc.ObjectID || !e.transform(c.Transform, oc.Transform) || !e.attrs(c.AnyAttr, oc.AnyAttr) {
+				return false
+			}
+		}
+	}
+	if a.Mesh != nil {
+		return e.mesh(a.Mesh, b.Mesh)
+	}
+	return true
+}
+
+func (e equaler) mesh(a, b *Mesh) bool {
+	if !e.attrs(a.AnyAttr, b.AnyAttr) || !reflect.DeepEqual(a.Any, b.Any) ||
+		!e.attrs(a.Vertices.AnyAttr, b.Vertices.AnyAttr) || !e.attrs(a.Triangles.AnyAttr, b.Triangles.AnyAttr) {
+		return false
+	}
+	for i, t := range a.Triangles.Triangle {
+		ot := b.Triangles.Triangle[i]
+		if t.V1 != ot.V1 || t.V2 != ot.V2 || t.V3 != ot.V3 || t.PID != ot.PID ||
+			t.P1 != ot.P1 || t.P2 != ot.P2 || t.P3 != ot.P3 || !e.attrs(t.AnyAttr, ot.AnyAttr) {
+			return false
+		}
+	}
+	for i, v := range a.Vertices.Vertex {
+		ov := b.Vertices.Vertex[i]
+		if !e.float(v[0], ov[0]) || !e.float(v[1], ov[1]) || !e.float(v[2], ov[2]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e equaler) metadata(a, b *MetadataGroup) bool {
+	return reflect.DeepEqual(a.Metadata, b.Metadata) && e.attrs(a.AnyAttr, b.AnyAttr)
+}
+
+func (e equaler) build(a, b *Build) bool {
+	if !e.attrs(a.AnyAttr, b.AnyAttr) {
+		return false
+	}
+	for i, item := range a.Items {
+		oitem := b.Items[i]
+		if item.ObjectID != oitem.ObjectID || item.PartNumber != oitem.PartNumber ||
+			!e.transform(item.Transform, oitem.Transform) ||
+			!e.metadata(&item.Metadata, &oitem.Metadata) || !e.attrs(item.AnyAttr, oitem.AnyAttr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e equaler) childs(a, b map[string]*ChildModel) bool {
+	for path, c := range a {
+		oc := b[path]
+		if !reflect.DeepEqual(c.Relationships, oc.Relationships) || !reflect.DeepEqual(c.Any, oc.Any) ||
+			!e.resources(&c.Resources, &oc.Resources) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/hpinc/go3mf/spec"
+)
+
+type uuidAttr struct {
+	UUID string
+}
+
+func (u uuidAttr) Namespace() string { return "http://dummy.com/uuid" }
+
+func (u uuidAttr) Marshal3MF(spec.Encoder, *xml.StartElement) error { return nil }
+
+func (u *uuidAttr) Unmarshal3MFAttr(spec.XMLAttr) error { return nil }
+
+func TestModel_Equal(t *testing.T) {
+	newModel := func(uuid string, offset float32) *Model {
+		return &Model{
+			Path:       "/3D/model.model",
+			Extensions: []Extension{{Namespace: "a"}, {Namespace: "b"}},
+			Resources: Resources{
+				Assets: []Asset{&BaseMaterials{ID: 1, Materials: []Base{{Name: "a"}}}},
+				Objects: []*Object{
+					{ID: 2, Mesh: &Mesh{
+						Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1 + offset, 0, 0}, {0, 1, 0}}},
+						Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+					}},
+					{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 2}}}},
+				},
+			},
+			Build: Build{Items: []*Item{{ObjectID: 3, AnyAttr: spec.AnyAttr{
+				&uuidAttr{UUID: uuid}, &fakeAttr{Value: "/3D/model.model"},
+			}}}},
+			Attachments: []Attachment{{Path: "/a.png"}, {Path: "/b.png"}},
+			Childs:      map[string]*ChildModel{"/other.model": {Resources: Resources{Objects: []*Object{{ID: 1}}}}},
+		}
+	}
+	swap := func(m *Model) *Model {
+		m.Extensions[0], m.Extensions[1] = m.Extensions[1], m.Extensions[0]
+		att := m.Build.Items[0].AnyAttr
+		att[0], att[1] = att[1], att[0]
+		m.Attachments[0], m.Attachments[1] = m.Attachments[1], m.Attachments[0]
+		return m
+	}
+	tests := []struct {
+		name  string
+		m     *Model
+		other *Model
+		opts  EqualOptions
+		want  bool
+	}{
+		{"nil", newModel("a", 0), nil, EqualOptions{}, false},
+		{"same", newModel("a", 0), newModel("a", 0), EqualOptions{}, true},
+		{"count", newModel("a", 0), &Model{Path: "/3D/model.model"}, EqualOptions{}, false},
+		{"uuid", newModel("a", 0), newModel("b", 0), EqualOptions{}, false},
+		{"ignoreUUID", newModel("a", 0), newModel("b", 0), EqualOptions{IgnoreUUIDs: true}, true},
+		{"float", newModel("a", 0), newModel("a", 1e-5), EqualOptions{}, false},
+		{"tolerance", newModel("a", 0), newModel("a", 1e-5), EqualOptions{Tolerance: 1e-4}, true},
+		{"order", newModel("a", 0), swap(newModel("a", 0)), EqualOptions{}, false},
+		{"ignoreOrder", newModel("a", 0), swap(newModel("a", 0)), EqualOptions{IgnoreAttrOrder: true, IgnoreAttachmentOrder: true}, true},
+		{"repeatedExtension", func() *Model {
+			m := newModel("a", 0)
+			m.Extensions[1] = m.Extensions[0]
+			return m
+		}(), newModel("a", 0), EqualOptions{IgnoreAttrOrder: true}, false},
+		{"repeatedAttr", func() *Model {
+			m := newModel("a", 0)
+			m.Build.Items[0].AnyAttr[1] = m.Build.Items[0].AnyAttr[0]
+			return m
+		}(), newModel("a", 0), EqualOptions{IgnoreAttrOrder: true}, false},
+		{"identity", &Model{Build: Build{Items: []*Item{{ObjectID: 1}}}}, &Model{Build: Build{Items: []*Item{{ObjectID: 1, Transform: Identity()}}}}, EqualOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Equal(tt.other, tt.opts); got != tt.want {
+				t.Errorf("Model.Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -36,6 +36,8 @@ var (
 	ErrRecursion              = errors.New("MUST NOT contain recursive references")
 	ErrInvalidObject          = errors.New("MUST contain a mesh or components")
 	ErrMeshConsistency        = errors.New("mesh has non-manifold edges without consistent triangle orientation")
+	ErrNonRigidTransform      = errors.New("transform contains shear, non-uniform scale or mirroring")
+	ErrDegenerateTriangle     = errors.New("triangle vertices are collinear")
 )
 
 type Level struct {
@@ -142,3 +144,56 @@ func (e *ParseAttrError) Error() string {
 	}
 	return fmt.Sprintf("error parsing %s attribute '%s'", req, e.Name)
 }
+
+// ParseMatrixError is returned when a transform attribute is not a valid matrix.
+// It unwraps to the equivalent *ParseAttrError.
+type ParseMatrixError struct {
+	Value string
+}
+
+func NewParseMatrixError(raw string) *ParseMatrixError {
+	return &ParseMatrixError{raw}
+}
+
+func (e *ParseMatrixError) Error() string {
+	return fmt.Sprintf("error parsing optional attribute 'transform' with value %q", e.Value)
+}
+
+// Unwrap returns the *ParseAttrError of the transform attribute.
+func (e *ParseMatrixError) Unwrap() error {
+	return NewParseAttrError("transform", false)
+}
+
+// PIndexError is returned when the default property index of an object
+// is not within the bounds of the property group referenced by its pid.
+type PIndexError struct {
+	ObjectID uint32
+	PID      uint32
+	PIndex   uint32
+	Len      int
+}
+
+func (e *PIndexError) Error() string {
+	return fmt.Sprintf("object %d pindex %d is out of the bounds of resource %d with %d properties", e.ObjectID, e.PIndex, e.PID, e.Len)
+}
+
+// Unwrap returns ErrIndexOutOfBounds.
+func (e *PIndexError) Unwrap() error {
+	return ErrIndexOutOfBounds
+}
+
+// PartNameError is returned when a part name does not
+// conform to the OPC part name syntax.
+type PartNameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *PartNameError) Error() string {
+	return fmt.Sprintf("part name %q %s", e.Name, e.Reason)
+}
+
+// Unwrap returns ErrOPCPartName.
+func (e *PartNameError) Unwrap() error {
+	return ErrOPCPartName
+}
@@ -4,6 +4,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -50,6 +51,16 @@ func (e *List) Unwrap() error {
 	return e.Errors[0]
 }
 
+// Is reports whether any error of the list matches target.
+func (e *List) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // Append is a helper function that will append more errors
 // onto an List in order to create a larger multi-error.
 //
@@ -50,6 +50,20 @@ func TestList_Error_multiple(t *testing.T) {
 	}
 }
 
+func TestList_Is(t *testing.T) {
+	foo, bar := errors.New("foo"), errors.New("bar")
+	multi := &List{Errors: []error{foo, Wrap(bar, "item")}}
+	if !errors.Is(multi, foo) {
+		t.Error("List.Is() expected foo")
+	}
+	if !errors.Is(multi, bar) {
+		t.Error("List.Is() expected wrapped bar")
+	}
+	if errors.Is(multi, errors.New("baz")) {
+		t.Error("List.Is() unexpected baz")
+	}
+}
+
 func TestAppend_Error(t *testing.T) {
 	original := &List{
 		Errors: []error{errors.New("foo")},
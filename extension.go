@@ -0,0 +1,226 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/hpinc/go3mf/spec"
+)
+
+// ErrCoreSpec is returned when trying to remove the core spec from a model.
+var ErrCoreSpec = errors.New("go3mf: the core spec cannot be removed")
+
+// ErrSpecInUse is returned by Model.RemoveSpec when removing the spec
+// would leave references to missing objects or assets.
+var ErrSpecInUse = errors.New("go3mf: removing the spec leaves dangling references")
+
+// RemoveSpec downgrades the model by removing every trace of the spec
+// identified by namespace: the model extension, the extension attributes,
+// the metadata and extension elements in that namespace and the assets defined by it.
+// Metadata named with the prefix declared for the namespace is also removed.
+//
+// Extension elements and assets are identified by an XMLName or a Namespace
+// method. Child models are kept, as they may still be used by other specs.
+//
+// The model is left untouched and ErrSpecInUse is returned if a build item
+// or component references an object in another model part through the spec,
+// or if an object or triangle references a removed asset.
+func (m *Model) RemoveSpec(namespace string) error {
+	if namespace == Namespace {
+		return ErrCoreSpec
+	}
+	if err := m.checkSpecRemoval(namespace); err != nil {
+		return err
+	}
+	// Decoded metadata names use the prefix declared for the namespace.
+	spaces := []string{namespace}
+	exts := m.Extensions[:0]
+	for _, ext := range m.Extensions {
+		if ext.Namespace != namespace {
+			exts = append(exts, ext)
+		} else if ext.LocalName != "" {
+			spaces = append(spaces, ext.LocalName)
+		}
+	}
+	m.Extensions = exts
+	m.AnyAttr = withoutSpecAttrs(m.AnyAttr, namespace)
+	m.Any = withoutSpecElements(m.Any, namespace)
+	m.Metadata = withoutSpecMetadata(m.Metadata, spaces)
+	m.Build.AnyAttr = withoutSpecAttrs(m.Build.AnyAttr, namespace)
+	for _, item := range m.Build.Items {
+		item.AnyAttr = withoutSpecAttrs(item.AnyAttr, namespace)
+		item.Metadata.AnyAttr = withoutSpecAttrs(item.Metadata.AnyAttr, namespace)
+		item.Metadata.Metadata = withoutSpecMetadata(item.Metadata.Metadata, spaces)
+	}
+	m.Resources.removeSpec(namespace, spaces)
+	for _, c := range m.Childs {
+		c.Any = withoutSpecElements(c.Any, namespace)
+		c.Resources.removeSpec(namespace, spaces)
+	}
+	return nil
+}
+
+func (m *Model) checkSpecRemoval(namespace string) error {
+	rootPath := m.PathOrDefault()
+	for i, item := range m.Build.Items {
+		if path := specObjectPath(item.AnyAttr, namespace); path != "" && path != rootPath {
+			return fmt.Errorf("%w: item %d references %s", ErrSpecInUse, i, path)
+		}
+	}
+	check := func(path string, rs *Resources) error {
+		removed := make(map[uint32]struct{})
+		for _, a := range rs.Assets {
+			if specNamespace(a) == namespace {
+				removed[a.Identify()] = struct{}{}
+			}
+		}
+		for _, o := range rs.Objects {
+			if _, ok := removed[o.PID]; ok && o.PID != 0 {
+				return fmt.Errorf("%w: object %d in %s uses asset %d", ErrSpecInUse, o.ID, path, o.PID)
+			}
+			if o.Mesh != nil {
+				for _, t := range o.Mesh.Triangles.Triangle {
+					if _, ok := removed[t.PID]; ok && t.PID != 0 {
+						return fmt.Errorf("%w: object %d in %s uses asset %d", ErrSpecInUse, o.ID, path, t.PID)
+					}
+				}
+			}
+			if o.Components != nil {
+				for _, c := range o.Components.Component {
+					if cpath := specObjectPath(c.AnyAttr, namespace); cpath != "" && cpath != path {
+						return fmt.Errorf("%w: object %d in %s references %s", ErrSpecInUse, o.ID, path, cpath)
+					}
+				}
+			}
+		}
+		return nil
+	}
+	for _, path := range m.sortedChilds() {
+		if err := check(path, &m.Childs[path].Resources); err != nil {
+			return err
+		}
+	}
+	return check(rootPath, &m.Resources)
+}
+
+func (rs *Resources) removeSpec(namespace string, spaces []string) {
+	rs.AnyAttr = withoutSpecAttrs(rs.AnyAttr, namespace)
+	assets := rs.Assets[:0]
+	for _, a := range rs.Assets {
+		if specNamespace(a) != namespace {
+			assets = append(assets, a)
+		}
+	}
+	if len(assets) == 0 {
+		assets = nil
+	}
+	rs.Assets = assets
+	for _, o := range rs.Objects {
+		o.AnyAttr = withoutSpecAttrs(o.AnyAttr, namespace)
+		o.Metadata.AnyAttr = withoutSpecAttrs(o.Metadata.AnyAttr, namespace)
+		o.Metadata.Metadata = withoutSpecMetadata(o.Metadata.Metadata, spaces)
+		if o.Mesh != nil {
+			o.Mesh.AnyAttr = withoutSpecAttrs(o.Mesh.AnyAttr, namespace)
+			o.Mesh.Any = withoutSpecElements(o.Mesh.Any, namespace)
+			o.Mesh.Vertices.AnyAttr = withoutSpecAttrs(o.Mesh.Vertices.AnyAttr, namespace)
+			o.Mesh.Triangles.AnyAttr = withoutSpecAttrs(o.Mesh.Triangles.AnyAttr, namespace)
+			for i := range o.Mesh.Triangles.Triangle {
+				t := &o.Mesh.Triangles.Triangle[i]
+				t.AnyAttr = withoutSpecAttrs(t.AnyAttr, namespace)
+			}
+		}
+		if o.Components != nil {
+			o.Components.AnyAttr = withoutSpecAttrs(o.Components.AnyAttr, namespace)
+			for _, c := range o.Components.Component {
+				c.AnyAttr = withoutSpecAttrs(c.AnyAttr, namespace)
+			}
+		}
+	}
+}
+
+// specObjectPath returns the object path defined
+// by the attributes of the given namespace.
+func specObjectPath(attrs spec.AnyAttr, namespace string) string {
+	for _, att := range attrs {
+		if att.Namespace() != namespace {
+			continue
+		}
+		if p, ok := att.(objectPather); ok {
+			return p.ObjectPath()
+		}
+	}
+	return ""
+}
+
+// specNamespace returns the namespace of an asset or extension element,
+// or an empty string if it cannot be determined.
+func specNamespace(v interface{}) string {
+	switch v := v.(type) {
+	case interface{ XMLName() xml.Name }:
+		return v.XMLName().Space
+	case interface{ Namespace() string }:
+		return v.Namespace()
+	}
+	return ""
+}
+
+func withoutSpecAttrs(attrs spec.AnyAttr, namespace string) spec.AnyAttr {
+	if len(attrs) == 0 {
+		return attrs
+	}
+	filtered := attrs[:0]
+	for _, att := range attrs {
+		if att.Namespace() != namespace {
+			filtered = append(filtered, att)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+func withoutSpecElements(elems spec.Any, namespace string) spec.Any {
+	if len(elems) == 0 {
+		return elems
+	}
+	filtered := elems[:0]
+	for _, e := range elems {
+		if specNamespace(e) != namespace {
+			filtered = append(filtered, e)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
+
+// withoutSpecMetadata removes the metadata whose name space
+// is any of spaces, which are the namespace and its declared prefixes.
+func withoutSpecMetadata(metadata []Metadata, spaces []string) []Metadata {
+	if len(metadata) == 0 {
+		return metadata
+	}
+	filtered := metadata[:0]
+	for _, md := range metadata {
+		var removed bool
+		for _, space := range spaces {
+			if md.Name.Space == space {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			filtered = append(filtered, md)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return filtered
+}
@@ -0,0 +1,146 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hpinc/go3mf/spec"
+)
+
+type specAsset struct {
+	ID uint32
+}
+
+func (s *specAsset) Identify() uint32 { return s.ID }
+
+func (specAsset) XMLName() xml.Name { return xml.Name{Space: fakeExtension, Local: "asset"} }
+
+func (specAsset) Marshal3MF(spec.Encoder, *xml.StartElement) error { return nil }
+
+func TestModel_RemoveSpec(t *testing.T) {
+	fooAttr := &spec.UnknownAttrs{Space: fooSpace}
+	newModel := func() *Model {
+		return &Model{
+			Extensions: []Extension{fakeSpec, fooSpec},
+			AnyAttr:    spec.AnyAttr{&fakeAttr{}, fooAttr},
+			Any:        spec.Any{&specAsset{}},
+			Metadata:   []Metadata{{Name: xml.Name{Space: fakeExtension, Local: "a"}}, {Name: xml.Name{Local: "Title"}}},
+			Resources: Resources{
+				Assets: []Asset{&specAsset{ID: 1}, &BaseMaterials{ID: 2}},
+				Objects: []*Object{
+					{ID: 3, PID: 2, AnyAttr: spec.AnyAttr{&fakeAttr{}}, Mesh: &Mesh{
+						AnyAttr:   spec.AnyAttr{&fakeAttr{}},
+						Triangles: Triangles{Triangle: []Triangle{{PID: 2, AnyAttr: spec.AnyAttr{&fakeAttr{}}}}},
+					}},
+					{ID: 4, Components: &Components{Component: []*Component{
+						{ObjectID: 3, AnyAttr: spec.AnyAttr{&fakeAttr{Value: DefaultModelPath}, fooAttr}},
+					}}},
+				},
+			},
+			Build: Build{AnyAttr: spec.AnyAttr{&fakeAttr{}}, Items: []*Item{{ObjectID: 4, AnyAttr: spec.AnyAttr{&fakeAttr{}}}}},
+			Childs: map[string]*ChildModel{"/other.model": {Resources: Resources{
+				Assets: []Asset{&specAsset{ID: 1}},
+			}}},
+		}
+	}
+	want := &Model{
+		Extensions: []Extension{fooSpec},
+		AnyAttr:    spec.AnyAttr{fooAttr},
+		Metadata:   []Metadata{{Name: xml.Name{Local: "Title"}}},
+		Resources: Resources{
+			Assets: []Asset{&BaseMaterials{ID: 2}},
+			Objects: []*Object{
+				{ID: 3, PID: 2, Mesh: &Mesh{Triangles: Triangles{Triangle: []Triangle{{PID: 2}}}}},
+				{ID: 4, Components: &Components{Component: []*Component{{ObjectID: 3, AnyAttr: spec.AnyAttr{fooAttr}}}}},
+			},
+		},
+		Build:  Build{Items: []*Item{{ObjectID: 4}}},
+		Childs: map[string]*ChildModel{"/other.model": {}},
+	}
+	tests := []struct {
+		name    string
+		m       *Model
+		ns      string
+		want    *Model
+		wantErr error
+	}{
+		{"core", newModel(), Namespace, newModel(), ErrCoreSpec},
+		{"undeclared", new(Model), fakeExtension, new(Model), nil},
+		{"remove", newModel(), fakeExtension, want, nil},
+		{"item", func() *Model {
+			m := newModel()
+			m.Build.Items[0].AnyAttr = spec.AnyAttr{&fakeAttr{Value: "/other.model"}}
+			return m
+		}(), fakeExtension, nil, ErrSpecInUse},
+		{"component", func() *Model {
+			m := newModel()
+			m.Resources.Objects[1].Components.Component[0].AnyAttr = spec.AnyAttr{&fakeAttr{Value: "/other.model"}}
+			return m
+		}(), fakeExtension, nil, ErrSpecInUse},
+		{"asset", func() *Model {
+			m := newModel()
+			m.Resources.Objects[0].Mesh.Triangles.Triangle[0].PID = 1
+			return m
+		}(), fakeExtension, nil, ErrSpecInUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.m.RemoveSpec(tt.ns)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Model.RemoveSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil {
+				if diff := deep.Equal(tt.m, tt.want); diff != nil {
+					t.Errorf("Model.RemoveSpec() = %v", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestModel_RemoveSpec_Decoded(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" xmlns:v="http://example.com/vendor" unit="millimeter" xml:lang="en-US">
+	<metadata name="Title">cube</metadata>
+	<metadata name="v:layer" preserve="1">0.1</metadata>
+	<resources>
+		<object id="1" type="model">
+			<metadatagroup><metadata name="v:infill">20</metadata></metadatagroup>
+			<mesh>
+				<vertices>
+					<vertex x="0" y="0" z="0" /><vertex x="1" y="0" z="0" /><vertex x="0" y="1" z="0" /><vertex x="0" y="0" z="1" />
+				</vertices>
+				<triangles>
+					<triangle v1="0" v2="1" v3="2" /><triangle v1="0" v2="3" v3="1" /><triangle v1="0" v2="2" v3="3" /><triangle v1="1" v2="3" v3="2" />
+				</triangles>
+			</mesh>
+		</object>
+	</resources>
+	<build><item objectid="1" /></build>
+</model>`
+	m := new(Model)
+	if err := UnmarshalModel([]byte(data), m); err != nil {
+		t.Fatalf("UnmarshalModel() error = %v", err)
+	}
+	if len(m.Metadata) != 2 || len(m.Resources.Objects[0].Metadata.Metadata) != 1 {
+		t.Fatalf("UnmarshalModel() = %+v", m)
+	}
+	if err := m.RemoveSpec("http://example.com/vendor"); err != nil {
+		t.Fatalf("Model.RemoveSpec() error = %v", err)
+	}
+	want := []Metadata{{Name: xml.Name{Local: "Title"}, Value: "cube"}}
+	if diff := deep.Equal(m.Metadata, want); diff != nil {
+		t.Errorf("Model.RemoveSpec() = %v", diff)
+	}
+	if md := m.Resources.Objects[0].Metadata.Metadata; md != nil {
+		t.Errorf("Model.RemoveSpec() object metadata = %v", md)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Model.Validate() error = %v", err)
+	}
+}
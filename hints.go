@@ -0,0 +1,63 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import "encoding/xml"
+
+const (
+	// SlicerHintsNamespace is the vendor namespace of the model metadata
+	// used to store the slicer hints.
+	SlicerHintsNamespace = "http://schemas.hp.com/go3mf/slicerhints/2021/06"
+	// SlicerHintsLocalName is the prefix declared for SlicerHintsNamespace
+	// by Model.SetSlicerHint if the model does not declare it yet.
+	SlicerHintsLocalName = "sh"
+)
+
+// SetSlicerHint stores a slicer setting as a model metadata named key
+// in SlicerHintsNamespace, replacing its previous value if any.
+// The metadata is marked as preserved so consumers keep it when
+// editing the model, and the namespace is added to the model Extensions
+// as not required if it is not already declared.
+//
+// As the decoded metadata, the name space is the prefix
+// declared for the namespace. key must be a valid XML local name.
+func (m *Model) SetSlicerHint(key, value string) {
+	prefix := m.slicerHintsPrefix()
+	if prefix == "" {
+		prefix = SlicerHintsLocalName
+		m.Extensions = append(m.Extensions, Extension{Namespace: SlicerHintsNamespace, LocalName: prefix})
+	}
+	for i := range m.Metadata {
+		if md := &m.Metadata[i]; md.Name.Local == key && (md.Name.Space == prefix || md.Name.Space == SlicerHintsNamespace) {
+			md.Value = value
+			md.Preserve = true
+			return
+		}
+	}
+	m.Metadata = append(m.Metadata, Metadata{
+		Name: xml.Name{Space: prefix, Local: key}, Value: value, Type: "xs:string", Preserve: true,
+	})
+}
+
+// SlicerHints returns the slicer settings stored in the model metadata
+// under SlicerHintsNamespace, indexed by name.
+func (m *Model) SlicerHints() map[string]string {
+	hints := make(map[string]string)
+	prefix := m.slicerHintsPrefix()
+	for _, md := range m.Metadata {
+		if md.Name.Space == SlicerHintsNamespace || (prefix != "" && md.Name.Space == prefix) {
+			hints[md.Name.Local] = md.Value
+		}
+	}
+	return hints
+}
+
+func (m *Model) slicerHintsPrefix() string {
+	for _, ext := range m.Extensions {
+		if ext.Namespace == SlicerHintsNamespace {
+			return ext.LocalName
+		}
+	}
+	return ""
+}
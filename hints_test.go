@@ -0,0 +1,49 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestModel_SlicerHints(t *testing.T) {
+	m := &Model{Metadata: []Metadata{{Name: xml.Name{Local: "Title"}, Value: "cube"}}}
+	m.SetSlicerHint("layerHeight", "0.2")
+	m.SetSlicerHint("infill", "20")
+	m.SetSlicerHint("layerHeight", "0.1")
+	want := map[string]string{"layerHeight": "0.1", "infill": "20"}
+	if got := m.SlicerHints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Model.SlicerHints() = %v, want %v", got, want)
+	}
+	if len(m.Extensions) != 1 || len(m.Metadata) != 3 || !m.Metadata[1].Preserve {
+		t.Errorf("Model.SetSlicerHint() = %+v", m)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("Model.Validate() error = %v", err)
+	}
+	b, err := MarshalModel(m)
+	if err != nil {
+		t.Fatalf("MarshalModel() error = %v", err)
+	}
+	newModel := new(Model)
+	if err := UnmarshalModel(b, newModel); err != nil {
+		t.Fatalf("UnmarshalModel() error = %v, s = %s", err, string(b))
+	}
+	if got := newModel.SlicerHints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Model.SlicerHints() after round-trip = %v, want %v, s = %s", got, want, string(b))
+	}
+	newModel.Extensions[0].LocalName = "hints"
+	for i := range newModel.Metadata {
+		if newModel.Metadata[i].Name.Space == SlicerHintsLocalName {
+			newModel.Metadata[i].Name.Space = "hints"
+		}
+	}
+	newModel.SetSlicerHint("infill", "30")
+	want["infill"] = "30"
+	if got := newModel.SlicerHints(); !reflect.DeepEqual(got, want) || len(newModel.Metadata) != 3 {
+		t.Errorf("Model.SlicerHints() with custom prefix = %v, want %v", got, want)
+	}
+}
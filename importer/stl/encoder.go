@@ -0,0 +1,138 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package stl
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/hpinc/go3mf"
+)
+
+// Format defines the encoding of a stl.
+type Format int
+
+// Supported stl encodings.
+const (
+	Binary Format = iota
+	ASCII
+)
+
+// ErrObjectNotFound is returned by Encoder.EncodeObject
+// when the root model does not contain the object.
+var ErrObjectNotFound = errors.New("stl: object not found")
+
+// Encoder can encode a mesh into a stl.
+// Each facet normal is computed from its vertices,
+// degenerate triangles are written with a zero normal.
+type Encoder struct {
+	Format Format
+	w      io.Writer
+}
+
+// NewEncoder creates a new encoder that writes a binary stl.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+	}
+}
+
+// Encode writes the triangles of m,
+// skipping the ones that reference out of range vertices.
+func (e *Encoder) Encode(m *go3mf.Mesh) error {
+	w := bufio.NewWriter(e.w)
+	var err error
+	if e.Format == ASCII {
+		err = encodeASCII(w, m)
+	} else {
+		err = encodeBinary(w, m)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// EncodeObject writes the geometry of the object of the root model
+// identified by objectID, expanding its components as in go3mf.Model.ObjectMesh.
+func (e *Encoder) EncodeObject(m *go3mf.Model, objectID uint32) error {
+	mesh, ok := m.ObjectMesh(objectID)
+	if !ok {
+		return ErrObjectNotFound
+	}
+	return e.Encode(mesh)
+}
+
+func encodeBinary(w *bufio.Writer, m *go3mf.Mesh) error {
+	var count uint32
+	m.Faces(func(uint32, go3mf.Point3D, go3mf.Point3D, go3mf.Point3D) bool {
+		count++
+		return true
+	})
+	var header [80]byte
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	var buf [50]byte
+	binary.LittleEndian.PutUint32(buf[:4], count)
+	if _, err := w.Write(buf[:4]); err != nil {
+		return err
+	}
+	var err error
+	m.Faces(func(_ uint32, a, b, c go3mf.Point3D) bool {
+		n := faceNormal(a, b, c)
+		for i, v := range [12]float32{n[0], n[1], n[2], a[0], a[1], a[2], b[0], b[1], b[2], c[0], c[1], c[2]} {
+			binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+		}
+		_, err = w.Write(buf[:])
+		return err == nil
+	})
+	return err
+}
+
+func encodeASCII(w *bufio.Writer, m *go3mf.Mesh) error {
+	var err error
+	write := func(s string) {
+		if err == nil {
+			_, err = w.WriteString(s)
+		}
+	}
+	writePoint := func(prefix string, p go3mf.Point3D) {
+		write(prefix)
+		for _, v := range p {
+			write(" ")
+			write(strconv.FormatFloat(float64(v), 'e', -1, 32))
+		}
+		write("\n")
+	}
+	write("solid\n")
+	m.Faces(func(_ uint32, a, b, c go3mf.Point3D) bool {
+		writePoint("  facet normal", faceNormal(a, b, c))
+		write("    outer loop\n")
+		writePoint("      vertex", a)
+		writePoint("      vertex", b)
+		writePoint("      vertex", c)
+		write("    endloop\n  endfacet\n")
+		return err == nil
+	})
+	write("endsolid\n")
+	return err
+}
+
+// faceNormal returns the unit normal of the triangle abc,
+// or zero if the triangle is degenerate.
+func faceNormal(a, b, c go3mf.Point3D) go3mf.Point3D {
+	ux, uy, uz := float64(b[0]-a[0]), float64(b[1]-a[1]), float64(b[2]-a[2])
+	vx, vy, vz := float64(c[0]-a[0]), float64(c[1]-a[1]), float64(c[2]-a[2])
+	nx, ny, nz := uy*vz-uz*vy, uz*vx-ux*vz, ux*vy-uy*vx
+	l := math.Sqrt(nx*nx + ny*ny + nz*nz)
+	if l == 0 || math.IsNaN(l) || math.IsInf(l, 0) {
+		return go3mf.Point3D{}
+	}
+	return go3mf.Point3D{float32(nx / l), float32(ny / l), float32(nz / l)}
+}
@@ -0,0 +1,117 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package stl
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hpinc/go3mf"
+)
+
+func faces(m *go3mf.Mesh) [][3]go3mf.Point3D {
+	var f [][3]go3mf.Point3D
+	m.Faces(func(_ uint32, a, b, c go3mf.Point3D) bool {
+		f = append(f, [3]go3mf.Point3D{a, b, c})
+		return true
+	})
+	return f
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	m := createMeshTriangle(0).Mesh
+	m.Triangles.Triangle = append(m.Triangles.Triangle, go3mf.Triangle{V1: 0, V2: 1, V3: 100})
+	want := faces(m)
+	for _, format := range []Format{Binary, ASCII} {
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		e.Format = format
+		if err := e.Encode(m); err != nil {
+			t.Fatalf("Encoder.Encode() error = %v", err)
+		}
+		got := new(go3mf.Mesh)
+		var err error
+		if format == ASCII {
+			if !strings.HasPrefix(buf.String(), "solid") {
+				t.Errorf("Encoder.Encode() ascii header = %s", buf.String()[:10])
+			}
+			err = (&asciiDecoder{r: &buf}).decode(context.Background(), got)
+		} else {
+			if buf.Len() != 84+50*len(want) {
+				t.Errorf("Encoder.Encode() binary size = %d, want %d", buf.Len(), 84+50*len(want))
+			}
+			err = (&binaryDecoder{r: &buf}).decode(context.Background(), got)
+		}
+		if err != nil {
+			t.Fatalf("decode() error = %v", err)
+		}
+		if got := faces(got); !reflect.DeepEqual(got, want) {
+			t.Errorf("Encoder.Encode() format %d = %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestEncoder_Encode_Normals(t *testing.T) {
+	m := &go3mf.Mesh{
+		Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {2, 0, 0}, {0, 2, 0}, {4, 0, 0}}},
+		Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+			{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 3},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	b := buf.Bytes()
+	normal := func(face int) [3]float32 {
+		var n [3]float32
+		for i := range n {
+			n[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[84+face*50+i*4:]))
+		}
+		return n
+	}
+	if got := normal(0); got != [3]float32{0, 0, 1} {
+		t.Errorf("Encoder.Encode() normal = %v, want [0 0 1]", got)
+	}
+	if got := normal(1); got != [3]float32{} {
+		t.Errorf("Encoder.Encode() degenerate normal = %v, want [0 0 0]", got)
+	}
+}
+
+func TestEncoder_EncodeObject(t *testing.T) {
+	tri := &go3mf.Mesh{
+		Vertices:  go3mf.Vertices{Vertex: []go3mf.Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+		Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{{V1: 0, V2: 1, V3: 2}}},
+	}
+	m := &go3mf.Model{Resources: go3mf.Resources{Objects: []*go3mf.Object{
+		{ID: 1, Mesh: tri},
+		{ID: 2, Components: &go3mf.Components{Component: []*go3mf.Component{
+			{ObjectID: 1, Transform: go3mf.Identity().Translate(5, 0, 0)},
+			{ObjectID: 1, Transform: go3mf.Matrix{-1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}},
+		}}},
+	}}}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).EncodeObject(m, 2); err != nil {
+		t.Fatalf("Encoder.EncodeObject() error = %v", err)
+	}
+	got := new(go3mf.Mesh)
+	if err := (&binaryDecoder{r: &buf}).decode(context.Background(), got); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	want := [][3]go3mf.Point3D{
+		{{5, 0, 0}, {6, 0, 0}, {5, 1, 0}},
+		{{0, 0, 0}, {0, 1, 0}, {-1, 0, 0}},
+	}
+	if got := faces(got); !reflect.DeepEqual(got, want) {
+		t.Errorf("Encoder.EncodeObject() = %v, want %v", got, want)
+	}
+	if err := NewEncoder(&buf).EncodeObject(m, 3); err != ErrObjectNotFound {
+		t.Errorf("Encoder.EncodeObject() error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
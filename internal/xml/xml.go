@@ -407,6 +407,63 @@ func (d *Decoder) RawToken() error {
 			b0 = b
 		}
 		return nil
+
+	case '!':
+		// <!: Maybe comment or CDATA.
+		if b, ok = d.getc(); !ok {
+			d.mustNotEOF()
+			return d.err
+		}
+		if b == '[' {
+			// <![CDATA[ section.
+			for i := 0; i < len("CDATA["); i++ {
+				if b, ok = d.getc(); !ok {
+					d.mustNotEOF()
+					return d.err
+				}
+				if b != "CDATA["[i] {
+					d.err = d.syntaxError("invalid <![ sequence")
+					return d.err
+				}
+			}
+			data := d.cdata()
+			if data == nil {
+				return d.err
+			}
+			if d.OnChar != nil {
+				d.OnChar(goxml.CharData(data))
+			}
+			return nil
+		}
+		if b != '-' {
+			d.err = d.syntaxError("invalid sequence <! not part of <!--")
+			return d.err
+		}
+		if b, ok = d.getc(); !ok {
+			d.mustNotEOF()
+			return d.err
+		}
+		if b != '-' {
+			d.err = d.syntaxError("invalid sequence <!- not part of <!--")
+			return d.err
+		}
+		// Look for terminator.
+		var b0, b1 byte
+		for {
+			if b, ok = d.getc(); !ok {
+				d.mustNotEOF()
+				return d.err
+			}
+			if b0 == '-' && b1 == '-' {
+				if b != '>' {
+					d.err = d.syntaxError(`invalid sequence "--" not allowed in comments`)
+					return d.err
+				}
+				break
+			}
+			b0, b1 = b1, b
+		}
+		return nil
 	}
 
 	// Must be an open element like <a href="foo">
@@ -682,6 +739,34 @@ Input:
 	return d.buf.Bytes()
 }
 
+// Read a CDATA section up to the ]]> terminator, which is not included.
+// Unlike text, entities are not decoded.
+// On failure return nil and leave the error in d.err.
+func (d *Decoder) cdata() []byte {
+	var b0, b1 byte
+	d.buf.Reset()
+	for {
+		b, ok := d.getc()
+		if !ok {
+			d.mustNotEOF()
+			return nil
+		}
+		if b0 == ']' && b1 == ']' && b == '>' {
+			d.buf.Truncate(d.buf.Len() - 2)
+			return d.buf.Bytes()
+		}
+		// We must rewrite unescaped \r and \r\n into \n.
+		if b == '\r' {
+			d.buf.WriteByte('\n')
+		} else if b1 == '\r' && b == '\n' {
+			// Skip \r\n--we already wrote \n.
+		} else {
+			d.buf.WriteByte(b)
+		}
+		b0, b1 = b1, b
+	}
+}
+
 // Get name space name: name with a : stuck in the middle.
 // The part before the : is the name space identifier.
 func (d *Decoder) nsname() (name goxml.Name, ok bool) {
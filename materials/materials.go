@@ -4,11 +4,19 @@
 package materials
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
+	"fmt"
+	"image"
 	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
 
 	"github.com/hpinc/go3mf"
+	specerr "github.com/hpinc/go3mf/errors"
 	"github.com/hpinc/go3mf/spec"
 )
 
@@ -16,7 +24,7 @@ const (
 	// Namespace is the canonical name of this extension.
 	Namespace = "http://schemas.microsoft.com/3dmanufacturing/material/2015/02"
 	// RelTypeTexture3D is the canonical 3D texture relationship type.
-	RelTypeTexture3D = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dtexture"
+	RelTypeTexture3D = go3mf.RelTypeTexture3D
 )
 
 var DefaultExtension = go3mf.Extension{
@@ -161,6 +169,11 @@ func (r *Texture2DGroup) Identify() uint32 {
 	return r.ID
 }
 
+// References returns the ID of the texture used by the group.
+func (r *Texture2DGroup) References() []uint32 {
+	return []uint32{r.TextureID}
+}
+
 // XMLName returns the xml identifier of the resource.
 func (Texture2DGroup) XMLName() xml.Name {
 	return xml.Name{Space: Namespace, Local: attrTexture2DGroup}
@@ -210,6 +223,11 @@ func (c *CompositeMaterials) Identify() uint32 {
 	return c.ID
 }
 
+// References returns the ID of the base materials used by the group.
+func (c *CompositeMaterials) References() []uint32 {
+	return []uint32{c.MaterialID}
+}
+
 // XMLName returns the xml identifier of the resource.
 func (CompositeMaterials) XMLName() xml.Name {
 	return xml.Name{Space: Namespace, Local: attrCompositematerials}
@@ -239,11 +257,143 @@ func (c *MultiProperties) Identify() uint32 {
 	return c.ID
 }
 
+// References returns the IDs of the property groups combined by the resource.
+func (c *MultiProperties) References() []uint32 {
+	return c.PIDs
+}
+
 // XMLName returns the xml identifier of the resource.
 func (MultiProperties) XMLName() xml.Name {
 	return xml.Name{Space: Namespace, Local: attrMultiProps}
 }
 
+// SetVertexColors stores per-vertex colors of obj as a new ColorGroup
+// placed next to obj and assigns each triangle corner the color of its vertex.
+// Identical colors are shared by a single ColorGroup entry.
+// The materials extension is added to m if it was not already declared.
+func SetVertexColors(m *go3mf.Model, obj *go3mf.Object, colors []color.RGBA) (*ColorGroup, error) {
+	if obj.Mesh == nil {
+		return nil, errors.New("materials: object has no mesh")
+	}
+	if len(colors) != len(obj.Mesh.Vertices.Vertex) {
+		return nil, errors.New("materials: colors count does not match vertex count")
+	}
+	var rs *go3mf.Resources
+	m.WalkObjects(func(path string, o *go3mf.Object) error {
+		if o == obj {
+			rs, _ = m.FindResources(path)
+		}
+		return nil
+	})
+	if rs == nil {
+		return nil, errors.New("materials: object not found in model")
+	}
+	for i := range obj.Mesh.Triangles.Triangle {
+		t := &obj.Mesh.Triangles.Triangle[i]
+		if int(t.V1) >= len(colors) || int(t.V2) >= len(colors) || int(t.V3) >= len(colors) {
+			return nil, specerr.WrapIndex(specerr.ErrIndexOutOfBounds, "triangle", i)
+		}
+	}
+	cg := &ColorGroup{ID: rs.UnusedID()}
+	indices := make(map[color.RGBA]uint32, len(colors))
+	vertexIndex := make([]uint32, len(colors))
+	for i, c := range colors {
+		idx, ok := indices[c]
+		if !ok {
+			idx = uint32(len(cg.Colors))
+			indices[c] = idx
+			cg.Colors = append(cg.Colors, c)
+		}
+		vertexIndex[i] = idx
+	}
+	for i := range obj.Mesh.Triangles.Triangle {
+		t := &obj.Mesh.Triangles.Triangle[i]
+		t.PID = cg.ID
+		t.P1, t.P2, t.P3 = vertexIndex[t.V1], vertexIndex[t.V2], vertexIndex[t.V3]
+	}
+	if len(cg.Colors) > 0 {
+		obj.PID, obj.PIndex = cg.ID, vertexIndex[0]
+	}
+	rs.Assets = append(rs.Assets, cg)
+	for _, ext := range m.Extensions {
+		if ext.Namespace == Namespace {
+			return cg, nil
+		}
+	}
+	m.Extensions = append(m.Extensions, DefaultExtension)
+	return cg, nil
+}
+
+// A TextureRef joins a Texture2D asset with the attachment
+// that contains its image, as returned by Textures.
+//
+// Attachment is nil if the model does not contain the texture part.
+// It points into Model.Attachments and is only valid until it is modified.
+type TextureRef struct {
+	ModelPath   string
+	ID          uint32
+	ContentType Texture2DType
+	Texture     *Texture2D
+	Attachment  *go3mf.Attachment
+}
+
+// Textures returns the Texture2D assets of the root and child models
+// together with their attachments. The images are not decoded until
+// TextureRef.Image is called.
+func Textures(m *go3mf.Model) []TextureRef {
+	var refs []TextureRef
+	m.WalkAssets(func(path string, a go3mf.Asset) error {
+		t, ok := a.(*Texture2D)
+		if !ok {
+			return nil
+		}
+		if path == "" {
+			path = m.PathOrDefault()
+		}
+		ref := TextureRef{ModelPath: path, ID: t.ID, ContentType: t.ContentType, Texture: t}
+		if a, ok := m.FindAttachment(t.Path); ok {
+			ref.Attachment = a
+		}
+		refs = append(refs, ref)
+		return nil
+	})
+	return refs
+}
+
+// Image decodes the texture attachment according to ContentType.
+// The attachment stream is left ready to be read again,
+// so the model can still be encoded afterwards.
+func (t TextureRef) Image() (image.Image, error) {
+	if t.Attachment == nil || t.Attachment.Stream == nil {
+		return nil, errors.New("materials: texture attachment not found")
+	}
+	var r io.Reader
+	switch s := t.Attachment.Stream.(type) {
+	case *bytes.Buffer:
+		r = bytes.NewReader(s.Bytes())
+	case io.ReadSeeker:
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		defer s.Seek(0, io.SeekStart)
+		r = s
+	default:
+		b, err := ioutil.ReadAll(s)
+		if err != nil {
+			return nil, err
+		}
+		t.Attachment.Stream = bytes.NewReader(b)
+		r = bytes.NewReader(b)
+	}
+	switch t.ContentType {
+	case TextureTypePNG:
+		return png.Decode(r)
+	case TextureTypeJPEG:
+		return jpeg.Decode(r)
+	}
+	return nil, fmt.Errorf("materials: unsupported texture content type %d", t.ContentType)
+}
+
 func newTexture2DType(s string) (t Texture2DType, ok bool) {
 	t, ok = map[string]Texture2DType{
 		"image/png":  TextureTypePNG,
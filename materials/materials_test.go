@@ -4,9 +4,13 @@
 package materials
 
 import (
+	"bytes"
+	"image"
 	"image/color"
+	"image/png"
 	"reflect"
 	"testing"
+	"testing/iotest"
 
 	"github.com/hpinc/go3mf"
 	"github.com/hpinc/go3mf/spec"
@@ -26,6 +30,9 @@ var _ spec.PropertyGroup = new(ColorGroup)
 var _ spec.PropertyGroup = new(Texture2DGroup)
 var _ spec.PropertyGroup = new(CompositeMaterials)
 var _ spec.PropertyGroup = new(MultiProperties)
+var _ go3mf.AssetReferencer = new(Texture2DGroup)
+var _ go3mf.AssetReferencer = new(CompositeMaterials)
+var _ go3mf.AssetReferencer = new(MultiProperties)
 
 func TestTexture2D_Identify(t *testing.T) {
 	tests := []struct {
@@ -388,3 +395,138 @@ func TestTexture2DGroup_Len(t *testing.T) {
 		})
 	}
 }
+
+func TestSetVertexColors(t *testing.T) {
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	newModel := func() (*go3mf.Model, *go3mf.Object) {
+		obj := &go3mf.Object{ID: 1, Mesh: &go3mf.Mesh{
+			Vertices: go3mf.Vertices{Vertex: []go3mf.Point3D{{}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+			Triangles: go3mf.Triangles{Triangle: []go3mf.Triangle{
+				{V1: 0, V2: 2, V3: 1}, {V1: 0, V2: 1, V3: 3},
+				{V1: 0, V2: 3, V3: 2}, {V1: 1, V2: 2, V3: 3},
+			}},
+		}}
+		return &go3mf.Model{Resources: go3mf.Resources{Objects: []*go3mf.Object{obj}}}, obj
+	}
+	m, obj := newModel()
+	cg, err := SetVertexColors(m, obj, []color.RGBA{red, blue, red, blue})
+	if err != nil {
+		t.Fatalf("SetVertexColors() error = %v", err)
+	}
+	want := &ColorGroup{ID: 2, Colors: []color.RGBA{red, blue}}
+	if !reflect.DeepEqual(cg, want) {
+		t.Errorf("SetVertexColors() = %v, want %v", cg, want)
+	}
+	if len(m.Resources.Assets) != 1 || m.Resources.Assets[0] != cg {
+		t.Errorf("SetVertexColors() assets = %v", m.Resources.Assets)
+	}
+	wantTri := []go3mf.Triangle{
+		{V1: 0, V2: 2, V3: 1, PID: 2, P1: 0, P2: 0, P3: 1},
+		{V1: 0, V2: 1, V3: 3, PID: 2, P1: 0, P2: 1, P3: 1},
+		{V1: 0, V2: 3, V3: 2, PID: 2, P1: 0, P2: 1, P3: 0},
+		{V1: 1, V2: 2, V3: 3, PID: 2, P1: 1, P2: 0, P3: 1},
+	}
+	if !reflect.DeepEqual(obj.Mesh.Triangles.Triangle, wantTri) {
+		t.Errorf("SetVertexColors() triangles = %v, want %v", obj.Mesh.Triangles.Triangle, wantTri)
+	}
+	if obj.PID != 2 {
+		t.Errorf("SetVertexColors() object pid = %d, want 2", obj.PID)
+	}
+	if len(m.Extensions) != 1 || m.Extensions[0].Namespace != Namespace {
+		t.Errorf("SetVertexColors() extensions = %v", m.Extensions)
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("SetVertexColors() validate = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		obj    func(*go3mf.Model, *go3mf.Object) *go3mf.Object
+		colors []color.RGBA
+	}{
+		{"count", func(_ *go3mf.Model, o *go3mf.Object) *go3mf.Object { return o }, []color.RGBA{red}},
+		{"notFound", func(*go3mf.Model, *go3mf.Object) *go3mf.Object {
+			return &go3mf.Object{Mesh: new(go3mf.Mesh)}
+		}, nil},
+		{"noMesh", func(*go3mf.Model, *go3mf.Object) *go3mf.Object { return new(go3mf.Object) }, nil},
+		{"outOfBounds", func(_ *go3mf.Model, o *go3mf.Object) *go3mf.Object {
+			o.Mesh.Vertices.Vertex = o.Mesh.Vertices.Vertex[:3]
+			return o
+		}, []color.RGBA{red, blue, red}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, obj := newModel()
+			if _, err := SetVertexColors(m, tt.obj(m, obj), tt.colors); err == nil {
+				t.Error("SetVertexColors() expected error")
+			}
+			if len(m.Resources.Assets) != 0 {
+				t.Errorf("SetVertexColors() assets = %v, want none", m.Resources.Assets)
+			}
+		})
+	}
+}
+
+func TestTextures(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	pngData := buf.Bytes()
+	m := &go3mf.Model{
+		Resources: go3mf.Resources{Assets: []go3mf.Asset{
+			&Texture2D{ID: 1, Path: "/3D/Textures/a.png", ContentType: TextureTypePNG},
+			&ColorGroup{ID: 2},
+		}},
+		Childs: map[string]*go3mf.ChildModel{"/3D/other.model": {Resources: go3mf.Resources{Assets: []go3mf.Asset{
+			&Texture2D{ID: 1, Path: "/3D/Textures/missing.png", ContentType: TextureTypePNG},
+			&Texture2D{ID: 2, Path: "/3D/Textures/b.png", ContentType: TextureTypePNG},
+		}}}},
+		Attachments: []go3mf.Attachment{
+			{Path: "/3D/Textures/A.png", Stream: bytes.NewBuffer(append([]byte(nil), pngData...))},
+			{Path: "/3D/Textures/b.png", Stream: iotest.OneByteReader(bytes.NewReader(pngData))},
+		},
+	}
+	refs := Textures(m)
+	if len(refs) != 3 {
+		t.Fatalf("Textures() = %v, want 3 refs", refs)
+	}
+	if refs[0].ModelPath != "/3D/other.model" || refs[0].Attachment != nil {
+		t.Errorf("Textures() missing = %v", refs[0])
+	}
+	if _, err := refs[0].Image(); err == nil {
+		t.Error("TextureRef.Image() expected error")
+	}
+	for _, ref := range refs[1:] {
+		for i := 0; i < 2; i++ {
+			got, err := ref.Image()
+			if err != nil {
+				t.Fatalf("TextureRef.Image() error = %v", err)
+			}
+			if got.Bounds() != img.Bounds() {
+				t.Errorf("TextureRef.Image() bounds = %v, want %v", got.Bounds(), img.Bounds())
+			}
+		}
+	}
+	if refs[2].ModelPath != go3mf.DefaultModelPath || refs[2].Attachment != &m.Attachments[0] {
+		t.Errorf("Textures() root = %v", refs[2])
+	}
+}
+
+func TestTopologicalOrder(t *testing.T) {
+	multi := &MultiProperties{ID: 1, PIDs: []uint32{2, 3}}
+	group := &Texture2DGroup{ID: 2, TextureID: 4}
+	composite := &CompositeMaterials{ID: 3, MaterialID: 5}
+	texture := &Texture2D{ID: 4}
+	base := &go3mf.BaseMaterials{ID: 5}
+	rs := &go3mf.Resources{Assets: []go3mf.Asset{multi, group, composite, texture, base}}
+	got, err := rs.TopologicalOrder()
+	if err != nil {
+		t.Fatalf("Resources.TopologicalOrder() error = %v", err)
+	}
+	want := []interface{}{texture, group, base, composite, multi}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Resources.TopologicalOrder() = %v, want %v", got, want)
+	}
+}
@@ -5,7 +5,6 @@ package materials
 
 import (
 	"image/color"
-	"strings"
 
 	"github.com/hpinc/go3mf"
 	"github.com/hpinc/go3mf/errors"
@@ -75,14 +74,7 @@ func validateTexture2D(m *go3mf.Model, path string, r *Texture2D) (errs error) {
 	if r.Path == "" {
 		errs = errors.Append(errs, errors.NewMissingFieldError(attrPath))
 	} else {
-		var hasTexture bool
-		for _, a := range m.Attachments {
-			if strings.EqualFold(a.Path, r.Path) {
-				hasTexture = true
-				break
-			}
-		}
-		if !hasTexture {
+		if _, ok := m.FindAttachment(r.Path); !ok {
 			errs = errors.Append(errs, ErrMissingTexturePart)
 		}
 	}
@@ -103,6 +103,10 @@ func (v1 Point3D) Z() float32 {
 	return v1[2]
 }
 
+func (v1 Point3D) isNaN() bool {
+	return v1[0] != v1[0] || v1[1] != v1[1] || v1[2] != v1[2]
+}
+
 // Matrix is a 4x4 matrix in row major order.
 //
 // m[4*r + c] is the element in the r'th row and c'th column.
@@ -122,6 +126,61 @@ func Identity() Matrix {
 	return Matrix{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
 }
 
+// matrixTolerance is the relative tolerance used to
+// classify the linear part of a matrix.
+const matrixTolerance = 1e-5
+
+// IsAffine returns true if the matrix is an invertible affine transform,
+// that is if its last row is (0 0 0 1) and its linear part is not singular.
+// A zero matrix is considered the identity.
+func (m1 Matrix) IsAffine() bool {
+	if m1 == (Matrix{}) {
+		return true
+	}
+	if m1[3] != 0 || m1[7] != 0 || m1[11] != 0 || m1[15] != 1 {
+		return false
+	}
+	x, y, z := m1.axes()
+	scale := math.Max(x.len(), math.Max(y.len(), z.len()))
+	return math.Abs(x.dot(y.cross(z))) > matrixTolerance*scale*scale*scale
+}
+
+// IsRigid returns true if the matrix is an affine transform composed
+// of a rotation, a translation and at most a uniform positive scale.
+// Shearing, non-uniform scaling and mirroring are not rigid.
+// A zero matrix is considered the identity.
+func (m1 Matrix) IsRigid() bool {
+	if !m1.IsAffine() {
+		return false
+	}
+	if m1 == (Matrix{}) {
+		return true
+	}
+	x, y, z := m1.axes()
+	s := x.len()
+	tol := matrixTolerance * s
+	return math.Abs(y.len()-s) <= tol && math.Abs(z.len()-s) <= tol &&
+		math.Abs(x.dot(y)) <= tol*s && math.Abs(x.dot(z)) <= tol*s && math.Abs(y.dot(z)) <= tol*s &&
+		x.dot(y.cross(z)) > 0
+}
+
+// determinant returns the determinant of the linear part of the matrix.
+// A zero matrix is considered the identity.
+func (m1 Matrix) determinant() float64 {
+	if m1 == (Matrix{}) {
+		return 1
+	}
+	x, y, z := m1.axes()
+	return x.dot(y.cross(z))
+}
+
+// axes returns the images of the x, y and z unit vectors.
+func (m1 Matrix) axes() (x, y, z vec3) {
+	return vec3{float64(m1[0]), float64(m1[1]), float64(m1[2])},
+		vec3{float64(m1[4]), float64(m1[5]), float64(m1[6])},
+		vec3{float64(m1[8]), float64(m1[9]), float64(m1[10])}
+}
+
 // Translate returns a matrix with a relative translation applied.
 func (m1 Matrix) Translate(x, y, z float32) Matrix {
 	m1[12] += x
@@ -173,23 +232,25 @@ func (m1 Matrix) Mul2D(v Point2D) Point2D {
 }
 
 // MulBox performs a "matrix product" between this matrix
-// and a box
+// and a box, returning the axis aligned box that contains
+// the transformed corners of b.
 func (m1 Matrix) MulBox(b Box) Box {
 	if m1[15] == 0 {
 		return b
 	}
-	box := Box{
-		Min: m1.Mul3D(b.Min),
-		Max: m1.Mul3D(b.Max),
-	}
-	if box.Min.X() > box.Max.X() {
-		box.Min[0], box.Max[0] = box.Max[0], box.Min[0]
-	}
-	if box.Min.Y() > box.Max.Y() {
-		box.Min[1], box.Max[1] = box.Max[1], box.Min[1]
-	}
-	if box.Min.Z() > box.Max.Z() {
-		box.Min[2], box.Max[2] = box.Max[2], box.Min[2]
+	box := newLimitBox()
+	for i := 0; i < 8; i++ {
+		corner := b.Min
+		if i&1 != 0 {
+			corner[0] = b.Max[0]
+		}
+		if i&2 != 0 {
+			corner[1] = b.Max[1]
+		}
+		if i&4 != 0 {
+			corner[2] = b.Max[2]
+		}
+		box = box.extendPoint(m1.Mul3D(corner))
 	}
 	return box
 }
@@ -224,6 +285,13 @@ func (b Box) extend(v Box) Box {
 	}
 }
 
+// intersects returns true if both boxes share a region with positive volume.
+func (b Box) intersects(v Box) bool {
+	return b.Min.X() < v.Max.X() && v.Min.X() < b.Max.X() &&
+		b.Min.Y() < v.Max.Y() && v.Min.Y() < b.Max.Y() &&
+		b.Min.Z() < v.Max.Z() && v.Min.Z() < b.Max.Z()
+}
+
 func (b Box) extendPoint(v Point3D) Box {
 	return Box{
 		Min: Point3D{
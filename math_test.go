@@ -4,6 +4,7 @@
 package go3mf
 
 import (
+	"math"
 	"reflect"
 	"testing"
 )
@@ -135,6 +136,38 @@ func TestMatrix_Mul(t *testing.T) {
 	}
 }
 
+func TestMatrix_IsAffine_IsRigid(t *testing.T) {
+	c, s := float32(math.Cos(0.5)), float32(math.Sin(0.5))
+	rotation := Matrix{c, s, 0, 0, -s, c, 0, 0, 0, 0, 1, 0, 10, -5, 3, 1}
+	tests := []struct {
+		name       string
+		m          Matrix
+		wantAffine bool
+		wantRigid  bool
+	}{
+		{"zero", Matrix{}, true, true},
+		{"identity", Identity(), true, true},
+		{"translation", Identity().Translate(1, 2, 3), true, true},
+		{"rotation", rotation, true, true},
+		{"uniformScale", Matrix{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1}.Mul(rotation), true, true},
+		{"nonUniformScale", Matrix{2, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, true, false},
+		{"shear", Matrix{1, 0, 0, 0, 0.5, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, true, false},
+		{"mirror", Matrix{-1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, true, false},
+		{"singular", Matrix{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, false, false},
+		{"projective", Matrix{1, 0, 0, 0.5, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.IsAffine(); got != tt.wantAffine {
+				t.Errorf("Matrix.IsAffine() = %v, want %v", got, tt.wantAffine)
+			}
+			if got := tt.m.IsRigid(); got != tt.wantRigid {
+				t.Errorf("Matrix.IsRigid() = %v, want %v", got, tt.wantRigid)
+			}
+		})
+	}
+}
+
 func TestMatrix_String(t *testing.T) {
 	tests := []struct {
 		name string
@@ -368,6 +401,13 @@ func TestMatrix_MulBox(t *testing.T) {
 			Min: Point3D{-4, 2, 2},
 			Max: Point3D{-2, 4, 4},
 		}},
+		{"rotation", Matrix{1, 1, 0, 0, -1, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, args{Box{
+			Min: Point3D{0, 0, 0},
+			Max: Point3D{1, 1, 1},
+		}}, Box{
+			Min: Point3D{-1, 0, 0},
+			Max: Point3D{1, 2, 1},
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
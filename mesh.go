@@ -0,0 +1,1070 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"sort"
+
+	specerr "github.com/hpinc/go3mf/errors"
+)
+
+// Translate moves all the vertices of the mesh by delta.
+func (m *Mesh) Translate(delta Point3D) {
+	for i, v := range m.Vertices.Vertex {
+		m.Vertices.Vertex[i] = Point3D{v.X() + delta.X(), v.Y() + delta.Y(), v.Z() + delta.Z()}
+	}
+}
+
+// Scale uniformly scales all the vertices of the mesh by factor
+// with respect to the origin.
+// A negative factor mirrors the mesh, so the triangles winding is
+// flipped to keep their normals pointing outwards.
+func (m *Mesh) Scale(factor float32) {
+	for i, v := range m.Vertices.Vertex {
+		m.Vertices.Vertex[i] = Point3D{v.X() * factor, v.Y() * factor, v.Z() * factor}
+	}
+	if factor < 0 {
+		m.flipWinding()
+	}
+}
+
+// Transform applies t to all the vertices of the mesh,
+// baking the transform into the geometry. A zero matrix is the identity.
+// If t mirrors the mesh, its determinant being negative, the triangles winding
+// is flipped to keep their normals pointing outwards.
+func (m *Mesh) Transform(t Matrix) {
+	if t == (Matrix{}) {
+		return
+	}
+	for i, v := range m.Vertices.Vertex {
+		m.Vertices.Vertex[i] = t.Mul3D(v)
+	}
+	if t.determinant() < 0 {
+		m.flipWinding()
+	}
+}
+
+// scale scales the mesh vertices and the component
+// translations of the objects by factor.
+func (rs *Resources) scale(factor float32) {
+	for _, o := range rs.Objects {
+		if o.Mesh != nil {
+			o.Mesh.Scale(factor)
+		}
+		if o.Components != nil {
+			for _, c := range o.Components.Component {
+				c.Transform[12] *= factor
+				c.Transform[13] *= factor
+				c.Transform[14] *= factor
+			}
+		}
+	}
+}
+
+// flipWinding reverses the orientation of every triangle,
+// keeping each property attached to its vertex.
+func (m *Mesh) flipWinding() {
+	for i := range m.Triangles.Triangle {
+		m.Triangles.Triangle[i].flip()
+	}
+}
+
+// SubMesh returns a new mesh containing a copy of the triangles
+// referenced by triangleIndices, in the same order, and only the vertices they use.
+// Triangle properties are preserved.
+//
+// Out of range triangle indices are ignored, as well as triangles
+// referencing out of range vertices.
+// The mesh extension attributes and elements are not copied.
+func (m *Mesh) SubMesh(triangleIndices []int) *Mesh {
+	sub := new(Mesh)
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	remap := make(map[uint32]uint32)
+	index := func(v uint32) uint32 {
+		if i, ok := remap[v]; ok {
+			return i
+		}
+		i := uint32(len(sub.Vertices.Vertex))
+		remap[v] = i
+		sub.Vertices.Vertex = append(sub.Vertices.Vertex, m.Vertices.Vertex[v])
+		return i
+	}
+	for _, ti := range triangleIndices {
+		if ti < 0 || ti >= len(m.Triangles.Triangle) {
+			continue
+		}
+		t := m.Triangles.Triangle[ti]
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		t.V1, t.V2, t.V3 = index(t.V1), index(t.V2), index(t.V3)
+		sub.Triangles.Triangle = append(sub.Triangles.Triangle, t)
+	}
+	return sub
+}
+
+// Volume returns the signed volume enclosed by the triangles,
+// computed as the sum of the signed tetrahedra formed by each triangle and the origin.
+// The result is only meaningful for closed meshes, and it is positive
+// if the triangles are oriented outwards. An empty mesh has zero volume.
+// Triangles referencing out of range vertices are ignored.
+func (m *Mesh) Volume() float64 {
+	var v float64
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	for _, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		a, b, c := newVec3(m.Vertices.Vertex[t.V1]), newVec3(m.Vertices.Vertex[t.V2]), newVec3(m.Vertices.Vertex[t.V3])
+		v += a.dot(b.cross(c))
+	}
+	return v / 6
+}
+
+// Faces calls fn for each triangle with its index and the coordinates
+// of its three vertices, in order, stopping if fn returns false.
+// Triangles referencing out of range vertices are skipped.
+func (m *Mesh) Faces(fn func(i uint32, a, b, c Point3D) bool) {
+	vertices := m.Vertices.Vertex
+	nodeCount := uint32(len(vertices))
+	for i, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		if !fn(uint32(i), vertices[t.V1], vertices[t.V2], vertices[t.V3]) {
+			return
+		}
+	}
+}
+
+// SurfaceArea returns the sum of the areas of the triangles.
+// Triangles referencing out of range vertices are ignored.
+func (m *Mesh) SurfaceArea() float64 {
+	var area float64
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	for _, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		a := newVec3(m.Vertices.Vertex[t.V1])
+		area += newVec3(m.Vertices.Vertex[t.V2]).sub(a).cross(newVec3(m.Vertices.Vertex[t.V3]).sub(a)).len()
+	}
+	return area / 2
+}
+
+// Smooth applies iterations steps of Laplacian smoothing, moving each vertex
+// towards the average of its neighbors by factor, which is usually in the (0, 1] range.
+// All the vertices are moved at once using the positions of the previous step.
+//
+// If pinBoundary is true the vertices lying in an edge used by a single triangle
+// are not moved, so open borders are preserved.
+// Vertices not referenced by any triangle are not moved.
+// The triangles and their properties are kept untouched.
+func (m *Mesh) Smooth(iterations int, factor float32, pinBoundary bool) {
+	if iterations <= 0 || factor == 0 {
+		return
+	}
+	neighbors := m.VertexNeighbors()
+	var pinned map[uint32]bool
+	if pinBoundary {
+		pinned = m.boundaryVertices()
+	}
+	f := float64(factor)
+	current := make([]vec3, len(m.Vertices.Vertex))
+	for i, v := range m.Vertices.Vertex {
+		current[i] = newVec3(v)
+	}
+	next := make([]vec3, len(current))
+	for it := 0; it < iterations; it++ {
+		for i, p := range current {
+			ns := neighbors[i]
+			if len(ns) == 0 || pinned[uint32(i)] {
+				next[i] = p
+				continue
+			}
+			var avg vec3
+			for _, n := range ns {
+				avg = avg.add(current[n])
+			}
+			avg = avg.scale(1 / float64(len(ns)))
+			next[i] = p.add(avg.sub(p).scale(f))
+		}
+		current, next = next, current
+	}
+	for i, p := range current {
+		m.Vertices.Vertex[i] = p.point()
+	}
+}
+
+// VertexNeighbors returns, for each vertex, the vertices sharing a triangle edge with it,
+// without duplicates and sorted in ascending order.
+// The result is indexed like Vertices.Vertex and is not updated when the mesh changes.
+// Triangles referencing out of range vertices are ignored.
+func (m *Mesh) VertexNeighbors() [][]uint32 {
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	neighbors := make([][]uint32, nodeCount)
+	for _, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		v := t.vertices()
+		for j := 0; j < 3; j++ {
+			a, b := v[j], v[(j+1)%3]
+			if a == b {
+				continue
+			}
+			neighbors[a] = append(neighbors[a], b)
+			neighbors[b] = append(neighbors[b], a)
+		}
+	}
+	for i, ns := range neighbors {
+		sort.Slice(ns, func(a, b int) bool { return ns[a] < ns[b] })
+		unique := ns[:0]
+		for j, n := range ns {
+			if j == 0 || n != ns[j-1] {
+				unique = append(unique, n)
+			}
+		}
+		neighbors[i] = unique
+	}
+	return neighbors
+}
+
+// boundaryVertices returns the vertices lying in
+// an edge used by a single triangle.
+func (m *Mesh) boundaryVertices() map[uint32]bool {
+	edges := make(map[edgeKey]int)
+	for _, t := range m.Triangles.Triangle {
+		v := t.vertices()
+		for j := 0; j < 3; j++ {
+			edges[newEdgeKey(v[j], v[(j+1)%3])]++
+		}
+	}
+	boundary := make(map[uint32]bool)
+	for e, n := range edges {
+		if n == 1 {
+			boundary[e[0]] = true
+			boundary[e[1]] = true
+		}
+	}
+	return boundary
+}
+
+// VertexNormals returns the smooth normal of each vertex, indexed like Vertices.Vertex.
+// It is the normalized sum of the normals of the triangles using the vertex,
+// each weighted by the triangle area, so small triangles barely affect the result.
+// The triangles are expected to be oriented outwards.
+//
+// Vertices not used by any non-degenerate triangle get a zero normal,
+// and nil is returned if the mesh has no triangles.
+// Triangles referencing out of range vertices are ignored.
+// Use CornerNormals to keep sharp edges.
+func (m *Mesh) VertexNormals() []Point3D {
+	if len(m.Triangles.Triangle) == 0 {
+		return nil
+	}
+	faces := m.faceNormals()
+	sums := make([]vec3, len(m.Vertices.Vertex))
+	for i, t := range m.Triangles.Triangle {
+		if faces[i] == (vec3{}) {
+			continue
+		}
+		for _, v := range t.vertices() {
+			sums[v] = sums[v].add(faces[i])
+		}
+	}
+	normals := make([]Point3D, len(sums))
+	for i, n := range sums {
+		normals[i] = n.normalize().point()
+	}
+	return normals
+}
+
+// CornerNormals returns the normal of each triangle corner, in V1, V2, V3 order,
+// indexed like Triangles.Triangle.
+// It is computed as in VertexNormals but only the triangles whose normal
+// deviates from the one of the corner triangle by at most creaseAngle,
+// in radians, are averaged. Edges whose dihedral angle exceeds creaseAngle
+// are then rendered sharp: a creaseAngle of 0 produces flat shading and
+// a creaseAngle of π or greater produces the same result as VertexNormals.
+//
+// Degenerate triangles and triangles referencing out of range vertices
+// get the normals returned by VertexNormals and zero normals respectively.
+func (m *Mesh) CornerNormals(creaseAngle float64) [][3]Point3D {
+	faces := m.faceNormals()
+	smooth := m.VertexNormals()
+	incident := make([][]int, len(m.Vertices.Vertex))
+	for i, t := range m.Triangles.Triangle {
+		if faces[i] == (vec3{}) {
+			continue
+		}
+		for _, v := range t.vertices() {
+			incident[v] = append(incident[v], i)
+		}
+	}
+	minCos := math.Cos(math.Min(creaseAngle, math.Pi))
+	normals := make([][3]Point3D, len(m.Triangles.Triangle))
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	for i, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		if faces[i] == (vec3{}) {
+			normals[i] = [3]Point3D{smooth[t.V1], smooth[t.V2], smooth[t.V3]}
+			continue
+		}
+		n := faces[i].normalize()
+		for j, v := range t.vertices() {
+			var sum vec3
+			for _, f := range incident[v] {
+				// The tolerance keeps coplanar triangles together despite rounding.
+				if f == i || n.dot(faces[f].normalize()) >= minCos-1e-9 {
+					sum = sum.add(faces[f])
+				}
+			}
+			normals[i][j] = sum.normalize().point()
+		}
+	}
+	return normals
+}
+
+// faceNormals returns the normal of each triangle scaled by twice its area,
+// or a zero vector if the triangle references out of range vertices.
+func (m *Mesh) faceNormals() []vec3 {
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	normals := make([]vec3, len(m.Triangles.Triangle))
+	for i, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		a, b, c := newVec3(m.Vertices.Vertex[t.V1]), newVec3(m.Vertices.Vertex[t.V2]), newVec3(m.Vertices.Vertex[t.V3])
+		normals[i] = b.sub(a).cross(c.sub(a))
+	}
+	return normals
+}
+
+// RecenterToOrigin translates the object mesh so the center of its
+// bounding box sits at the origin and returns the applied offset,
+// which has to be added back to the vertices to get their original position.
+// Objects without a mesh are not modified and a zero offset is returned.
+//
+// The transforms of the build items and components referencing the object
+// are not updated, use Model.RecenterObject to keep the object in place.
+func (o *Object) RecenterToOrigin() (offset Point3D) {
+	if o.Mesh == nil || len(o.Mesh.Vertices.Vertex) == 0 {
+		return
+	}
+	box := o.Mesh.BoundingBox()
+	offset = Point3D{
+		(box.Min.X() + box.Max.X()) / 2,
+		(box.Min.Y() + box.Max.Y()) / 2,
+		(box.Min.Z() + box.Max.Z()) / 2,
+	}
+	o.Mesh.Translate(Point3D{-offset.X(), -offset.Y(), -offset.Z()})
+	return
+}
+
+// RecenterObject recenters the object identified by path and id as in
+// Object.RecenterToOrigin and pushes the offset into the transforms of
+// the build items and components referencing it, so the object does not move.
+// It returns false if the object does not exist.
+func (m *Model) RecenterObject(path string, id uint32) (Point3D, bool) {
+	o, ok := m.FindObject(path, id)
+	if !ok {
+		return Point3D{}, false
+	}
+	offset := o.RecenterToOrigin()
+	if offset == (Point3D{}) {
+		return offset, true
+	}
+	if path == "" {
+		path = m.PathOrDefault()
+	}
+	isTarget := func(p string, oid uint32) bool {
+		if p == "" {
+			p = m.PathOrDefault()
+		}
+		return oid == id && p == path
+	}
+	compensate := func(t Matrix) Matrix {
+		if t == (Matrix{}) {
+			t = Identity()
+		}
+		return t.Mul(Identity().Translate(offset.X(), offset.Y(), offset.Z()))
+	}
+	for _, item := range m.Build.Items {
+		if isTarget(item.ObjectPath(), item.ObjectID) {
+			item.Transform = compensate(item.Transform)
+		}
+	}
+	m.WalkObjects(func(p string, obj *Object) error {
+		if obj.Components == nil {
+			return nil
+		}
+		if p == "" {
+			p = m.PathOrDefault()
+		}
+		for _, c := range obj.Components.Component {
+			if isTarget(c.ObjectPath(p), c.ObjectID) {
+				c.Transform = compensate(c.Transform)
+			}
+		}
+		return nil
+	})
+	return offset, true
+}
+
+// PropertyRef identifies an entry of a property group,
+// such as a base material or a color.
+// Path is the model part defining the group, empty for the root part.
+// The zero value means no property.
+type PropertyRef struct {
+	Path   string
+	PID    uint32
+	PIndex uint32
+}
+
+// AreaByMaterial returns the surface area of the object grouped by
+// the property assigned to its triangles. The object is expected to
+// be in the root model part.
+//
+// Triangles without a pid inherit the object pid and pindex, triangles
+// without an effective property are accumulated in the zero PropertyRef.
+// The area of each triangle is split evenly between its three corners,
+// so a triangle with a different property index per vertex contributes
+// a third of its area to each of them.
+// Components are resolved through m and their transforms applied,
+// so the areas are measured in the object coordinate system.
+// Missing objects and recursive components are ignored.
+func (o *Object) AreaByMaterial(m *Model) map[PropertyRef]float64 {
+	areas := make(map[PropertyRef]float64)
+	o.addAreaByMaterial(m, "", Identity(), areas, make(map[*Object]bool))
+	return areas
+}
+
+func (o *Object) addAreaByMaterial(m *Model, path string, transform Matrix, areas map[PropertyRef]float64, visiting map[*Object]bool) {
+	if visiting[o] {
+		return
+	}
+	if o.Mesh != nil {
+		o.Mesh.addAreaByMaterial(o, path, transform, areas)
+	}
+	if o.Components == nil || m == nil {
+		return
+	}
+	visiting[o] = true
+	for _, c := range o.Components.Component {
+		cpath := c.ObjectPath(path)
+		if obj, ok := m.FindObject(cpath, c.ObjectID); ok {
+			ct := c.Transform
+			if ct == (Matrix{}) {
+				ct = Identity()
+			}
+			if cpath == m.PathOrDefault() {
+				cpath = ""
+			}
+			obj.addAreaByMaterial(m, cpath, transform.Mul(ct), areas, visiting)
+		}
+	}
+	visiting[o] = false
+}
+
+func (m *Mesh) addAreaByMaterial(o *Object, path string, transform Matrix, areas map[PropertyRef]float64) {
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	for _, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		a := newVec3(transform.Mul3D(m.Vertices.Vertex[t.V1]))
+		b := newVec3(transform.Mul3D(m.Vertices.Vertex[t.V2]))
+		c := newVec3(transform.Mul3D(m.Vertices.Vertex[t.V3]))
+		area := b.sub(a).cross(c.sub(a)).len() / 2
+		pid, indices := t.PID, [3]uint32{t.P1, t.P2, t.P3}
+		if pid == 0 {
+			pid, indices = o.PID, [3]uint32{o.PIndex, o.PIndex, o.PIndex}
+		}
+		if pid == 0 {
+			areas[PropertyRef{}] += area
+			continue
+		}
+		for _, index := range indices {
+			areas[PropertyRef{Path: path, PID: pid, PIndex: index}] += area / 3
+		}
+	}
+}
+
+// simplifyMaxError is the maximum error allowed when simplifying a mesh,
+// relative to the diagonal of its bounding box.
+const simplifyMaxError = 1e-2
+
+// Simplify reduces the triangle count of the mesh down to targetTriangles
+// using quadric error metric edge collapses.
+//
+// Vertices lying in boundary or non-manifold edges are never moved, so open borders
+// are preserved. Triangle properties are kept untouched.
+// The decimation stops before reaching the target if the next collapse
+// would introduce an error bigger than 1% of the bounding box diagonal,
+// or if there are no more edges that can be collapsed without flipping a triangle.
+// Vertices not referenced by any triangle are removed once finished.
+func (m *Mesh) Simplify(targetTriangles int) error {
+	if targetTriangles < 0 {
+		return errors.New("go3mf: target triangle count must not be negative")
+	}
+	if len(m.Triangles.Triangle) <= targetTriangles {
+		return nil
+	}
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	for _, t := range m.Triangles.Triangle {
+		if t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			return specerr.ErrIndexOutOfBounds
+		}
+	}
+	s := newSimplifier(m)
+	s.run(targetTriangles)
+	s.compact()
+	return nil
+}
+
+// quadric is a symmetric 4x4 matrix stored as its upper triangle:
+// a2, ab, ac, ad, b2, bc, bd, c2, cd, d2.
+type quadric [10]float64
+
+func newPlaneQuadric(a, b, c, d float64) quadric {
+	return quadric{a * a, a * b, a * c, a * d, b * b, b * c, b * d, c * c, c * d, d * d}
+}
+
+func (q quadric) add(o quadric) quadric {
+	for i := range q {
+		q[i] += o[i]
+	}
+	return q
+}
+
+func (q quadric) eval(p vec3) float64 {
+	x, y, z := p[0], p[1], p[2]
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z + q[9]
+}
+
+// optimal returns the point that minimizes the quadric error,
+// if the quadric is invertible.
+func (q quadric) optimal() (vec3, bool) {
+	det := q[0]*(q[4]*q[7]-q[5]*q[5]) - q[1]*(q[1]*q[7]-q[5]*q[2]) + q[2]*(q[1]*q[5]-q[4]*q[2])
+	if math.Abs(det) < 1e-12 {
+		return vec3{}, false
+	}
+	bx, by, bz := -q[3], -q[6], -q[8]
+	x := (bx*(q[4]*q[7]-q[5]*q[5]) - q[1]*(by*q[7]-q[5]*bz) + q[2]*(by*q[5]-q[4]*bz)) / det
+	y := (q[0]*(by*q[7]-bz*q[5]) - bx*(q[1]*q[7]-q[5]*q[2]) + q[2]*(q[1]*bz-by*q[2])) / det
+	z := (q[0]*(q[4]*bz-q[5]*by) - q[1]*(q[1]*bz-by*q[2]) + bx*(q[1]*q[5]-q[4]*q[2])) / det
+	return vec3{x, y, z}, true
+}
+
+// vec3 is a double precision 3D vector used by the mesh algorithms.
+type vec3 [3]float64
+
+func newVec3(p Point3D) vec3 {
+	return vec3{float64(p[0]), float64(p[1]), float64(p[2])}
+}
+
+func (v vec3) sub(o vec3) vec3 {
+	return vec3{v[0] - o[0], v[1] - o[1], v[2] - o[2]}
+}
+
+func (v vec3) add(o vec3) vec3 {
+	return vec3{v[0] + o[0], v[1] + o[1], v[2] + o[2]}
+}
+
+func (v vec3) scale(f float64) vec3 {
+	return vec3{v[0] * f, v[1] * f, v[2] * f}
+}
+
+func (v vec3) dot(o vec3) float64 {
+	return v[0]*o[0] + v[1]*o[1] + v[2]*o[2]
+}
+
+func (v vec3) cross(o vec3) vec3 {
+	return vec3{v[1]*o[2] - v[2]*o[1], v[2]*o[0] - v[0]*o[2], v[0]*o[1] - v[1]*o[0]}
+}
+
+func (v vec3) len() float64 {
+	return math.Sqrt(v.dot(v))
+}
+
+// normalize returns the unit vector with the direction of v,
+// or the zero vector if v has no length.
+func (v vec3) normalize() vec3 {
+	l := v.len()
+	if l == 0 {
+		return vec3{}
+	}
+	return v.scale(1 / l)
+}
+
+func (v vec3) point() Point3D {
+	return Point3D{float32(v[0]), float32(v[1]), float32(v[2])}
+}
+
+type collapseCandidate struct {
+	a, b     uint32
+	va, vb   int
+	cost     float64
+	position vec3
+}
+
+type collapseHeap []*collapseCandidate
+
+func (h collapseHeap) Len() int            { return len(h) }
+func (h collapseHeap) Less(i, j int) bool  { return h[i].cost < h[j].cost }
+func (h collapseHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *collapseHeap) Push(x interface{}) { *h = append(*h, x.(*collapseCandidate)) }
+func (h *collapseHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+type simplifier struct {
+	mesh        *Mesh
+	positions   []vec3
+	quadrics    []quadric
+	vertexTris  [][]int
+	locked      []bool
+	deleted     []bool
+	versions    []int
+	removedTris []bool
+	liveTris    int
+	maxCost     float64
+	candidates  collapseHeap
+}
+
+func newSimplifier(m *Mesh) *simplifier {
+	nv := len(m.Vertices.Vertex)
+	s := &simplifier{
+		mesh:        m,
+		positions:   make([]vec3, nv),
+		quadrics:    make([]quadric, nv),
+		vertexTris:  make([][]int, nv),
+		locked:      make([]bool, nv),
+		deleted:     make([]bool, nv),
+		versions:    make([]int, nv),
+		removedTris: make([]bool, len(m.Triangles.Triangle)),
+		liveTris:    len(m.Triangles.Triangle),
+	}
+	for i, v := range m.Vertices.Vertex {
+		s.positions[i] = newVec3(v)
+	}
+	box := m.BoundingBox()
+	diag := newVec3(box.Max).sub(newVec3(box.Min)).len() * simplifyMaxError
+	s.maxCost = diag * diag
+
+	edges := make(map[pairEntry]int)
+	var sortedEdges []pairEntry
+	for i, t := range m.Triangles.Triangle {
+		fv := [3]uint32{t.V1, t.V2, t.V3}
+		n := s.positions[t.V2].sub(s.positions[t.V1]).cross(s.positions[t.V3].sub(s.positions[t.V1]))
+		if l := n.len(); l > 0 {
+			n = n.scale(1 / l)
+			q := newPlaneQuadric(n[0], n[1], n[2], -n.dot(s.positions[t.V1]))
+			for _, v := range fv {
+				s.quadrics[v] = s.quadrics[v].add(q)
+			}
+		}
+		for j, v := range fv {
+			s.vertexTris[v] = append(s.vertexTris[v], i)
+			e := newPairEntry(v, fv[(j+1)%3])
+			if edges[e] == 0 {
+				sortedEdges = append(sortedEdges, e)
+			}
+			edges[e]++
+		}
+	}
+	for _, e := range sortedEdges {
+		if edges[e] != 2 {
+			s.locked[e.a], s.locked[e.b] = true, true
+		}
+	}
+	for _, e := range sortedEdges {
+		s.pushCandidate(e.a, e.b)
+	}
+	return s
+}
+
+func (s *simplifier) pushCandidate(a, b uint32) {
+	if a == b || s.locked[a] || s.locked[b] {
+		return
+	}
+	q := s.quadrics[a].add(s.quadrics[b])
+	best, bestCost := s.positions[a], q.eval(s.positions[a])
+	options := []vec3{s.positions[b], s.positions[a].add(s.positions[b]).scale(0.5)}
+	if p, ok := q.optimal(); ok {
+		options = append(options, p)
+	}
+	for _, p := range options {
+		if c := q.eval(p); c < bestCost {
+			best, bestCost = p, c
+		}
+	}
+	heap.Push(&s.candidates, &collapseCandidate{
+		a: a, b: b, va: s.versions[a], vb: s.versions[b], cost: bestCost, position: best,
+	})
+}
+
+func (s *simplifier) run(target int) {
+	heap.Init(&s.candidates)
+	for s.liveTris > target && s.candidates.Len() > 0 {
+		c := heap.Pop(&s.candidates).(*collapseCandidate)
+		if s.deleted[c.a] || s.deleted[c.b] || s.versions[c.a] != c.va || s.versions[c.b] != c.vb {
+			continue
+		}
+		if c.cost > s.maxCost {
+			break
+		}
+		if s.canCollapse(c.a, c.b, c.position) {
+			s.collapse(c.a, c.b, c.position)
+		}
+	}
+}
+
+func (s *simplifier) triangleVertices(i int) [3]uint32 {
+	t := &s.mesh.Triangles.Triangle[i]
+	return [3]uint32{t.V1, t.V2, t.V3}
+}
+
+// neighbors returns the vertices connected to v
+// in order of appearance.
+func (s *simplifier) neighbors(v uint32) []uint32 {
+	var n []uint32
+	for _, i := range s.vertexTris[v] {
+		if s.removedTris[i] {
+			continue
+		}
+		for _, w := range s.triangleVertices(i) {
+			if w != v && !containsIndex(n, w) {
+				n = append(n, w)
+			}
+		}
+	}
+	return n
+}
+
+func containsIndex(s []uint32, v uint32) bool {
+	for _, w := range s {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *simplifier) canCollapse(a, b uint32, p vec3) bool {
+	// Link condition: the only common neighbors of a and b
+	// must be the opposite vertices of the triangles sharing the edge.
+	var shared int
+	for _, i := range s.vertexTris[a] {
+		if s.removedTris[i] {
+			continue
+		}
+		fv := s.triangleVertices(i)
+		if fv[0] == b || fv[1] == b || fv[2] == b {
+			shared++
+		}
+	}
+	na, nb := s.neighbors(a), s.neighbors(b)
+	var common int
+	for _, v := range na {
+		if containsIndex(nb, v) {
+			common++
+		}
+	}
+	if common != shared {
+		return false
+	}
+	// Reject collapses that flip or degenerate any of the remaining triangles.
+	for _, v := range [2]uint32{a, b} {
+		for _, i := range s.vertexTris[v] {
+			if s.removedTris[i] {
+				continue
+			}
+			fv := s.triangleVertices(i)
+			if (fv[0] == a || fv[1] == a || fv[2] == a) && (fv[0] == b || fv[1] == b || fv[2] == b) {
+				continue // removed by the collapse
+			}
+			var before, after [3]vec3
+			for j, w := range fv {
+				before[j] = s.positions[w]
+				after[j] = before[j]
+				if w == a || w == b {
+					after[j] = p
+				}
+			}
+			n1 := before[1].sub(before[0]).cross(before[2].sub(before[0]))
+			n2 := after[1].sub(after[0]).cross(after[2].sub(after[0]))
+			if n1.dot(n2) <= 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *simplifier) collapse(a, b uint32, p vec3) {
+	s.positions[a] = p
+	s.quadrics[a] = s.quadrics[a].add(s.quadrics[b])
+	for _, i := range s.vertexTris[b] {
+		if s.removedTris[i] {
+			continue
+		}
+		t := &s.mesh.Triangles.Triangle[i]
+		if t.V1 == a || t.V2 == a || t.V3 == a {
+			s.removedTris[i] = true
+			s.liveTris--
+			continue
+		}
+		switch b {
+		case t.V1:
+			t.V1 = a
+		case t.V2:
+			t.V2 = a
+		case t.V3:
+			t.V3 = a
+		}
+		s.vertexTris[a] = append(s.vertexTris[a], i)
+	}
+	s.vertexTris[b] = nil
+	s.deleted[b] = true
+	s.versions[b]++
+
+	tris := s.vertexTris[a][:0]
+	for _, i := range s.vertexTris[a] {
+		if !s.removedTris[i] {
+			tris = append(tris, i)
+		}
+	}
+	s.vertexTris[a] = tris
+
+	// The validity of the collapses around the one-ring of a may have changed,
+	// so all the edges touching the ring are evaluated again.
+	ring := append(s.neighbors(a), a)
+	for _, n := range ring {
+		s.versions[n]++
+	}
+	for _, n := range ring {
+		for _, w := range s.neighbors(n) {
+			if n < w || !containsIndex(ring, w) {
+				s.pushCandidate(n, w)
+			}
+		}
+	}
+}
+
+// compact removes the collapsed triangles and the unused vertices.
+func (s *simplifier) compact() {
+	m := s.mesh
+	newIndex := make([]uint32, len(s.positions))
+	used := make([]bool, len(s.positions))
+	triangles := m.Triangles.Triangle[:0]
+	for i, t := range m.Triangles.Triangle {
+		if s.removedTris[i] {
+			continue
+		}
+		used[t.V1], used[t.V2], used[t.V3] = true, true, true
+		triangles = append(triangles, t)
+	}
+	vertices := make([]Point3D, 0, len(s.positions))
+	for i, p := range s.positions {
+		if used[i] {
+			newIndex[i] = uint32(len(vertices))
+			vertices = append(vertices, p.point())
+		}
+	}
+	for i := range triangles {
+		t := &triangles[i]
+		t.V1, t.V2, t.V3 = newIndex[t.V1], newIndex[t.V2], newIndex[t.V3]
+	}
+	m.Vertices.Vertex = vertices
+	m.Triangles.Triangle = triangles
+}
+
+// ConvexHull returns a new mesh with the convex hull of the mesh vertices,
+// computed with the quickhull algorithm. Triangles are not considered,
+// so unreferenced vertices are also part of the hull.
+// The returned mesh is closed and its triangles are oriented outwards.
+// It only contains the hull vertices, points lying on a hull face are discarded.
+//
+// If the vertices do not span a volume, that is if there are less than four
+// vertices or they are all coplanar or collinear, an empty mesh is returned.
+func (m *Mesh) ConvexHull() *Mesh {
+	h := newHullBuilder(m.Vertices.Vertex)
+	if h == nil {
+		return new(Mesh)
+	}
+	h.run()
+	return h.mesh()
+}
+
+type hullFace struct {
+	v       [3]int
+	normal  vec3
+	offset  float64
+	outside []int
+	dead    bool
+}
+
+type hullBuilder struct {
+	points []vec3
+	faces  []*hullFace
+	eps    float64
+}
+
+func newHullBuilder(vertices []Point3D) *hullBuilder {
+	if len(vertices) < 4 {
+		return nil
+	}
+	h := &hullBuilder{points: make([]vec3, len(vertices))}
+	box := newLimitBox()
+	for i, v := range vertices {
+		h.points[i] = newVec3(v)
+		box = box.extendPoint(v)
+	}
+	h.eps = newVec3(box.Max).sub(newVec3(box.Min)).len() * 1e-9
+	// Initial tetrahedron from the extreme points.
+	a, b := 0, 0
+	for i, p := range h.points {
+		if p.sub(h.points[a]).len() > h.points[b].sub(h.points[a]).len() {
+			b = i
+		}
+	}
+	ab := h.points[b].sub(h.points[a])
+	c, maxDist := -1, h.eps
+	for i, p := range h.points {
+		if d := ab.cross(p.sub(h.points[a])).len() / ab.len(); d > maxDist {
+			c, maxDist = i, d
+		}
+	}
+	if c < 0 {
+		return nil
+	}
+	n := ab.cross(h.points[c].sub(h.points[a]))
+	n = n.scale(1 / n.len())
+	d, maxDist := -1, h.eps
+	for i, p := range h.points {
+		if dist := math.Abs(n.dot(p.sub(h.points[a]))); dist > maxDist {
+			d, maxDist = i, dist
+		}
+	}
+	if d < 0 {
+		return nil
+	}
+	if n.dot(h.points[d].sub(h.points[a])) > 0 {
+		b, c = c, b
+	}
+	h.faces = []*hullFace{
+		h.newFace(a, b, c), h.newFace(a, d, b),
+		h.newFace(b, d, c), h.newFace(c, d, a),
+	}
+	for i := range h.points {
+		if i != a && i != b && i != c && i != d {
+			h.assign(i, h.faces)
+		}
+	}
+	return h
+}
+
+func (h *hullBuilder) newFace(a, b, c int) *hullFace {
+	n := h.points[b].sub(h.points[a]).cross(h.points[c].sub(h.points[a]))
+	n = n.scale(1 / n.len())
+	return &hullFace{v: [3]int{a, b, c}, normal: n, offset: n.dot(h.points[a])}
+}
+
+func (h *hullBuilder) distance(f *hullFace, i int) float64 {
+	return f.normal.dot(h.points[i]) - f.offset
+}
+
+// assign adds point i to the outside set of the first face it is above of.
+// Points below all the faces are inside the hull and are dropped.
+func (h *hullBuilder) assign(i int, faces []*hullFace) {
+	for _, f := range faces {
+		if h.distance(f, i) > h.eps {
+			f.outside = append(f.outside, i)
+			return
+		}
+	}
+}
+
+func (h *hullBuilder) run() {
+	for k := 0; k < len(h.faces); k++ {
+		f := h.faces[k]
+		if f.dead || len(f.outside) == 0 {
+			continue
+		}
+		eye, maxDist := f.outside[0], h.distance(f, f.outside[0])
+		for _, i := range f.outside[1:] {
+			if d := h.distance(f, i); d > maxDist {
+				eye, maxDist = i, d
+			}
+		}
+		var (
+			visible []*hullFace
+			orphans []int
+		)
+		edges := make(map[[2]int]bool)
+		for _, vf := range h.faces {
+			if !vf.dead && h.distance(vf, eye) > h.eps {
+				vf.dead = true
+				visible = append(visible, vf)
+				orphans = append(orphans, vf.outside...)
+				vf.outside = nil
+				for j := 0; j < 3; j++ {
+					edges[[2]int{vf.v[j], vf.v[(j+1)%3]}] = true
+				}
+			}
+		}
+		var created []*hullFace
+		for _, vf := range visible {
+			for j := 0; j < 3; j++ {
+				u, v := vf.v[j], vf.v[(j+1)%3]
+				if !edges[[2]int{v, u}] {
+					created = append(created, h.newFace(u, v, eye))
+				}
+			}
+		}
+		for _, i := range orphans {
+			if i != eye {
+				h.assign(i, created)
+			}
+		}
+		h.faces = append(h.faces, created...)
+		// The current face is dead, so the loop can continue from the next one.
+	}
+}
+
+func (h *hullBuilder) mesh() *Mesh {
+	m := new(Mesh)
+	index := make(map[int]uint32)
+	for _, f := range h.faces {
+		if f.dead {
+			continue
+		}
+		var t Triangle
+		ids := [3]*uint32{&t.V1, &t.V2, &t.V3}
+		for j, i := range f.v {
+			idx, ok := index[i]
+			if !ok {
+				idx = uint32(len(m.Vertices.Vertex))
+				index[i] = idx
+				m.Vertices.Vertex = append(m.Vertices.Vertex, h.points[i].point())
+			}
+			*ids[j] = idx
+		}
+		m.Triangles.Triangle = append(m.Triangles.Triangle, t)
+	}
+	return m
+}
@@ -0,0 +1,577 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// newGridCube returns a closed cube of the given size whose faces
+// are subdivided in a n*n grid.
+func newGridCube(n int, size float32) *Mesh {
+	m := new(Mesh)
+	mb := NewMeshBuilder(m)
+	step := size / float32(n)
+	faces := []func(u, v float32) Point3D{
+		func(u, v float32) Point3D { return Point3D{v, u, 0} },
+		func(u, v float32) Point3D { return Point3D{u, v, size} },
+		func(u, v float32) Point3D { return Point3D{u, 0, v} },
+		func(u, v float32) Point3D { return Point3D{v, size, u} },
+		func(u, v float32) Point3D { return Point3D{0, v, u} },
+		func(u, v float32) Point3D { return Point3D{size, u, v} },
+	}
+	for _, f := range faces {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				u0, u1 := float32(i)*step, float32(i+1)*step
+				v0, v1 := float32(j)*step, float32(j+1)*step
+				a, b := mb.AddVertex(f(u0, v0)), mb.AddVertex(f(u1, v0))
+				c, d := mb.AddVertex(f(u1, v1)), mb.AddVertex(f(u0, v1))
+				m.Triangles.Triangle = append(m.Triangles.Triangle,
+					Triangle{V1: a, V2: b, V3: c}, Triangle{V1: a, V2: c, V3: d})
+			}
+		}
+	}
+	return m
+}
+
+func TestMesh_Simplify(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       *Mesh
+		target  int
+		wantMax int
+		wantErr bool
+	}{
+		{"negative", newGridCube(1, 10), -1, 12, true},
+		{"outOfBounds", &Mesh{Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}}}, 0, 1, true},
+		{"underTarget", newGridCube(1, 10), 12, 12, false},
+		{"cube", newGridCube(4, 10), 12, 12, false},
+		{"budget", newGridCube(8, 10), 300, 300, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := tt.m.BoundingBox()
+			if err := tt.m.Simplify(tt.target); (err != nil) != tt.wantErr {
+				t.Errorf("Mesh.Simplify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := len(tt.m.Triangles.Triangle); got > tt.wantMax {
+				t.Errorf("Mesh.Simplify() triangles = %d, want <= %d", got, tt.wantMax)
+			}
+			if got := tt.m.BoundingBox(); !reflect.DeepEqual(got, box) {
+				t.Errorf("Mesh.Simplify() box = %v, want %v", got, box)
+			}
+			if err := tt.m.ValidateCoherency(); err != nil {
+				t.Errorf("Mesh.Simplify() coherency = %v", err)
+			}
+		})
+	}
+}
+
+func TestMesh_Simplify_Boundary(t *testing.T) {
+	m := newGridCube(4, 10)
+	// Remove the top face to leave an open border.
+	m.Triangles.Triangle = append(m.Triangles.Triangle[:32], m.Triangles.Triangle[64:]...)
+	var border []Point3D
+	for _, v := range m.Vertices.Vertex {
+		if v.Z() == 10 && (v.X() == 0 || v.X() == 10 || v.Y() == 0 || v.Y() == 10) {
+			border = append(border, v)
+		}
+	}
+	if err := m.Simplify(0); err != nil {
+		t.Fatalf("Mesh.Simplify() error = %v", err)
+	}
+	for _, b := range border {
+		var found bool
+		for _, v := range m.Vertices.Vertex {
+			if v == b {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Mesh.Simplify() border vertex %v removed", b)
+		}
+	}
+}
+
+func TestObject_RecenterToOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		o        *Object
+		want     Point3D
+		wantVert []Point3D
+	}{
+		{"noMesh", new(Object), Point3D{}, nil},
+		{"base", &Object{Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{100, 0, -10}, {110, 20, 10}}}}},
+			Point3D{105, 10, 0}, []Point3D{{-5, -10, -10}, {5, 10, 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.o.RecenterToOrigin(); got != tt.want {
+				t.Errorf("Object.RecenterToOrigin() = %v, want %v", got, tt.want)
+			}
+			if tt.o.Mesh != nil && !reflect.DeepEqual(tt.o.Mesh.Vertices.Vertex, tt.wantVert) {
+				t.Errorf("Object.RecenterToOrigin() vertices = %v, want %v", tt.o.Mesh.Vertices.Vertex, tt.wantVert)
+			}
+		})
+	}
+}
+
+func TestModel_RecenterObject(t *testing.T) {
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: &Mesh{Vertices: Vertices{Vertex: []Point3D{{100, 100, 100}, {110, 110, 110}}}}},
+			{ID: 2, Components: &Components{Component: []*Component{{ObjectID: 1}}}},
+		}},
+		Build: Build{Items: []*Item{
+			{ObjectID: 1, Transform: Identity().Translate(0, 0, 5)},
+			{ObjectID: 2},
+		}},
+	}
+	want := m.BoundingBox()
+	if _, ok := m.RecenterObject("", 3); ok {
+		t.Error("Model.RecenterObject() expected not found")
+	}
+	offset, ok := m.RecenterObject("", 1)
+	if !ok || offset != (Point3D{105, 105, 105}) {
+		t.Errorf("Model.RecenterObject() = %v %v", offset, ok)
+	}
+	if got := m.BoundingBox(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Model.RecenterObject() box = %v, want %v", got, want)
+	}
+	wantComp := Identity().Translate(105, 105, 105)
+	if got := m.Resources.Objects[1].Components.Component[0].Transform; got != wantComp {
+		t.Errorf("Model.RecenterObject() component transform = %v, want %v", got, wantComp)
+	}
+}
+
+func TestMesh_Translate(t *testing.T) {
+	m := &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 2, 3}}}}
+	m.Translate(Point3D{10, -1, 0.5})
+	want := []Point3D{{10, -1, 0.5}, {11, 1, 3.5}}
+	if !reflect.DeepEqual(m.Vertices.Vertex, want) {
+		t.Errorf("Mesh.Translate() = %v, want %v", m.Vertices.Vertex, want)
+	}
+}
+
+func TestMesh_Scale(t *testing.T) {
+	tests := []struct {
+		name     string
+		factor   float32
+		wantVert []Point3D
+		wantTri  []Triangle
+	}{
+		{"positive", 2, []Point3D{{0, 0, 0}, {2, 0, 0}, {0, 4, 0}}, []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 1, P3: 2}}},
+		{"negative", -1, []Point3D{{0, 0, 0}, {-1, 0, 0}, {0, -2, 0}}, []Triangle{{V1: 0, V2: 2, V3: 1, PID: 1, P1: 0, P2: 2, P3: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Mesh{
+				Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}}},
+				Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 1, P3: 2}}},
+			}
+			m.Scale(tt.factor)
+			if !reflect.DeepEqual(m.Vertices.Vertex, tt.wantVert) {
+				t.Errorf("Mesh.Scale() vertices = %v, want %v", m.Vertices.Vertex, tt.wantVert)
+			}
+			if !reflect.DeepEqual(m.Triangles.Triangle, tt.wantTri) {
+				t.Errorf("Mesh.Scale() triangles = %v, want %v", m.Triangles.Triangle, tt.wantTri)
+			}
+		})
+	}
+}
+
+func TestMesh_Transform(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        Matrix
+		wantVert []Point3D
+		wantTri  []Triangle
+	}{
+		{"zero", Matrix{}, []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}}, []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 1, P3: 2}}},
+		{"translate", Identity().Translate(1, 2, 3), []Point3D{{1, 2, 3}, {2, 2, 3}, {1, 4, 3}}, []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 1, P3: 2}}},
+		{"mirror", Matrix{-1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, []Point3D{{0, 0, 0}, {-1, 0, 0}, {0, 2, 0}}, []Triangle{{V1: 0, V2: 2, V3: 1, PID: 1, P1: 0, P2: 2, P3: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Mesh{
+				Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 2, 0}}},
+				Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2, PID: 1, P1: 0, P2: 1, P3: 2}}},
+			}
+			m.Transform(tt.t)
+			if !reflect.DeepEqual(m.Vertices.Vertex, tt.wantVert) {
+				t.Errorf("Mesh.Transform() vertices = %v, want %v", m.Vertices.Vertex, tt.wantVert)
+			}
+			if !reflect.DeepEqual(m.Triangles.Triangle, tt.wantTri) {
+				t.Errorf("Mesh.Transform() triangles = %v, want %v", m.Triangles.Triangle, tt.wantTri)
+			}
+		})
+	}
+}
+
+func TestMesh_VolumeSurfaceArea(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          *Mesh
+		wantVolume float64
+		wantArea   float64
+	}{
+		{"empty", new(Mesh), 0, 0},
+		{"unitCube", newGridCube(1, 1), 1, 6},
+		{"gridCube", newGridCube(3, 2), 8, 24},
+		{"outOfRange", &Mesh{
+			Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+			Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 3}}},
+		}, 0, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Volume(); math.Abs(got-tt.wantVolume) > 1e-6 {
+				t.Errorf("Mesh.Volume() = %v, want %v", got, tt.wantVolume)
+			}
+			if got := tt.m.SurfaceArea(); math.Abs(got-tt.wantArea) > 1e-6 {
+				t.Errorf("Mesh.SurfaceArea() = %v, want %v", got, tt.wantArea)
+			}
+		})
+	}
+}
+
+func TestMesh_Faces(t *testing.T) {
+	m := &Mesh{
+		Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+		Triangles: Triangles{Triangle: []Triangle{
+			{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 9}, {V1: 3, V2: 2, V3: 1}, {V1: 0, V2: 3, V3: 2},
+		}},
+	}
+	type face struct {
+		i       uint32
+		a, b, c Point3D
+	}
+	tests := []struct {
+		name  string
+		limit int
+		want  []face
+	}{
+		{"all", 10, []face{
+			{0, Point3D{0, 0, 0}, Point3D{1, 0, 0}, Point3D{0, 1, 0}},
+			{2, Point3D{0, 0, 1}, Point3D{0, 1, 0}, Point3D{1, 0, 0}},
+			{3, Point3D{0, 0, 0}, Point3D{0, 0, 1}, Point3D{0, 1, 0}},
+		}},
+		{"stop", 2, []face{
+			{0, Point3D{0, 0, 0}, Point3D{1, 0, 0}, Point3D{0, 1, 0}},
+			{2, Point3D{0, 0, 1}, Point3D{0, 1, 0}, Point3D{1, 0, 0}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []face
+			m.Faces(func(i uint32, a, b, c Point3D) bool {
+				got = append(got, face{i, a, b, c})
+				return len(got) < tt.limit
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mesh.Faces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMesh_VertexNeighbors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *Mesh
+		want [][]uint32
+	}{
+		{"empty", new(Mesh), [][]uint32{}},
+		{"isolated", &Mesh{Vertices: Vertices{Vertex: []Point3D{{}, {}}}}, [][]uint32{nil, nil}},
+		{"fan", &Mesh{
+			Vertices: Vertices{Vertex: make([]Point3D, 5)},
+			Triangles: Triangles{Triangle: []Triangle{
+				{V1: 4, V2: 0, V3: 1}, {V1: 4, V2: 1, V3: 2}, {V1: 4, V2: 2, V3: 3}, {V1: 4, V2: 3, V3: 0},
+				{V1: 4, V2: 0, V3: 1}, {V1: 0, V2: 1, V3: 7}, {V1: 2, V2: 2, V3: 3},
+			}},
+		}, [][]uint32{{1, 3, 4}, {0, 2, 4}, {1, 3, 4}, {0, 2, 4}, {0, 1, 2, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.VertexNeighbors(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mesh.VertexNeighbors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMesh_Smooth(t *testing.T) {
+	// Square pyramid without base: a fan around the apex,
+	// whose corners are boundary vertices.
+	newPyramid := func() *Mesh {
+		return &Mesh{
+			Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0}, {1, 1, 1}}},
+			Triangles: Triangles{Triangle: []Triangle{
+				{V1: 0, V2: 1, V3: 4, PID: 1}, {V1: 1, V2: 2, V3: 4}, {V1: 2, V2: 3, V3: 4}, {V1: 3, V2: 0, V3: 4},
+			}},
+		}
+	}
+	tests := []struct {
+		name        string
+		iterations  int
+		factor      float32
+		pinBoundary bool
+		want        []Point3D
+	}{
+		{"none", 0, 0.5, true, []Point3D{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0}, {1, 1, 1}}},
+		{"pinned", 1, 0.5, true, []Point3D{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0}, {1, 1, 0.5}}},
+		{"pinnedTwice", 2, 0.5, true, []Point3D{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0}, {1, 1, 0.25}}},
+		{"full", 1, 1, true, []Point3D{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0}, {1, 1, 0}}},
+		{"free", 1, 0.5, false, []Point3D{
+			{0.5, 0.5, 1.0 / 6}, {1.5, 0.5, 1.0 / 6}, {1.5, 1.5, 1.0 / 6}, {0.5, 1.5, 1.0 / 6}, {1, 1, 0.5},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newPyramid()
+			m.Smooth(tt.iterations, tt.factor, tt.pinBoundary)
+			for i, v := range m.Vertices.Vertex {
+				for j := 0; j < 3; j++ {
+					if math.Abs(float64(v[j]-tt.want[i][j])) > 1e-6 {
+						t.Fatalf("Mesh.Smooth() = %v, want %v", m.Vertices.Vertex, tt.want)
+					}
+				}
+			}
+			if want := newPyramid().Triangles; !reflect.DeepEqual(m.Triangles, want) {
+				t.Errorf("Mesh.Smooth() triangles = %v, want %v", m.Triangles, want)
+			}
+		})
+	}
+}
+
+// newFold returns two right triangles sharing the edge 0-1
+// and folded 90 degrees, plus a degenerate and an out of range triangle.
+func newFold() *Mesh {
+	return &Mesh{
+		Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {5, 5, 5}}},
+		Triangles: Triangles{Triangle: []Triangle{
+			{V1: 0, V2: 1, V3: 2}, {V1: 1, V2: 0, V3: 3}, {V1: 4, V2: 4, V3: 0}, {V1: 0, V2: 1, V3: 9},
+		}},
+	}
+}
+
+func TestMesh_VertexNormals(t *testing.T) {
+	d := float32(1 / math.Sqrt2)
+	want := []Point3D{{0, d, d}, {0, d, d}, {0, 0, 1}, {0, 1, 0}, {}}
+	if got := newFold().VertexNormals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mesh.VertexNormals() = %v, want %v", got, want)
+	}
+	if got := new(Mesh).VertexNormals(); got != nil {
+		t.Errorf("Mesh.VertexNormals() = %v, want nil", got)
+	}
+	if got := (&Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}}}}).VertexNormals(); got != nil {
+		t.Errorf("Mesh.VertexNormals() = %v, want nil", got)
+	}
+}
+
+func TestMesh_CornerNormals(t *testing.T) {
+	d := float32(1 / math.Sqrt2)
+	tests := []struct {
+		name        string
+		creaseAngle float64
+		want        [][3]Point3D
+	}{
+		{"flat", 0, [][3]Point3D{
+			{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+			{{0, 1, 0}, {0, 1, 0}, {0, 1, 0}},
+			{{}, {}, {0, d, d}},
+			{},
+		}},
+		{"sharp", math.Pi / 4, [][3]Point3D{
+			{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+			{{0, 1, 0}, {0, 1, 0}, {0, 1, 0}},
+			{{}, {}, {0, d, d}},
+			{},
+		}},
+		{"smooth", math.Pi / 2, [][3]Point3D{
+			{{0, d, d}, {0, d, d}, {0, 0, 1}},
+			{{0, d, d}, {0, d, d}, {0, 1, 0}},
+			{{}, {}, {0, d, d}},
+			{},
+		}},
+		{"all", 2 * math.Pi, [][3]Point3D{
+			{{0, d, d}, {0, d, d}, {0, 0, 1}},
+			{{0, d, d}, {0, d, d}, {0, 1, 0}},
+			{{}, {}, {0, d, d}},
+			{},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newFold().CornerNormals(tt.creaseAngle); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mesh.CornerNormals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMesh_SubMesh(t *testing.T) {
+	m := &Mesh{
+		Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+		Triangles: Triangles{Triangle: []Triangle{
+			{V1: 0, V2: 2, V3: 1, PID: 1, P1: 1, P2: 2, P3: 3},
+			{V1: 0, V2: 1, V3: 3},
+			{V1: 0, V2: 3, V3: 2},
+			{V1: 1, V2: 2, V3: 3, PID: 2},
+			{V1: 1, V2: 2, V3: 10},
+		}},
+	}
+	tests := []struct {
+		name    string
+		indices []int
+		want    *Mesh
+	}{
+		{"empty", nil, new(Mesh)},
+		{"ignored", []int{-1, 4, 5}, new(Mesh)},
+		{"base", []int{3, 1}, &Mesh{
+			Vertices:  Vertices{Vertex: []Point3D{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {0, 0, 0}}},
+			Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2, PID: 2}, {V1: 3, V2: 0, V3: 2}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.SubMesh(tt.indices); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mesh.SubMesh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMesh_SubMesh_All(t *testing.T) {
+	m := newGridCube(3, 10)
+	indices := make([]int, len(m.Triangles.Triangle))
+	for i := range indices {
+		indices[i] = i
+	}
+	sub := m.SubMesh(indices)
+	if len(sub.Vertices.Vertex) != len(m.Vertices.Vertex) || len(sub.Triangles.Triangle) != len(m.Triangles.Triangle) {
+		t.Fatalf("Mesh.SubMesh() = %d vertices %d triangles", len(sub.Vertices.Vertex), len(sub.Triangles.Triangle))
+	}
+	for i, tri := range m.Triangles.Triangle {
+		got := sub.Triangles.Triangle[i]
+		if sub.Vertices.Vertex[got.V1] != m.Vertices.Vertex[tri.V1] ||
+			sub.Vertices.Vertex[got.V2] != m.Vertices.Vertex[tri.V2] ||
+			sub.Vertices.Vertex[got.V3] != m.Vertices.Vertex[tri.V3] {
+			t.Errorf("Mesh.SubMesh() triangle %d = %v, want %v", i, got, tri)
+		}
+	}
+}
+
+func TestMesh_ConvexHull(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        *Mesh
+		wantVert int
+		wantTri  int
+	}{
+		{"empty", new(Mesh), 0, 0},
+		{"fewPoints", &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}}}, 0, 0},
+		{"coplanar", &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {1, 1, 0}, {2, 3, 0}}}}, 0, 0},
+		{"collinear", &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 1, 1}, {2, 2, 2}, {3, 3, 3}}}}, 0, 0},
+		{"tetrahedron", &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {0.1, 0.1, 0.1}}}}, 4, 4},
+		{"cube", newGridCube(4, 10), 8, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.m.ConvexHull()
+			if len(got.Vertices.Vertex) != tt.wantVert || len(got.Triangles.Triangle) != tt.wantTri {
+				t.Fatalf("Mesh.ConvexHull() = %d vertices %d triangles, want %d %d",
+					len(got.Vertices.Vertex), len(got.Triangles.Triangle), tt.wantVert, tt.wantTri)
+			}
+			if tt.wantTri > 0 {
+				checkConvexHull(t, got, tt.m.Vertices.Vertex)
+			}
+		})
+	}
+}
+
+func TestMesh_ConvexHull_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	m := new(Mesh)
+	for i := 0; i < 2000; i++ {
+		m.Vertices.Vertex = append(m.Vertices.Vertex, Point3D{rnd.Float32() * 100, rnd.Float32() * 50, rnd.Float32() * 10})
+	}
+	checkConvexHull(t, m.ConvexHull(), m.Vertices.Vertex)
+}
+
+// checkConvexHull verifies that hull is closed and that all
+// the points are behind each of its outward oriented faces.
+func checkConvexHull(t *testing.T, hull *Mesh, points []Point3D) {
+	t.Helper()
+	if err := hull.ValidateCoherency(); err != nil {
+		t.Errorf("Mesh.ConvexHull() coherency = %v", err)
+	}
+	for i, tri := range hull.Triangles.Triangle {
+		a := newVec3(hull.Vertices.Vertex[tri.V1])
+		n := newVec3(hull.Vertices.Vertex[tri.V2]).sub(a).cross(newVec3(hull.Vertices.Vertex[tri.V3]).sub(a))
+		n = n.scale(1 / n.len())
+		for _, p := range points {
+			if d := n.dot(newVec3(p).sub(a)); d > 1e-4 {
+				t.Fatalf("Mesh.ConvexHull() point %v is %v in front of face %d", p, d, i)
+			}
+		}
+	}
+}
+
+func TestObject_AreaByMaterial(t *testing.T) {
+	newCube := func() *Mesh {
+		m := newGridCube(1, 1)
+		// Bottom face colored with a gradient.
+		m.Triangles.Triangle[0].PID, m.Triangles.Triangle[0].P1 = 2, 0
+		m.Triangles.Triangle[0].P2, m.Triangles.Triangle[0].P3 = 1, 1
+		m.Triangles.Triangle[1].PID, m.Triangles.Triangle[1].P1 = 2, 1
+		m.Triangles.Triangle[1].P2, m.Triangles.Triangle[1].P3 = 1, 1
+		return m
+	}
+	plain := &Object{ID: 1, Mesh: newCube()}
+	colored := &Object{ID: 2, PID: 1, PIndex: 3, Mesh: newCube()}
+	model := &Model{Resources: Resources{Objects: []*Object{plain, colored}}}
+	assembly := &Object{ID: 3, Components: &Components{Component: []*Component{
+		{ObjectID: 1},
+		{ObjectID: 2, Transform: Identity().Translate(5, 0, 0).Mul(Matrix{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1})},
+		{ObjectID: 3},
+		{ObjectID: 100},
+	}}}
+	model.Resources.Objects = append(model.Resources.Objects, assembly)
+	tests := []struct {
+		name string
+		o    *Object
+		want map[PropertyRef]float64
+	}{
+		{"empty", new(Object), map[PropertyRef]float64{}},
+		{"plain", plain, map[PropertyRef]float64{{}: 5, {PID: 2, PIndex: 0}: 0.5 / 3, {PID: 2, PIndex: 1}: 0.5*2/3 + 0.5}},
+		{"inherited", colored, map[PropertyRef]float64{{PID: 1, PIndex: 3}: 5, {PID: 2, PIndex: 0}: 0.5 / 3, {PID: 2, PIndex: 1}: 0.5*2/3 + 0.5}},
+		{"components", assembly, map[PropertyRef]float64{
+			{}: 5, {PID: 1, PIndex: 3}: 20,
+			{PID: 2, PIndex: 0}: 0.5/3 + 2.0/3, {PID: 2, PIndex: 1}: 0.5*2/3 + 0.5 + 2*2.0/3 + 2,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.o.AreaByMaterial(model)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Object.AreaByMaterial() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if math.Abs(got[k]-v) > 1e-6 {
+					t.Errorf("Object.AreaByMaterial()[%v] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
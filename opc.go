@@ -4,7 +4,15 @@
 package go3mf
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/xml"
 	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/qmuntal/opc"
 )
@@ -28,16 +36,29 @@ func (o *opcPart) AddRelationship(r Relationship) {
 }
 
 type opcWriter struct {
-	w *opc.Writer
+	w           *opc.Writer
+	out         io.Writer
+	buff        *bytes.Buffer // not nil when the entries have to be restamped
+	modTime     time.Time
+	compression Compression
 }
 
 func newOpcWriter(w io.Writer) *opcWriter {
-	return &opcWriter{opc.NewWriter(w)}
+	return &opcWriter{w: opc.NewWriter(w), out: w}
+}
+
+// setModTime makes all the package entries be stamped with t.
+// The package is buffered until Close, so it has to be called
+// before creating any part.
+func (o *opcWriter) setModTime(t time.Time) {
+	o.modTime = t
+	o.buff = new(bytes.Buffer)
+	o.w = opc.NewWriter(o.buff)
 }
 
 func (o *opcWriter) Create(name, contentType string) (packagePart, error) {
 	p := &opc.Part{Name: opc.NormalizePartName(name), ContentType: contentType}
-	w, err := o.w.CreatePart(p, opc.CompressionNormal)
+	w, err := o.w.CreatePart(p, o.compression.option())
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +79,107 @@ func (o *opcWriter) AddRelationship(r Relationship) {
 }
 
 func (o *opcWriter) Close() error {
-	return o.w.Close()
+	if err := o.w.Close(); err != nil {
+		return err
+	}
+	if o.buff == nil {
+		return nil
+	}
+	return restampZip(o.out, o.buff.Bytes(), o.modTime, o.compression)
+}
+
+func (c Compression) option() opc.CompressionOption {
+	switch c {
+	case CompressionMaximum:
+		return opc.CompressionMaximum
+	case CompressionFast:
+		return opc.CompressionFast
+	case CompressionNone:
+		return opc.CompressionNone
+	}
+	return opc.CompressionNormal
+}
+
+func (c Compression) level() int {
+	switch c {
+	case CompressionMaximum:
+		return flate.BestCompression
+	case CompressionFast:
+		return flate.BestSpeed
+	case CompressionNone:
+		return flate.NoCompression
+	}
+	return flate.DefaultCompression
+}
+
+// restampZip copies the zip archive b into w setting the
+// modification time of every entry to t and recompressing them with c.
+func restampZip(w io.Writer, b []byte, t time.Time, c Compression) error {
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, c.level())
+	})
+	for _, f := range r.File {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: t})
+		if err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		if f.Name == contentTypesName {
+			err = sortContentTypes(fw, rc)
+		} else {
+			_, err = io.Copy(fw, rc)
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+const contentTypesName = "[Content_Types].xml"
+
+type contentTypes struct {
+	XMLName   xml.Name              `xml:"http://schemas.openxmlformats.org/package/2006/content-types Types"`
+	Defaults  []contentTypeDefault  `xml:"Default"`
+	Overrides []contentTypeOverride `xml:"Override"`
+}
+
+type contentTypeDefault struct {
+	Extension   string `xml:",attr"`
+	ContentType string `xml:",attr"`
+}
+
+type contentTypeOverride struct {
+	PartName    string `xml:",attr"`
+	ContentType string `xml:",attr"`
+}
+
+// sortContentTypes copies the content types part from r to w
+// sorting its entries, which the OPC writer emits in random order.
+func sortContentTypes(w io.Writer, r io.Reader) error {
+	var ct contentTypes
+	if err := xml.NewDecoder(r).Decode(&ct); err != nil {
+		return err
+	}
+	sort.Slice(ct.Defaults, func(i, j int) bool {
+		return ct.Defaults[i].Extension < ct.Defaults[j].Extension
+	})
+	sort.Slice(ct.Overrides, func(i, j int) bool {
+		return ct.Overrides[i].PartName < ct.Overrides[j].PartName
+	})
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(&ct)
 }
 
 func newRelationships(rels []*opc.Relationship) []Relationship {
@@ -70,8 +191,9 @@ func newRelationships(rels []*opc.Relationship) []Relationship {
 }
 
 type opcFile struct {
-	r *opc.Reader
-	f *opc.File
+	r             *opc.Reader
+	f             *opc.File
+	caseSensitive bool
 }
 
 func (o *opcFile) Open() (io.ReadCloser, error) {
@@ -88,7 +210,7 @@ func (o *opcFile) ContentType() string {
 
 func (o *opcFile) FindFileFromName(name string) (packageFile, bool) {
 	name = opc.ResolveRelationship(o.f.Name, name)
-	return findOPCFileFromName(name, o.r)
+	return findOPCFileFromName(name, o.r, o.caseSensitive)
 }
 
 func (o *opcFile) Relationships() []Relationship {
@@ -96,9 +218,10 @@ func (o *opcFile) Relationships() []Relationship {
 }
 
 type opcReader struct {
-	ra   io.ReaderAt
-	size int64
-	r    *opc.Reader // nil until call Open.
+	ra            io.ReaderAt
+	size          int64
+	r             *opc.Reader // nil until call Open.
+	caseSensitive bool
 }
 
 func (o *opcReader) Open(f func(r io.Reader) io.ReadCloser) (err error) {
@@ -115,18 +238,46 @@ func (o *opcReader) Relationships() []Relationship {
 
 func (o *opcReader) FindFileFromName(name string) (packageFile, bool) {
 	name = opc.ResolveRelationship("/", name)
-	return findOPCFileFromName(name, o.r)
+	return findOPCFileFromName(name, o.r, o.caseSensitive)
 }
 
 func resolveRelationship(source, rel string) string {
 	return opc.ResolveRelationship(source, rel)
 }
 
-func findOPCFileFromName(name string, r *opc.Reader) (packageFile, bool) {
+func findOPCFileFromName(name string, r *opc.Reader, caseSensitive bool) (packageFile, bool) {
 	for _, f := range r.Files {
 		if f.Name == name {
-			return &opcFile{r, f}, true
+			return &opcFile{r, f, caseSensitive}, true
+		}
+	}
+	// Some producers write percent-encoded or backslash separated targets
+	// that do not match the part name literally.
+	name = normalizePartName(name)
+	for _, f := range r.Files {
+		if normalizePartName(f.Name) == name {
+			return &opcFile{r, f, caseSensitive}, true
+		}
+	}
+	if !caseSensitive {
+		for _, f := range r.Files {
+			if strings.EqualFold(normalizePartName(f.Name), name) {
+				return &opcFile{r, f, caseSensitive}, true
+			}
 		}
 	}
 	return nil, false
 }
+
+// normalizePartName returns name with forward slash separators,
+// percent-encoded characters decoded and a leading slash.
+func normalizePartName(name string) string {
+	name = strings.Replace(name, "\\", "/", -1)
+	if s, err := url.PathUnescape(name); err == nil {
+		name = s
+	}
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return name
+}
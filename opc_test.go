@@ -36,8 +36,8 @@ func Test_opcFile_Name(t *testing.T) {
 		o    *opcFile
 		want string
 	}{
-		{"empty", &opcFile{nil, &opc.File{Part: new(opc.Part)}}, ""},
-		{"base", &opcFile{nil, &opc.File{Part: &opc.Part{Name: "a.xml"}}}, "a.xml"},
+		{"empty", &opcFile{nil, &opc.File{Part: new(opc.Part)}, false}, ""},
+		{"base", &opcFile{nil, &opc.File{Part: &opc.Part{Name: "a.xml"}}, false}, "a.xml"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -54,11 +54,11 @@ func Test_opcFile_Relationships(t *testing.T) {
 		o    *opcFile
 		want []Relationship
 	}{
-		{"empty", &opcFile{nil, &opc.File{Part: new(opc.Part)}}, []Relationship{}},
+		{"empty", &opcFile{nil, &opc.File{Part: new(opc.Part)}, false}, []Relationship{}},
 		{"base", &opcFile{nil, &opc.File{Part: &opc.Part{Relationships: []*opc.Relationship{
 			{Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dtexture", TargetURI: "/a.xml"},
 			{Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel", TargetURI: "/b.xml"},
-		}}}}, []Relationship{
+		}}}, false}, []Relationship{
 			{Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dtexture", Path: "/a.xml"},
 			{Type: "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel", Path: "/b.xml"},
 		}},
@@ -73,7 +73,10 @@ func Test_opcFile_Relationships(t *testing.T) {
 }
 
 func Test_opcReader_FindFileFromName(t *testing.T) {
-	reader := &opc.Reader{Files: []*opc.File{{Part: &opc.Part{Name: "/a.xml"}}, {Part: &opc.Part{Name: "/b.xml"}}}}
+	reader := &opc.Reader{Files: []*opc.File{
+		{Part: &opc.Part{Name: "/a.xml"}}, {Part: &opc.Part{Name: "/b.xml"}},
+		{Part: &opc.Part{Name: "/3D/my%20model.model"}}, {Part: &opc.Part{Name: "/3D/~tilde.model"}},
+	}}
 	type args struct {
 		name string
 	}
@@ -83,9 +86,15 @@ func Test_opcReader_FindFileFromName(t *testing.T) {
 		args args
 		want packageFile
 	}{
-		{"foundA", &opcReader{nil, 0, reader}, args{"/a.xml"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/a.xml"}}}},
-		{"foundB", &opcReader{nil, 0, reader}, args{"/b.xml"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/b.xml"}}}},
-		{"notfound", &opcReader{nil, 0, reader}, args{"/c.xml"}, nil},
+		{"foundA", &opcReader{nil, 0, reader, false}, args{"/a.xml"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/a.xml"}}, false}},
+		{"foundB", &opcReader{nil, 0, reader, false}, args{"/b.xml"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/b.xml"}}, false}},
+		{"notfound", &opcReader{nil, 0, reader, false}, args{"/c.xml"}, nil},
+		{"encoded", &opcReader{nil, 0, reader, false}, args{"/3D/my model.model"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/3D/my%20model.model"}}, false}},
+		{"backslash", &opcReader{nil, 0, reader, false}, args{"\\3D\\my%20model.model"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/3D/my%20model.model"}}, false}},
+		{"relative", &opcReader{nil, 0, reader, false}, args{"3D/my%20model.model"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/3D/my%20model.model"}}, false}},
+		{"encodedPart", &opcReader{nil, 0, reader, false}, args{"/3D/%7Etilde.model"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/3D/~tilde.model"}}, false}},
+		{"case", &opcReader{nil, 0, reader, false}, args{"/A.XML"}, &opcFile{reader, &opc.File{Part: &opc.Part{Name: "/a.xml"}}, false}},
+		{"caseSensitive", &opcReader{nil, 0, reader, true}, args{"/A.XML"}, nil},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
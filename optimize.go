@@ -0,0 +1,161 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"io"
+	"time"
+)
+
+// optimizeModTime is the modification time of the entries written by Optimize,
+// the earliest one representable in a zip file.
+var optimizeModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// OptimizeOptions configures the steps run by Optimize.
+// The zero value runs every step welding only identical vertices.
+type OptimizeOptions struct {
+	// SkipDeduplicate disables merging identical objects, see Model.DeduplicateObjects.
+	SkipDeduplicate bool
+	// SkipPrune disables removing the objects not reachable from the build.
+	SkipPrune bool
+	// SkipWeld disables merging duplicated vertices.
+	SkipWeld bool
+	// WeldTolerance is the maximum distance between two vertices
+	// to be merged. Zero only merges vertices with identical coordinates.
+	WeldTolerance float32
+	// FloatPrecision is the number of decimals written for each coordinate,
+	// which quantizes them. Zero uses the Encoder default.
+	FloatPrecision int
+}
+
+// OptimizeReport summarizes the changes done by Optimize.
+// The counts include the objects, vertices and triangles of every model part.
+type OptimizeReport struct {
+	InputSize, OutputSize           int64
+	InputObjects, OutputObjects     int
+	InputVertices, OutputVertices   int
+	InputTriangles, OutputTriangles int
+}
+
+// Optimize decodes the 3MF package read from in, makes it smaller and
+// writes it to out. The steps are run in this order, each one can be disabled in opts:
+//   - Deduplicate: identical mesh objects are merged as in Model.DeduplicateObjects.
+//   - Prune: the objects not referenced by a build item, directly or through
+//     components, are removed.
+//   - Weld: vertices closer than opts.WeldTolerance are merged into the first one,
+//     and the triangles left with repeated vertices are removed, as well as
+//     the triangles referencing out of range vertices.
+//
+// The output is deterministic, encoding the same input with the same
+// options always produces the same bytes.
+//
+// Model parts containing meshes with extension elements, such as beam lattices,
+// are not deduplicated nor pruned and those meshes are not welded, as the elements
+// may reference other objects or vertex indices. Attachments and assets are written untouched.
+func Optimize(in io.ReaderAt, size int64, out io.Writer, opts OptimizeOptions) (OptimizeReport, error) {
+	report := OptimizeReport{InputSize: size}
+	var m Model
+	if err := NewDecoder(in, size).Decode(&m); err != nil {
+		return report, err
+	}
+	report.InputObjects, report.InputVertices, report.InputTriangles = m.geometryCounts()
+	if !opts.SkipDeduplicate {
+		m.DeduplicateObjects()
+	}
+	if !opts.SkipPrune {
+		m.pruneUnreachableObjects()
+	}
+	if !opts.SkipWeld {
+		m.WalkObjects(func(_ string, o *Object) error {
+			if o.Mesh != nil && len(o.Mesh.Any) == 0 {
+				o.Mesh.weld(opts.WeldTolerance)
+			}
+			return nil
+		})
+	}
+	report.OutputObjects, report.OutputVertices, report.OutputTriangles = m.geometryCounts()
+	cw := &countWriter{w: out}
+	enc := NewEncoder(cw)
+	enc.ModTime = optimizeModTime
+	if opts.FloatPrecision > 0 {
+		enc.FloatPrecision = opts.FloatPrecision
+	}
+	err := enc.Encode(&m)
+	report.OutputSize = cw.n
+	return report, err
+}
+
+// pruneUnreachableObjects removes the objects that are not
+// referenced by a build item, directly or through components,
+// from the model parts without mesh extension elements.
+func (m *Model) pruneUnreachableObjects() (pruned int) {
+	reachable := make(map[instanceKey]bool)
+	var visit func(path string, id uint32)
+	visit = func(path string, id uint32) {
+		key := instanceKey{path, id}
+		if reachable[key] {
+			return
+		}
+		reachable[key] = true
+		if o, ok := m.FindObject(path, id); ok && o.Components != nil {
+			for _, c := range o.Components.Component {
+				visit(c.ObjectPath(path), c.ObjectID)
+			}
+		}
+	}
+	rootPath := m.PathOrDefault()
+	for _, item := range m.Build.Items {
+		path := item.ObjectPath()
+		if path == "" {
+			path = rootPath
+		}
+		visit(path, item.ObjectID)
+	}
+	prune := func(path string, rs *Resources) {
+		for _, o := range rs.Objects {
+			if o.Mesh != nil && len(o.Mesh.Any) != 0 {
+				return
+			}
+		}
+		objects := rs.Objects[:0]
+		for _, o := range rs.Objects {
+			if reachable[instanceKey{path, o.ID}] {
+				objects = append(objects, o)
+			}
+		}
+		for i := len(objects); i < len(rs.Objects); i++ {
+			rs.Objects[i] = nil
+		}
+		pruned += len(rs.Objects) - len(objects)
+		rs.Objects = objects
+	}
+	prune(rootPath, &m.Resources)
+	for path, c := range m.Childs {
+		prune(path, &c.Resources)
+	}
+	return
+}
+
+func (m *Model) geometryCounts() (objects, vertices, triangles int) {
+	m.WalkObjects(func(_ string, o *Object) error {
+		objects++
+		if o.Mesh != nil {
+			vertices += len(o.Mesh.Vertices.Vertex)
+			triangles += len(o.Mesh.Triangles.Triangle)
+		}
+		return nil
+	})
+	return
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
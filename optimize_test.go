@@ -0,0 +1,108 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hpinc/go3mf/spec"
+)
+
+func TestOptimize(t *testing.T) {
+	newTetra := func(id uint32) *Object {
+		return &Object{ID: id, Mesh: &Mesh{
+			// Vertex 4 duplicates vertex 0.
+			Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {0, 0, 0}}},
+			Triangles: Triangles{Triangle: []Triangle{
+				{V1: 0, V2: 2, V3: 1}, {V1: 4, V2: 1, V3: 3}, {V1: 0, V2: 3, V3: 2}, {V1: 1, V2: 2, V3: 3},
+			}},
+		}}
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			newTetra(1),
+			newTetra(2),
+			newTetra(3),
+			{ID: 4, Components: &Components{Component: []*Component{{ObjectID: 2, Transform: Identity().Translate(5, 0, 0)}}}},
+		}},
+		Build: Build{Items: []*Item{{ObjectID: 1}, {ObjectID: 4}}},
+	}
+	var in bytes.Buffer
+	if err := NewEncoder(&in).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		opts OptimizeOptions
+		want OptimizeReport
+	}{
+		{"all", OptimizeOptions{}, OptimizeReport{
+			InputObjects: 4, OutputObjects: 2, InputVertices: 15, OutputVertices: 4, InputTriangles: 12, OutputTriangles: 4,
+		}},
+		{"skipAll", OptimizeOptions{SkipDeduplicate: true, SkipPrune: true, SkipWeld: true}, OptimizeReport{
+			InputObjects: 4, OutputObjects: 4, InputVertices: 15, OutputVertices: 15, InputTriangles: 12, OutputTriangles: 12,
+		}},
+		{"skipDeduplicate", OptimizeOptions{SkipDeduplicate: true}, OptimizeReport{
+			InputObjects: 4, OutputObjects: 3, InputVertices: 15, OutputVertices: 8, InputTriangles: 12, OutputTriangles: 8,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := Optimize(bytes.NewReader(in.Bytes()), int64(in.Len()), &out, tt.opts)
+			if err != nil {
+				t.Fatalf("Optimize() error = %v", err)
+			}
+			if got.InputSize != int64(in.Len()) || got.OutputSize != int64(out.Len()) {
+				t.Errorf("Optimize() sizes = %d %d, want %d %d", got.InputSize, got.OutputSize, in.Len(), out.Len())
+			}
+			tt.want.InputSize, tt.want.OutputSize = got.InputSize, got.OutputSize
+			if got != tt.want {
+				t.Errorf("Optimize() = %+v, want %+v", got, tt.want)
+			}
+			var again bytes.Buffer
+			if _, err := Optimize(bytes.NewReader(in.Bytes()), int64(in.Len()), &again, tt.opts); err != nil {
+				t.Fatalf("Optimize() error = %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), again.Bytes()) {
+				t.Error("Optimize() is not deterministic")
+			}
+			var dec Model
+			if err := NewDecoder(bytes.NewReader(out.Bytes()), int64(out.Len())).Decode(&dec); err != nil {
+				t.Fatalf("Decoder.Decode() error = %v", err)
+			}
+			if err := dec.Validate(); err != nil {
+				t.Errorf("Model.Validate() error = %v", err)
+			}
+			if len(dec.Instances()) != 2 {
+				t.Errorf("Optimize() instances = %d, want 2", len(dec.Instances()))
+			}
+		})
+	}
+}
+
+func TestModel_pruneUnreachableObjects(t *testing.T) {
+	m := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: new(Mesh)},
+			{ID: 2, Mesh: new(Mesh)},
+			{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 3}, {ObjectID: 1}}}},
+		}},
+		Build: Build{Items: []*Item{{ObjectID: 3}}},
+		Childs: map[string]*ChildModel{
+			"/a.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: new(Mesh)}}}},
+			"/b.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: &Mesh{Any: spec.Any{&specAsset{}}}}}}},
+		},
+	}
+	if got := m.pruneUnreachableObjects(); got != 2 {
+		t.Errorf("Model.pruneUnreachableObjects() = %d, want 2", got)
+	}
+	if len(m.Resources.Objects) != 2 || m.Resources.Objects[0].ID != 1 || m.Resources.Objects[1].ID != 3 {
+		t.Errorf("Model.pruneUnreachableObjects() root = %v", m.Resources.Objects)
+	}
+	if len(m.Childs["/a.model"].Resources.Objects) != 0 || len(m.Childs["/b.model"].Resources.Objects) != 1 {
+		t.Errorf("Model.pruneUnreachableObjects() childs = %v", m.Childs)
+	}
+}
@@ -4,6 +4,8 @@
 package production
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -51,3 +53,76 @@ func TestMarshalModel(t *testing.T) {
 		t.Errorf("production.MarshalModel() = %v, s = %s", diff, string(b))
 	}
 }
+
+func TestModel_RemoveSpec(t *testing.T) {
+	m := &go3mf.Model{Path: "/3D/3dmodel.model", Build: go3mf.Build{
+		AnyAttr: spec.AnyAttr{&BuildAttr{UUID: "e9e25302-6428-402e-8633-cc95528d0ed3"}},
+	}}
+	m.Resources = go3mf.Resources{Objects: []*go3mf.Object{{
+		AnyAttr: spec.AnyAttr{&ObjectAttr{UUID: "cb828680-8895-4e08-a1fc-be63e033df15"}},
+		ID:      20,
+		Components: &go3mf.Components{Component: []*go3mf.Component{{
+			ObjectID: 8,
+			AnyAttr:  spec.AnyAttr{&ComponentAttr{UUID: "cb828680-8895-4e08-a1fc-be63e033df16"}},
+		}}},
+	}, {ID: 8, Mesh: new(go3mf.Mesh)}}}
+	m.Build.Items = append(m.Build.Items, &go3mf.Item{ObjectID: 20,
+		AnyAttr: spec.AnyAttr{&ItemAttr{UUID: "e9e25302-6428-402e-8633-cc95528d0ed2"}},
+	})
+	m.Extensions = []go3mf.Extension{DefaultExtension}
+	if err := m.RemoveSpec(Namespace); err != nil {
+		t.Fatalf("Model.RemoveSpec() error = %v", err)
+	}
+	b, err := go3mf.MarshalModel(m)
+	if err != nil {
+		t.Fatalf("go3mf.MarshalModel() error = %v", err)
+	}
+	if strings.Contains(string(b), Namespace) {
+		t.Errorf("Model.RemoveSpec() encoded = %s", b)
+	}
+	newModel := new(go3mf.Model)
+	newModel.Path = m.Path
+	if err := go3mf.UnmarshalModel(b, newModel); err != nil {
+		t.Fatalf("go3mf.UnmarshalModel() error = %v", err)
+	}
+	want := &go3mf.Model{Path: "/3D/3dmodel.model"}
+	want.Resources = go3mf.Resources{Objects: []*go3mf.Object{{
+		ID: 20, Components: &go3mf.Components{Component: []*go3mf.Component{{ObjectID: 8}}},
+	}, {ID: 8, Mesh: new(go3mf.Mesh)}}}
+	want.Build.Items = []*go3mf.Item{{ObjectID: 20}}
+	if diff := deep.Equal(newModel, want); diff != nil {
+		t.Errorf("Model.RemoveSpec() = %v, s = %s", diff, b)
+	}
+	other := &go3mf.Model{Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 1,
+		AnyAttr: spec.AnyAttr{&ItemAttr{Path: "/3D/other.model"}},
+	}}}}
+	if err := other.RemoveSpec(Namespace); !errors.Is(err, go3mf.ErrSpecInUse) {
+		t.Errorf("Model.RemoveSpec() error = %v, want %v", err, go3mf.ErrSpecInUse)
+	}
+}
+
+func TestMarshalModel_RequiredExtensions(t *testing.T) {
+	data := []byte(`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" xmlns:m="http://schemas.microsoft.com/3dmanufacturing/material/2015/02"
+		xmlns:p="http://schemas.microsoft.com/3dmanufacturing/production/2015/06" requiredextensions="p" unit="millimeter">
+		<resources />
+		<build p:UUID="e9e25302-6428-402e-8633-cc95528d0ed3" />
+	</model>`)
+	m := new(go3mf.Model)
+	if err := go3mf.UnmarshalModel(data, m); err != nil {
+		t.Fatalf("production.UnmarshalModel() error = %v", err)
+	}
+	b, err := go3mf.MarshalModel(m)
+	if err != nil {
+		t.Fatalf("production.MarshalModel() error = %v", err)
+	}
+	if !strings.Contains(string(b), `requiredextensions="p"`) {
+		t.Errorf("production.MarshalModel() missing requiredextensions, s = %s", string(b))
+	}
+	newModel := new(go3mf.Model)
+	if err := go3mf.UnmarshalModel(b, newModel); err != nil {
+		t.Fatalf("production.UnmarshalModel() error decoding = %v, s = %s", err, string(b))
+	}
+	if diff := deep.Equal(newModel.Extensions, m.Extensions); diff != nil {
+		t.Errorf("production.MarshalModel() = %v, s = %s", diff, string(b))
+	}
+}
@@ -5,6 +5,7 @@ package production
 
 import (
 	"errors"
+	"math/rand"
 
 	"github.com/hpinc/go3mf"
 	"github.com/hpinc/go3mf/spec"
@@ -127,38 +128,58 @@ func (p *ComponentAttr) getUUID() string {
 // SetMissingUUIDs traverse all the model tree setting
 // all missing UUID attributes.
 func SetMissingUUIDs(m *go3mf.Model) {
+	SetMissingUUIDsFunc(m, uuid.New)
+}
+
+// SetMissingUUIDsFunc is like SetMissingUUIDs but the new UUIDs
+// are generated by newUUID, such as the function returned by DeterministicUUIDs.
+// Existing UUIDs are never overwritten.
+func SetMissingUUIDsFunc(m *go3mf.Model, newUUID func() string) {
 	if GetBuildAttr(&m.Build) == nil {
-		m.Build.AnyAttr = append(m.Build.AnyAttr, &BuildAttr{UUID: uuid.New()})
+		m.Build.AnyAttr = append(m.Build.AnyAttr, &BuildAttr{UUID: newUUID()})
 	}
 	for _, item := range m.Build.Items {
 		ext := GetItemAttr(item)
 		if ext == nil {
 			item.AnyAttr = append(item.AnyAttr, &ItemAttr{
-				UUID: uuid.New(),
+				UUID: newUUID(),
 			})
 		} else if ext.UUID == "" {
-			ext.UUID = uuid.New()
+			ext.UUID = newUUID()
 		}
 	}
 	m.WalkObjects(func(s string, obj *go3mf.Object) error {
 		oext := GetObjectAttr(obj)
 		if oext == nil {
-			obj.AnyAttr = append(obj.AnyAttr, &ObjectAttr{UUID: uuid.New()})
+			obj.AnyAttr = append(obj.AnyAttr, &ObjectAttr{UUID: newUUID()})
 		} else if oext.UUID == "" {
-			oext.UUID = uuid.New()
+			oext.UUID = newUUID()
 		}
 		if obj.Components != nil {
 			for _, c := range obj.Components.Component {
 				ext := GetComponentAttr(c)
 				if ext == nil {
 					c.AnyAttr = append(c.AnyAttr, &ComponentAttr{
-						UUID: uuid.New(),
+						UUID: newUUID(),
 					})
 				} else if ext.UUID == "" {
-					ext.UUID = uuid.New()
+					ext.UUID = newUUID()
 				}
 			}
 		}
 		return nil
 	})
 }
+
+// DeterministicUUIDs returns a function that generates a sequence of
+// valid version 4 UUIDs which only depends on seed, so encoding
+// the same model twice produces the same output.
+// The UUIDs are not random enough to be globally unique, so it is only
+// meant for tests and reproducible builds.
+// The returned function is not safe for concurrent use.
+func DeterministicUUIDs(seed int64) func() string {
+	r := rand.New(rand.NewSource(seed))
+	return func() string {
+		return uuid.NewFromReader(r)
+	}
+}
@@ -6,8 +6,10 @@ package production
 import (
 	"testing"
 
+	"github.com/go-test/deep"
 	"github.com/hpinc/go3mf"
 	"github.com/hpinc/go3mf/spec"
+	"github.com/hpinc/go3mf/uuid"
 )
 
 var _ spec.Marshaler = new(BuildAttr)
@@ -75,3 +77,43 @@ func TestSetMissingUUIDs(t *testing.T) {
 		t.Errorf("SetMissingUUIDs() should have filled object attrs")
 	}
 }
+
+func TestSetMissingUUIDsFunc(t *testing.T) {
+	newModel := func() *go3mf.Model {
+		return &go3mf.Model{
+			Resources: go3mf.Resources{Objects: []*go3mf.Object{
+				{ID: 1, AnyAttr: spec.AnyAttr{&ObjectAttr{UUID: "cb828680-8895-4e08-a1fc-be63e033df15"}}},
+				{ID: 2, Components: &go3mf.Components{Component: []*go3mf.Component{{ObjectID: 1}}}},
+			}},
+			Build: go3mf.Build{Items: []*go3mf.Item{{ObjectID: 2}, {ObjectID: 1, AnyAttr: spec.AnyAttr{&ItemAttr{Path: "/3D/other.model"}}}}},
+		}
+	}
+	m1, m2 := newModel(), newModel()
+	SetMissingUUIDsFunc(m1, DeterministicUUIDs(1))
+	SetMissingUUIDsFunc(m2, DeterministicUUIDs(1))
+	if diff := deep.Equal(m1, m2); diff != nil {
+		t.Errorf("SetMissingUUIDsFunc() not deterministic = %v", diff)
+	}
+	if got := GetObjectAttr(m1.Resources.Objects[0]).UUID; got != "cb828680-8895-4e08-a1fc-be63e033df15" {
+		t.Errorf("SetMissingUUIDsFunc() overwrote UUID = %s", got)
+	}
+	uuids := []string{
+		GetBuildAttr(&m1.Build).UUID,
+		GetItemAttr(m1.Build.Items[0]).UUID,
+		GetItemAttr(m1.Build.Items[1]).UUID,
+		GetObjectAttr(m1.Resources.Objects[1]).UUID,
+		GetComponentAttr(m1.Resources.Objects[1].Components.Component[0]).UUID,
+	}
+	seen := make(map[string]bool)
+	for _, u := range uuids {
+		if err := uuid.Validate(u); err != nil || seen[u] {
+			t.Errorf("SetMissingUUIDsFunc() invalid or duplicated UUID %q", u)
+		}
+		seen[u] = true
+	}
+	m3 := newModel()
+	SetMissingUUIDsFunc(m3, DeterministicUUIDs(2))
+	if GetBuildAttr(&m3.Build).UUID == uuids[0] {
+		t.Errorf("DeterministicUUIDs() same UUID for different seeds")
+	}
+}
@@ -60,6 +60,16 @@ func TestValidate(t *testing.T) {
 			fmt.Sprintf("go3mf: Path: /other.model XPath: /model/resources/object[0]: %v", &errors.MissingFieldError{Name: attrProdUUID}),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[0]: %v", &errors.MissingFieldError{Name: attrProdUUID}),
 		}},
+		{"itemMissingChild", &go3mf.Model{Build: go3mf.Build{
+			AnyAttr: spec.AnyAttr{&BuildAttr{UUID: "f47ac10b-58cc-0372-8567-0e02b2c3d479"}}, Items: []*go3mf.Item{
+				{ObjectID: 1, AnyAttr: spec.AnyAttr{&ItemAttr{UUID: "f47ac10b-58cc-0372-8567-0e02b2c3d478", Path: "/missing.model"}}},
+				{ObjectID: 2, AnyAttr: spec.AnyAttr{&ItemAttr{UUID: "f47ac10b-58cc-0372-8567-0e02b2c3d477", Path: "/other.model"}}},
+			}},
+			Childs: map[string]*go3mf.ChildModel{"/other.model": {Resources: go3mf.Resources{Objects: []*go3mf.Object{validMesh}}}}}, []string{
+			fmt.Sprintf("go3mf: Path: /other.model XPath: /model/resources/object[0]: %v", &errors.MissingFieldError{Name: attrProdUUID}),
+			fmt.Sprintf("go3mf: XPath: /model/build/item[0]: %v", errors.ErrMissingResource),
+			fmt.Sprintf("go3mf: XPath: /model/build/item[1]: %v", errors.ErrMissingResource),
+		}},
 		{"components", &go3mf.Model{Resources: go3mf.Resources{
 			Objects: []*go3mf.Object{
 				{ID: 2, Mesh: validMesh.Mesh, AnyAttr: spec.AnyAttr{&ObjectAttr{UUID: "a-b-c-d"}}},
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -20,7 +21,56 @@ import (
 	"github.com/hpinc/go3mf/spec"
 )
 
-var checkEveryTokens = 1000
+// checkEveryBytes is the number of decompressed bytes
+// read from a model part between Decoder.OnProgress calls.
+var checkEveryBytes int64 = 4 << 20
+
+// ErrNotAPackage is returned when decoding an input that is not
+// a valid OPC package, such as a non-zip file.
+// The returned error also wraps the underlying cause.
+var ErrNotAPackage = errors.New("go3mf: input is not a valid 3MF package")
+
+// ErrUnitMismatch is reported in Decoder.Warnings when a non-root model part
+// declares a unit different from the root model unit.
+var ErrUnitMismatch = errors.New("go3mf: model part unit differs from the root model unit")
+
+// DefaultCancelCheckInterval is the Decoder.CancelCheckInterval set by NewDecoder.
+const DefaultCancelCheckInterval int64 = 4 << 20
+
+// DefaultMaxComponentDepth is the Decoder.MaxComponentDepth set by NewDecoder.
+const DefaultMaxComponentDepth = 256
+
+// ErrMaxComponentDepth is returned when the components of a decoded object
+// are nested deeper than Decoder.MaxComponentDepth.
+var ErrMaxComponentDepth = errors.New("go3mf: components exceed the maximum nesting depth")
+
+// ErrPathCaseCollision is reported in Decoder.Warnings when two
+// package parts have names that only differ in case.
+var ErrPathCaseCollision = errors.New("go3mf: part names only differ in case")
+
+// ErrAttachmentTooLarge is returned when an attachment
+// is bigger than Decoder.MaxAttachmentBytes.
+var ErrAttachmentTooLarge = errors.New("go3mf: attachment exceeds the maximum size")
+
+// ErrTooManyErrors is returned when the decoding is aborted
+// because it reached Decoder.MaxErrors.
+var ErrTooManyErrors = errors.New("go3mf: too many errors")
+
+type notAPackageError struct {
+	err error
+}
+
+func (e *notAPackageError) Error() string {
+	return ErrNotAPackage.Error() + ": " + e.err.Error()
+}
+
+func (e *notAPackageError) Unwrap() error {
+	return e.err
+}
+
+func (e *notAPackageError) Is(target error) bool {
+	return target == ErrNotAPackage
+}
 
 type packageFile interface {
 	Name() string
@@ -61,8 +111,12 @@ func (r *ReadCloser) Close() error {
 	return r.f.Close()
 }
 
-func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string, isRoot, strict bool) error {
-	x := xml3mf.NewDecoder(r)
+// decodeModelFile decodes a model part into model.
+// The unit declared by the part is stored in units
+// and the element warnings are appended to warnings.
+func (d *Decoder) decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string, isRoot bool, units *Units, warnings *[]error) error {
+	cr := &countReader{r: r}
+	x := xml3mf.NewDecoder(cr)
 	type stackElement struct {
 		decoder spec.ElementDecoder
 		name    xml.Name
@@ -75,9 +129,32 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 		currentName    xml.Name
 		errs           specerr.List
 	)
-	currentDecoder = &topLevelDecoder{isRoot: isRoot, model: model, path: path}
+	wrapStack := func(err error) error {
+		for j := len(stack) - 1; j >= 0; j-- {
+			element := stack[j]
+			err = specerr.WrapIndex(err, element.name.Local, element.i)
+		}
+		return err
+	}
+	vertexSink := d.VertexSink
+	if d.stream != nil {
+		vertexSink = &streamVertexSink{onVertex: d.stream.OnVertex, counts: make(map[*Mesh]int)}
+	} else if vertexSink == nil {
+		vertexSink = sliceVertexSink{}
+	}
+	currentDecoder = &topLevelDecoder{
+		isRoot: isRoot, model: model, path: path, units: units,
+		vertexSink: vertexSink, maxBuildItems: d.MaxBuildItems, skipGeometry: d.SkipGeometry, stream: d.stream,
+	}
 	var err error
+	checkCtx := true
 	x.OnStart = func(tp xml3mf.StartElement) {
+		if tp.Name.Space == NamespaceDraft {
+			tp.Name.Space = Namespace
+			if isRoot {
+				model.CoreNamespace = NamespaceDraft
+			}
+		}
 		if childDecoder, ok := currentDecoder.(spec.ChildElementDecoder); ok {
 			i, tmpDecoder := childDecoder.Child(tp.Name)
 			if tmpDecoder != nil {
@@ -86,11 +163,12 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 				currentDecoder = tmpDecoder
 				err := currentDecoder.Start(*(*[]spec.XMLAttr)(unsafe.Pointer(&tp.Attr)))
 				if err != nil {
-					for j := len(stack) - 1; j >= 0; j-- {
-						element := stack[j]
-						err = specerr.WrapIndex(err, element.name.Local, element.i)
+					err = d.splitWarnings(err, func(w error) {
+						*warnings = append(*warnings, wrapStack(w))
+					})
+					if err != nil {
+						specerr.Append(&errs, wrapStack(err))
 					}
-					specerr.Append(&errs, err)
 				}
 			}
 		} else if appendDecoder, ok := currentDecoder.(spec.AppendTokenElementDecoder); ok {
@@ -108,6 +186,12 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 		}
 	}
 	x.OnEnd = func(tp xml.EndElement) {
+		if d.CancelCheckInterval <= 0 {
+			checkCtx = true
+		}
+		if tp.Name.Space == NamespaceDraft {
+			tp.Name.Space = Namespace
+		}
 		if currentName == tp.Name {
 			currentDecoder.End()
 			stack = stack[:len(stack)-1]
@@ -127,13 +211,22 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 			appendDecoder.AppendToken(tp)
 		}
 	}
-	var i int
+	var nextBytesCheck int64
 	for {
 		err = x.RawToken()
-		if err != nil || (strict && errs.Len() != 0) {
+		if err != nil || (d.Strict && errs.Len() != 0) {
 			break
 		}
-		if i%checkEveryTokens == 0 {
+		if d.MaxErrors > 0 && errs.Len() >= d.MaxErrors {
+			specerr.Append(&errs, ErrTooManyErrors)
+			break
+		}
+		if d.CancelCheckInterval > 0 && cr.n >= nextBytesCheck {
+			checkCtx = true
+			nextBytesCheck = cr.n + d.CancelCheckInterval
+		}
+		if checkCtx {
+			checkCtx = false
 			select {
 			case <-ctx.Done():
 				err = ctx.Err()
@@ -143,13 +236,12 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 				break
 			}
 		}
-		i++
 	}
 	if err == io.EOF {
 		err = nil
 	}
 	if err == nil && errs.Len() != 0 {
-		if strict || errs.Len() == 1 {
+		if d.Strict || errs.Len() == 1 {
 			err = errs.Unwrap()
 		} else {
 			err = &errs
@@ -158,22 +250,162 @@ func decodeModelFile(ctx context.Context, r io.Reader, model *Model, path string
 	return err
 }
 
+// A VertexSink stores the vertices of the decoded meshes,
+// allowing them to be backed by a custom storage such as a memory-mapped file.
+//
+// Vertices stored in a custom sink are not visible through Mesh.Vertices,
+// so validation and the Mesh helpers will only see an empty vertex list.
+// Non-root model parts are decoded concurrently, so implementations
+// must be safe for concurrent use.
+type VertexSink interface {
+	// AddVertex stores v as the next vertex of mesh.
+	AddVertex(mesh *Mesh, v Point3D) error
+	// Len returns the number of vertices stored for mesh.
+	Len(mesh *Mesh) int
+}
+
+// sliceVertexSink is the default VertexSink,
+// which appends the vertices to Mesh.Vertices.Vertex.
+type sliceVertexSink struct{}
+
+func (sliceVertexSink) AddVertex(mesh *Mesh, v Point3D) error {
+	mesh.Vertices.Vertex = append(mesh.Vertices.Vertex, v)
+	return nil
+}
+
+func (sliceVertexSink) Len(mesh *Mesh) int {
+	return len(mesh.Vertices.Vertex)
+}
+
 // Decoder implements a 3mf file decoder.
+//
+// The references between build items, objects and assets are not resolved
+// while decoding, so the resources and build elements are accepted in any order
+// although the specification requires the resources to come first.
+// Model.Validate resolves them once the whole model is decoded.
 type Decoder struct {
-	Strict        bool
-	p             packageReader
-	flate         func(r io.Reader) io.ReadCloser
-	nonRootModels []packageFile
+	Strict bool
+	// MaxErrors is the maximum number of errors accumulated while decoding a model part.
+	// Once reached the decoding is aborted and ErrTooManyErrors is appended to the errors.
+	// Zero means unlimited.
+	MaxErrors int
+	// MaxComponentDepth is the maximum nesting depth of the components of an object.
+	// Once decoded, the component graph is walked without recursion and
+	// ErrMaxComponentDepth is returned if an object exceeds it, or errors.ErrRecursion
+	// if the components contain a cycle. Zero disables the check.
+	MaxComponentDepth int
+	// MaxBuildItems is the maximum number of build items decoded.
+	// The items after it are skipped, resources and metadata are still decoded.
+	// Zero means unlimited.
+	MaxBuildItems int
+	// VertexSink receives the decoded mesh vertices instead of Mesh.Vertices.
+	// If nil, the vertices are appended to Mesh.Vertices.Vertex.
+	VertexSink VertexSink
+	// SkipGeometry leaves the mesh vertices and triangles undecoded,
+	// which is useful to index a package by its metadata, objects and build.
+	// Mesh objects keep their attributes and an empty Mesh,
+	// so such a model will not pass Model.Validate.
+	SkipGeometry bool
+	// ConvertChildUnits converts the non-root model parts that declare a unit
+	// different from the root model unit to the root unit, scaling their mesh vertices
+	// and component translations. Geometry defined by extensions is not converted.
+	// If false, the mismatch is reported in Warnings as ErrUnitMismatch.
+	ConvertChildUnits bool
+	// Shallow decodes only the root model part. The non-root model parts are
+	// added to Model.Childs with empty resources, only their relationships and
+	// attachments are read. References to their objects and assets
+	// cannot be resolved, so such a model will not pass Model.Validate.
+	Shallow bool
+	// CaseSensitivePaths makes the package part names case-sensitive
+	// when resolving relationships and deduplicating attachments.
+	// If false, parts whose names only differ in case are considered the same part,
+	// the first one is kept and the collision is reported in Warnings as ErrPathCaseCollision.
+	CaseSensitivePaths bool
+	// ContinueOnChildError makes a failing non-root model part not abort the decoding.
+	// The part error is recorded in Warnings and its resources are left empty.
+	ContinueOnChildError bool
+	// CancelCheckInterval is the number of decompressed bytes read from
+	// a model part between checks of the context passed to DecodeContext.
+	// Zero checks the context after every end element.
+	CancelCheckInterval int64
+	// RootModelPath is the name of the root model part used when the package
+	// does not have a root model relationship, as written by some exporters.
+	// The part must have the ContentType3DModel content type.
+	// If empty, a package without the relationship is rejected.
+	RootModelPath string
+	// MaxAttachmentBytes is the maximum size of an attachment once decompressed.
+	// Bigger attachments are not added to the model and ErrAttachmentTooLarge
+	// is returned if Strict, else it is reported in Warnings.
+	// Zero means unlimited.
+	MaxAttachmentBytes int64
+	// OnProgress, if not nil, is called every time 4MB of a model part
+	// are read and once the part is fully read, with the decompressed bytes
+	// read so far and the part decompressed size, or -1 if unknown.
+	// It is called from the goroutine decoding the part, so it must not block,
+	// and it can be called concurrently for different non-root model parts.
+	OnProgress func(bytesRead, totalBytes int64)
+	// Warnings contains the non-fatal errors found during the last decoding.
+	// Each warning is a *errors.Error whose Path is the model part it comes from.
+	Warnings        []error
+	stream          *StreamHandlers
+	p               packageReader
+	flate           func(r io.Reader) io.ReadCloser
+	nonRootModels   []packageFile
+	childUnits      []Units
+	childWarnings   [][]error
+	contentHandlers map[string]ContentHandler
+	attachmentErr   error
+}
+
+// A ContentHandler is called by the Decoder with the content of an attachment
+// of the content type it is registered for. path is the attachment part name.
+type ContentHandler func(path string, r io.Reader, model *Model) error
+
+// RegisterContentHandler makes the decoder call fn for each attachment
+// whose content type is contentType, once the package relationships are read
+// and before the model parts are decoded.
+// The attachment is still added to Model.Attachments.
+// If fn returns an error, the decoding is aborted with that error.
+// Registering a nil fn removes the handler of contentType.
+func (d *Decoder) RegisterContentHandler(contentType string, fn ContentHandler) {
+	if fn == nil {
+		delete(d.contentHandlers, contentType)
+		return
+	}
+	if d.contentHandlers == nil {
+		d.contentHandlers = make(map[string]ContentHandler)
+	}
+	d.contentHandlers[contentType] = fn
 }
 
 // NewDecoder returns a new Decoder reading a 3mf file from r.
 func NewDecoder(r io.ReaderAt, size int64) *Decoder {
 	return &Decoder{
-		p:      &opcReader{ra: r, size: size},
-		Strict: true,
+		p:                   &opcReader{ra: r, size: size},
+		Strict:              true,
+		MaxComponentDepth:   DefaultMaxComponentDepth,
+		CancelCheckInterval: DefaultCancelCheckInterval,
 	}
 }
 
+// WarningsByPart returns the Warnings grouped by the path
+// of the model part they come from.
+func (d *Decoder) WarningsByPart() map[string][]error {
+	if len(d.Warnings) == 0 {
+		return nil
+	}
+	parts := make(map[string][]error)
+	for _, w := range d.Warnings {
+		var path string
+		var e *specerr.Error
+		if errors.As(w, &e) {
+			path = e.Path
+		}
+		parts[path] = append(parts[path], w)
+	}
+	return parts
+}
+
 // Decode reads the 3mf file and unmarshall its content into the model.
 func (d *Decoder) Decode(model *Model) error {
 	return d.DecodeContext(context.Background(), model)
@@ -181,14 +413,98 @@ func (d *Decoder) Decode(model *Model) error {
 
 // DecodeContext reads the 3mf file and unmarshall its content into the model.
 func (d *Decoder) DecodeContext(ctx context.Context, model *Model) error {
+	d.Warnings = nil
+	d.attachmentErr = nil
 	rootFile, err := d.processOPC(model)
 	if err != nil {
 		return err
 	}
-	if err := d.processNonRootModels(ctx, model); err != nil {
+	if !d.Shallow {
+		if err := d.processNonRootModels(ctx, model); err != nil {
+			return err
+		}
+	}
+	if err := d.processRootModel(ctx, rootFile, model); err != nil {
 		return err
 	}
-	return d.processRootModel(ctx, rootFile, model)
+	if d.MaxComponentDepth > 0 {
+		if err := model.checkComponentDepth(d.MaxComponentDepth); err != nil {
+			return err
+		}
+	}
+	if !d.Shallow {
+		d.reconcileChildUnits(model)
+	}
+	return nil
+}
+
+// reconcileChildUnits converts or reports the non-root model parts
+// whose declared unit differs from the root model unit.
+func (d *Decoder) reconcileChildUnits(model *Model) {
+	for i, file := range d.nonRootModels {
+		units := d.childUnits[i]
+		if units == model.Units {
+			continue
+		}
+		path := file.Name()
+		child, ok := model.Childs[path]
+		if !ok {
+			continue
+		}
+		if d.ConvertChildUnits {
+			child.Resources.scale(units.ConversionFactor(model.Units))
+		} else {
+			err := fmt.Errorf("%w: %s, root model uses %s", ErrUnitMismatch, units, model.Units)
+			d.Warnings = append(d.Warnings, withModelPath(err, path))
+		}
+	}
+}
+
+// StreamHandlers contains the callbacks invoked by Decoder.DecodeStream.
+// Any of them can be nil.
+type StreamHandlers struct {
+	// OnObject is called once an object has been decoded, after the
+	// vertices and triangles of its mesh. Its mesh, if any, is empty.
+	OnObject func(*Object)
+	// OnVertex is called for each mesh vertex, in document order.
+	OnVertex func(Point3D)
+	// OnTriangle is called for each mesh triangle, in document order,
+	// after applying the object default properties.
+	OnTriangle func(Triangle)
+	// OnMetadata is called for each metadata of the root model element.
+	OnMetadata func(Metadata)
+}
+
+// DecodeStream reads the 3mf file invoking the handlers as the elements
+// are decoded, without storing the mesh vertices and triangles in memory.
+// The decoder options are honored, the context is checked periodically
+// and the non-fatal errors are collected in Warnings as in DecodeContext.
+//
+// Non-root model parts are decoded concurrently before the root one,
+// so the handlers must be safe for concurrent use.
+func (d *Decoder) DecodeStream(ctx context.Context, handlers StreamHandlers) error {
+	d.stream = &handlers
+	defer func() { d.stream = nil }()
+	return d.DecodeContext(ctx, new(Model))
+}
+
+// streamVertexSink forwards the vertices to a StreamHandlers.OnVertex.
+// Each model part uses its own sink, so it is not safe for concurrent use.
+type streamVertexSink struct {
+	onVertex func(Point3D)
+	counts   map[*Mesh]int
+}
+
+func (s *streamVertexSink) AddVertex(mesh *Mesh, v Point3D) error {
+	if s.onVertex != nil {
+		s.onVertex(v)
+	}
+	s.counts[mesh]++
+	return nil
+}
+
+func (s *streamVertexSink) Len(mesh *Mesh) int {
+	return s.counts[mesh]
 }
 
 // UnmarshalModel fills a model with the data of a root model file
@@ -199,47 +515,124 @@ func UnmarshalModel(data []byte, model *Model) error {
 	return d.processRootModel(context.Background(), &fakePackageFile{data: data}, model)
 }
 
+// UnmarshalModelReader fills a model with the data of a root model file
+// read from r, which can be any stream such as a gzip.Reader.
+// The decoder options are honored, but as there is no package
+// the model cannot reference other parts nor attachments.
+func (d *Decoder) UnmarshalModelReader(r io.Reader, model *Model) error {
+	d.Warnings = nil
+	return d.processRootModel(context.Background(), &fakePackageFile{r: r}, model)
+}
+
 func (d *Decoder) processRootModel(ctx context.Context, rootFile packageFile, model *Model) error {
 	f, err := rootFile.Open()
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	err = decodeModelFile(ctx, f, model, rootFile.Name(), true, d.Strict)
+	var warnings []error
+	err = d.decodeModelFile(ctx, d.progressReader(f, rootFile), model, rootFile.Name(), true, &model.Units, &warnings)
+	for _, w := range warnings {
+		d.Warnings = append(d.Warnings, withModelPath(w, rootFile.Name()))
+	}
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *Decoder) processNonRootModels(ctx context.Context, model *Model) (errs error) {
+// splitWarnings calls warn with each warning contained in err
+// and returns the remaining errors, if any.
+// The warnings that fail in strict mode are returned as errors if d.Strict.
+func (d *Decoder) splitWarnings(err error, warn func(error)) error {
+	errs := []error{err}
+	if l, ok := err.(*specerr.List); ok {
+		errs = l.Errors
+	}
+	var remaining error
+	for _, e := range errs {
+		if w, ok := e.(warning); ok {
+			if !w.failStrict || !d.Strict {
+				warn(w.error)
+				continue
+			}
+			e = w.error
+		}
+		remaining = specerr.Append(remaining, e)
+	}
+	return remaining
+}
+
+// processNonRootModels decodes the non-root model parts concurrently.
+// When a part fails only the parts after it are cancelled, so the returned error
+// is always the one of the first failing part regardless of the goroutines scheduling.
+func (d *Decoder) processNonRootModels(ctx context.Context, model *Model) error {
 	var (
 		wg                 sync.WaitGroup
 		nonRootModelsCount = len(d.nonRootModels)
 	)
 	wg.Add(nonRootModelsCount)
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	ctxs := make([]context.Context, nonRootModelsCount)
+	cancels := make([]context.CancelFunc, nonRootModelsCount)
+	for i := range ctxs {
+		ctxs[i], cancels[i] = context.WithCancel(ctx)
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	errs := make([]error, nonRootModelsCount)
+	warnings := make([]error, nonRootModelsCount)
+	d.childUnits = make([]Units, nonRootModelsCount)
+	d.childWarnings = make([][]error, nonRootModelsCount)
 	for i := 0; i < nonRootModelsCount; i++ {
 		go func(i int) {
 			defer wg.Done()
-			err := d.readChildModel(ctx, i, model)
-			if err != nil {
-				errs = err
+			err := d.readChildModel(ctxs[i], i, model)
+			if err == nil {
+				return
+			}
+			path := d.nonRootModels[i].Name()
+			if d.ContinueOnChildError && ctx.Err() == nil {
+				if child, ok := model.Childs[path]; ok {
+					child.Resources = Resources{}
+				}
+				warnings[i] = withModelPath(err, path)
+				return
+			}
+			errs[i] = withModelPath(err, path)
+			for _, cancel := range cancels[i+1:] {
 				cancel()
 			}
 		}(i)
 	}
 	wg.Wait()
-	if errs != nil {
-		return errs
+	for i, w := range warnings {
+		path := d.nonRootModels[i].Name()
+		for _, cw := range d.childWarnings[i] {
+			d.Warnings = append(d.Warnings, withModelPath(cw, path))
+		}
+		if l, ok := w.(*specerr.List); ok {
+			d.Warnings = append(d.Warnings, l.Errors...)
+		} else if w != nil {
+			d.Warnings = append(d.Warnings, w)
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func (d *Decoder) processOPC(model *Model) (packageFile, error) {
+	if r, ok := d.p.(*opcReader); ok {
+		r.caseSensitive = d.CaseSensitivePaths
+	}
 	if err := d.p.Open(d.flate); err != nil {
-		return nil, err
+		return nil, &notAPackageError{err}
 	}
 	var rootFile packageFile
 	for _, r := range d.p.Relationships() {
@@ -249,22 +642,35 @@ func (d *Decoder) processOPC(model *Model) (packageFile, error) {
 			if !ok {
 				return nil, errors.New("package root model points to an unexisting file")
 			}
-			model.Path = rootFile.Name()
-			d.extractCoreAttachments(rootFile, model, true)
-			for _, file := range d.nonRootModels {
-				d.extractCoreAttachments(file, model, false)
-			}
+			d.extractRootAttachments(rootFile, model)
 		} else if att, ok := d.p.FindFileFromName(r.Path); ok {
 			model.RootRelationships = append(model.RootRelationships, r)
-			model.Attachments = d.addAttachment(model.Attachments, att)
+			d.addAttachment(model, att)
+		}
+	}
+	if rootFile == nil && d.RootModelPath != "" {
+		if file, ok := d.p.FindFileFromName(d.RootModelPath); ok && file.ContentType() == ContentType3DModel {
+			rootFile = file
+			d.extractRootAttachments(rootFile, model)
 		}
 	}
 	if rootFile == nil {
 		return nil, errors.New("package does not have root model")
 	}
+	if d.attachmentErr != nil {
+		return nil, d.attachmentErr
+	}
 	return rootFile, nil
 }
 
+func (d *Decoder) extractRootAttachments(rootFile packageFile, model *Model) {
+	model.Path = rootFile.Name()
+	d.extractCoreAttachments(rootFile, model, true)
+	for _, file := range d.nonRootModels {
+		d.extractCoreAttachments(file, model, false)
+	}
+}
+
 func (d *Decoder) extractCoreAttachments(modelFile packageFile, model *Model, isRoot bool) {
 	for _, rel := range modelFile.Relationships() {
 		if file, ok := modelFile.FindFileFromName(rel.Path); ok {
@@ -276,12 +682,12 @@ func (d *Decoder) extractCoreAttachments(modelFile packageFile, model *Model, is
 					}
 					model.Childs[file.Name()] = new(ChildModel)
 				} else {
-					model.Attachments = d.addAttachment(model.Attachments, file)
+					d.addAttachment(model, file)
 					model.Relationships = append(model.Relationships, rel)
 				}
 			} else if rel.Type != RelType3DModel {
 				if child, ok := model.Childs[modelFile.Name()]; ok {
-					model.Attachments = d.addAttachment(model.Attachments, file)
+					d.addAttachment(model, file)
 					child.Relationships = append(child.Relationships, rel)
 				}
 			}
@@ -289,20 +695,47 @@ func (d *Decoder) extractCoreAttachments(modelFile packageFile, model *Model, is
 	}
 }
 
-func (d *Decoder) addAttachment(attachments []Attachment, file packageFile) []Attachment {
-	for _, att := range attachments {
+// addAttachment adds file to the model attachments, unless it is already there,
+// and calls the content handler registered for its content type.
+func (d *Decoder) addAttachment(model *Model, file packageFile) {
+	for _, att := range model.Attachments {
+		if att.Path == file.Name() {
+			return
+		}
 		if strings.EqualFold(att.Path, file.Name()) {
-			return attachments
+			err := fmt.Errorf("%w: %s and %s", ErrPathCaseCollision, att.Path, file.Name())
+			d.Warnings = append(d.Warnings, withModelPath(err, file.Name()))
+			if !d.CaseSensitivePaths {
+				return
+			}
 		}
 	}
-	if buff, err := copyFile(file); err == nil {
-		return append(attachments, Attachment{
-			Path:        file.Name(),
-			Stream:      buff,
-			ContentType: file.ContentType(),
-		})
+	buff, err := copyFile(file, d.MaxAttachmentBytes)
+	if err == ErrAttachmentTooLarge {
+		err = withModelPath(err, file.Name())
+		if d.Strict {
+			if d.attachmentErr == nil {
+				d.attachmentErr = err
+			}
+		} else {
+			d.Warnings = append(d.Warnings, err)
+		}
+	}
+	if err != nil {
+		return
+	}
+	if fn, ok := d.contentHandlers[file.ContentType()]; ok && d.attachmentErr == nil {
+		d.attachmentErr = fn(file.Name(), bytes.NewReader(buff.Bytes()), model)
+	}
+	att := Attachment{
+		Path:        file.Name(),
+		Stream:      buff,
+		ContentType: file.ContentType(),
 	}
-	return attachments
+	if rels := file.Relationships(); len(rels) > 0 {
+		att.Relationships = rels
+	}
+	model.Attachments = append(model.Attachments, att)
 }
 
 func (d *Decoder) readChildModel(ctx context.Context, i int, model *Model) error {
@@ -312,7 +745,7 @@ func (d *Decoder) readChildModel(ctx context.Context, i int, model *Model) error
 		return err
 	}
 	defer file.Close()
-	err = decodeModelFile(ctx, file, model, attachment.Name(), false, d.Strict)
+	err = d.decodeModelFile(ctx, d.progressReader(file, attachment), model, attachment.Name(), false, &d.childUnits[i], &d.childWarnings[i])
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
@@ -321,19 +754,98 @@ func (d *Decoder) readChildModel(ctx context.Context, i int, model *Model) error
 	return err
 }
 
-func copyFile(file packageFile) (io.Reader, error) {
+// progressReader wraps r so Decoder.OnProgress is called while reading it.
+func (d *Decoder) progressReader(r io.Reader, file packageFile) io.Reader {
+	if d.OnProgress == nil {
+		return r
+	}
+	total := int64(-1)
+	switch f := file.(type) {
+	case *opcFile:
+		total = int64(f.f.Size)
+	case *tarFile:
+		total = int64(len(f.r.data[f.part.Name]))
+	case *fakePackageFile:
+		if f.r == nil {
+			total = int64(len(f.data))
+		}
+	}
+	return &progressReader{r: r, total: total, next: checkEveryBytes, onProgress: d.OnProgress}
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+type progressReader struct {
+	r          io.Reader
+	n, total   int64
+	next       int64
+	reported   bool
+	onProgress func(bytesRead, totalBytes int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		p.reported = false
+	}
+	if p.n >= p.next || (err == io.EOF && !p.reported) {
+		p.onProgress(p.n, p.total)
+		p.reported = true
+		for p.next <= p.n {
+			p.next += checkEveryBytes
+		}
+	}
+	return n, err
+}
+
+// withModelPath sets the model part path to the errors of err.
+func withModelPath(err error, path string) error {
+	switch e := err.(type) {
+	case *specerr.Error:
+		e.Path = path
+		return e
+	case *specerr.List:
+		for i, e1 := range e.Errors {
+			e.Errors[i] = withModelPath(e1, path)
+		}
+		return e
+	}
+	return specerr.WrapPath(err, attrModel, path)
+}
+
+// copyFile reads the whole content of file.
+// If limit is positive and the content is bigger, ErrAttachmentTooLarge is returned.
+func copyFile(file packageFile, limit int64) (*bytes.Buffer, error) {
 	stream, err := file.Open()
 	if err != nil {
 		return nil, err
 	}
+	defer stream.Close()
 	buff := new(bytes.Buffer)
-	_, err = io.Copy(buff, stream)
-	stream.Close()
+	if limit <= 0 {
+		_, err = io.Copy(buff, stream)
+		return buff, err
+	}
+	n, err := io.Copy(buff, io.LimitReader(stream, limit+1))
+	if err == nil && n > limit {
+		return nil, ErrAttachmentTooLarge
+	}
 	return buff, err
 }
 
 type fakePackageFile struct {
 	data []byte
+	r    io.Reader
 }
 
 func (f *fakePackageFile) Name() string                                { return DefaultModelPath }
@@ -341,5 +853,8 @@ func (f *fakePackageFile) ContentType() string                         { return
 func (f *fakePackageFile) FindFileFromName(string) (packageFile, bool) { return nil, false }
 func (f *fakePackageFile) Relationships() []Relationship               { return nil }
 func (f *fakePackageFile) Open() (io.ReadCloser, error) {
+	if f.r != nil {
+		return ioutil.NopCloser(f.r), nil
+	}
 	return ioutil.NopCloser(bytes.NewBuffer(f.data)), nil
 }
@@ -4,7 +4,9 @@
 package go3mf
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/xml"
 	"errors"
@@ -15,6 +17,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/go-test/deep"
@@ -283,6 +286,14 @@ func TestDecoder_processOPC(t *testing.T) {
 			Relationships: []Relationship{{Path: "/other.png", Type: extType}},
 			Attachments:   []Attachment{{Path: "/other.png", Stream: new(bytes.Buffer)}},
 		}, false},
+		{"withAttachmentRels", &Decoder{
+			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: extType, Path: "/other.png"}},
+				newMockFile("/other.png", []Relationship{{Type: "profile", Path: "/a.icc"}}, nil, false), false)),
+		}, &Model{
+			Path:          "/a.model",
+			Relationships: []Relationship{{Path: "/other.png", Type: extType}},
+			Attachments:   []Attachment{{Path: "/other.png", Stream: new(bytes.Buffer), Relationships: []Relationship{{Path: "/a.icc", Type: "profile"}}}},
+		}, false},
 		{"withOtherRel", &Decoder{
 			p: newMockPackage(newMockFile("/a.model", []Relationship{{Type: "other", Path: "/a.png"}}, nil, false)),
 		}, &Model{Path: "/a.model"}, false},
@@ -568,6 +579,173 @@ func TestDecoder_processNonRootModels(t *testing.T) {
 	}
 }
 
+func TestDecoder_processNonRootModels_ContinueOnChildError(t *testing.T) {
+	newDecoder := func() *Decoder {
+		return &Decoder{Strict: true, nonRootModels: []packageFile{
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<basematerials id="5" />
+					<basematerials id="a" />
+				</resources>
+			`).build("/3D/bad.model"),
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<basematerials id="6" />
+				</resources>
+			`).build("/3D/good.model"),
+		}}
+	}
+	newModel := func() *Model {
+		return &Model{Childs: map[string]*ChildModel{"/3D/bad.model": new(ChildModel), "/3D/good.model": new(ChildModel)}}
+	}
+	if err := newDecoder().processNonRootModels(context.Background(), newModel()); err == nil {
+		t.Error("Decoder.processNonRootModels() expected error")
+	}
+	d := newDecoder()
+	d.ContinueOnChildError = true
+	got := newModel()
+	if err := d.processNonRootModels(context.Background(), got); err != nil {
+		t.Fatalf("Decoder.processNonRootModels() unexpected error = %v", err)
+	}
+	want := &Model{Childs: map[string]*ChildModel{
+		"/3D/bad.model":  new(ChildModel),
+		"/3D/good.model": {Resources: Resources{Assets: []Asset{&BaseMaterials{ID: 6}}}},
+	}}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.processNonRootModels() = %v", diff)
+	}
+	wantWarns := []string{
+		fmt.Sprintf("go3mf: Path: /3D/bad.model XPath: /model/resources/basematerials[1]: %v", specerr.NewParseAttrError("id", true)),
+	}
+	var warns []string
+	for _, w := range d.Warnings {
+		warns = append(warns, w.Error())
+	}
+	if diff := deep.Equal(warns, wantWarns); diff != nil {
+		t.Errorf("Decoder.processNonRootModels() warnings = %v", diff)
+	}
+}
+
+func TestDecoder_processNonRootModels_FirstError(t *testing.T) {
+	newDecoder := func() *Decoder {
+		return &Decoder{Strict: true, nonRootModels: []packageFile{
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<basematerials id="6" />
+				</resources>
+			`).build("/3D/good.model"),
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<basematerials id="a" />
+				</resources>
+			`).build("/3D/bad1.model"),
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<object id="1"><mesh><vertices><vertex x="b" y="0" z="0" /></vertices></mesh></object>
+				</resources>
+			`).build("/3D/bad2.model"),
+		}}
+	}
+	newModel := func() *Model {
+		return &Model{Childs: map[string]*ChildModel{
+			"/3D/good.model": new(ChildModel), "/3D/bad1.model": new(ChildModel), "/3D/bad2.model": new(ChildModel),
+		}}
+	}
+	want := fmt.Sprintf("go3mf: Path: /3D/bad1.model XPath: /model/resources/basematerials[0]: %v", specerr.NewParseAttrError("id", true))
+	for i := 0; i < 20; i++ {
+		err := newDecoder().processNonRootModels(context.Background(), newModel())
+		if err == nil || err.Error() != want {
+			t.Fatalf("Decoder.processNonRootModels() error = %v, want %s", err, want)
+		}
+	}
+}
+
+func TestDecoder_reconcileChildUnits(t *testing.T) {
+	newDecoder := func() *Decoder {
+		return &Decoder{nonRootModels: []packageFile{
+			new(modelBuilder).withModel("inch", "en-US", "").withElement(`
+				<resources>
+					<object id="1"><mesh><vertices><vertex x="1" y="2" z="3" /></vertices></mesh></object>
+					<object id="2"><components><component objectid="1" transform="1 0 0 0 1 0 0 0 1 1 0 0" /></components></object>
+				</resources>
+			`).build("/3D/inch.model"),
+			new(modelBuilder).withDefaultModel().withElement(`
+				<resources>
+					<object id="1"><mesh><vertices><vertex x="1" y="2" z="3" /></vertices></mesh></object>
+				</resources>
+			`).build("/3D/mm.model"),
+		}}
+	}
+	newModel := func() *Model {
+		return &Model{Childs: map[string]*ChildModel{"/3D/inch.model": new(ChildModel), "/3D/mm.model": new(ChildModel)}}
+	}
+	d := newDecoder()
+	got := newModel()
+	if err := d.processNonRootModels(context.Background(), got); err != nil {
+		t.Fatalf("Decoder.processNonRootModels() unexpected error = %v", err)
+	}
+	d.reconcileChildUnits(got)
+	if len(d.Warnings) != 1 || !errors.Is(d.Warnings[0], ErrUnitMismatch) {
+		t.Fatalf("Decoder.reconcileChildUnits() warnings = %v", d.Warnings)
+	}
+	if want := "go3mf: Path: /3D/inch.model XPath: /model: go3mf: model part unit differs from the root model unit: inch, root model uses millimeter"; d.Warnings[0].Error() != want {
+		t.Errorf("Decoder.reconcileChildUnits() warning = %s, want %s", d.Warnings[0], want)
+	}
+
+	d = newDecoder()
+	d.ConvertChildUnits = true
+	got = newModel()
+	if err := d.processNonRootModels(context.Background(), got); err != nil {
+		t.Fatalf("Decoder.processNonRootModels() unexpected error = %v", err)
+	}
+	d.reconcileChildUnits(got)
+	if len(d.Warnings) != 0 {
+		t.Errorf("Decoder.reconcileChildUnits() warnings = %v", d.Warnings)
+	}
+	inch := got.Childs["/3D/inch.model"].Resources.Objects
+	if want := []Point3D{{25.4, 50.8, 76.2}}; !reflect.DeepEqual(inch[0].Mesh.Vertices.Vertex, want) {
+		t.Errorf("Decoder.reconcileChildUnits() vertices = %v, want %v", inch[0].Mesh.Vertices.Vertex, want)
+	}
+	if want := Identity().Translate(25.4, 0, 0); inch[1].Components.Component[0].Transform != want {
+		t.Errorf("Decoder.reconcileChildUnits() transform = %v, want %v", inch[1].Components.Component[0].Transform, want)
+	}
+	mm := got.Childs["/3D/mm.model"].Resources.Objects
+	if want := []Point3D{{1, 2, 3}}; !reflect.DeepEqual(mm[0].Mesh.Vertices.Vertex, want) {
+		t.Errorf("Decoder.reconcileChildUnits() vertices = %v, want %v", mm[0].Mesh.Vertices.Vertex, want)
+	}
+}
+
+func TestDecoder_WarningsByPart(t *testing.T) {
+	if got := new(Decoder).WarningsByPart(); got != nil {
+		t.Errorf("Decoder.WarningsByPart() = %v, want nil", got)
+	}
+	d := &Decoder{ContinueOnChildError: true, nonRootModels: []packageFile{
+		new(modelBuilder).withDefaultModel().withElement(`
+			<resources>
+				<basematerials id="a" />
+				<basematerials id="b" />
+			</resources>
+		`).build("/3D/a.model"),
+		new(modelBuilder).withDefaultModel().withElement(`
+			<resources>
+				<basematerials id="c" />
+			</resources>
+		`).build("/3D/b.model"),
+	}}
+	m := &Model{Childs: map[string]*ChildModel{"/3D/a.model": new(ChildModel), "/3D/b.model": new(ChildModel)}}
+	if err := d.processNonRootModels(context.Background(), m); err != nil {
+		t.Fatalf("Decoder.processNonRootModels() unexpected error = %v", err)
+	}
+	got := make(map[string]int)
+	for path, warns := range d.WarningsByPart() {
+		got[path] = len(warns)
+	}
+	want := map[string]int{"/3D/a.model": 2, "/3D/b.model": 1}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.WarningsByPart() = %v", diff)
+	}
+}
+
 func TestDecoder_Decode(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -587,10 +765,311 @@ func TestDecoder_Decode(t *testing.T) {
 	}
 }
 
+func TestDecoder_Decode_RootPathVariants(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"literal", "/3D/my%20model.model"},
+		{"decoded", "/3D/my model.model"},
+		{"backslash", "\\3D\\my%20model.model"},
+		{"relative", "3D/my%20model.model"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := zip.NewWriter(&buf)
+			files := []struct{ name, body string }{
+				{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+					<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+					<Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+				</Types>`},
+				{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+					<Relationship Id="rel0" Target="` + tt.target + `" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+				</Relationships>`},
+				{"3D/my%20model.model", `<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" unit="millimeter"><resources/><build/></model>`},
+			}
+			for _, f := range files {
+				fw, err := w.Create(f.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				fw.Write([]byte(f.body))
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var model Model
+			if err := NewDecoder(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Decode(&model); err != nil {
+				t.Fatalf("Decoder.Decode() error = %v", err)
+			}
+			if want := "/3D/my%20model.model"; model.Path != want {
+				t.Errorf("Decoder.Decode() path = %s, want %s", model.Path, want)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_RootModelPath(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+			<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+			<Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+			<Default Extension="png" ContentType="image/png"/>
+		</Types>`},
+		{"3D/3dmodel.model", `<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" unit="millimeter"><resources/><build/></model>`},
+		{"3D/thumb.png", "png"},
+	}
+	for _, f := range files {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(f.body))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		rootPath string
+		wantErr  bool
+	}{
+		{"empty", "", true},
+		{"found", "/3D/3dmodel.model", false},
+		{"relative", "3D/3dmodel.model", false},
+		{"missing", "/3D/other.model", true},
+		{"contentType", "/3D/thumb.png", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			d.RootModelPath = tt.rootPath
+			var model Model
+			err := d.Decode(&model)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && model.Path != "/3D/3dmodel.model" {
+				t.Errorf("Decoder.Decode() path = %s, want /3D/3dmodel.model", model.Path)
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_BuildBeforeResources(t *testing.T) {
+	resources := `<resources>
+		<object id="1"><mesh>
+			<vertices><vertex x="0" y="0" z="0" /><vertex x="1" y="0" z="0" /><vertex x="0" y="1" z="0" /><vertex x="0" y="0" z="1" /></vertices>
+			<triangles><triangle v1="0" v2="2" v3="1" /><triangle v1="0" v2="1" v3="3" /><triangle v1="0" v2="3" v3="2" /><triangle v1="1" v2="2" v3="3" /></triangles>
+		</mesh></object>
+		<object id="2"><components><component objectid="1" transform="1 0 0 0 1 0 0 0 1 5 0 0" /></components></object>
+	</resources>`
+	build := `<build><item objectid="2" partnumber="a" /><item objectid="1" /></build>`
+	decode := func(body string) *Model {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		files := []struct{ name, body string }{
+			{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+				<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+				<Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+			</Types>`},
+			{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+				<Relationship Id="rel0" Target="/3D/3dmodel.model" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+			</Relationships>`},
+			{"3D/3dmodel.model", `<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" unit="millimeter">` + body + `</model>`},
+		}
+		for _, f := range files {
+			fw, err := w.Create(f.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fw.Write([]byte(f.body))
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		model := new(Model)
+		if err := NewDecoder(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Decode(model); err != nil {
+			t.Fatalf("Decoder.Decode() error = %v", err)
+		}
+		return model
+	}
+	want := decode(resources + build)
+	got := decode(build + resources)
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.Decode() = %v", diff)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("Model.Validate() error = %v", err)
+	}
+	if n := len(got.Instances()); n != 2 {
+		t.Errorf("Model.Instances() = %d instances, want 2", n)
+	}
+}
+
+func TestDecoder_OnProgress(t *testing.T) {
+	defer func(n int64) { checkEveryBytes = n }(checkEveryBytes)
+	checkEveryBytes = 1024
+	var buf bytes.Buffer
+	m := &Model{Resources: Resources{Objects: []*Object{{ID: 1, Mesh: largeMesh(100)}}}}
+	if err := NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	var read, totals []int64
+	d := NewDecoder(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	d.OnProgress = func(bytesRead, totalBytes int64) {
+		read = append(read, bytesRead)
+		totals = append(totals, totalBytes)
+	}
+	if err := d.Decode(new(Model)); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if len(read) < 2 {
+		t.Fatalf("Decoder.OnProgress calls = %d, want at least 2", len(read))
+	}
+	for i := range read {
+		if totals[i] <= 0 || totals[i] != totals[0] {
+			t.Errorf("Decoder.OnProgress() total = %d, want %d", totals[i], totals[0])
+		}
+		if i > 0 && read[i] <= read[i-1] {
+			t.Errorf("Decoder.OnProgress() read = %d after %d", read[i], read[i-1])
+		}
+	}
+	if last := read[len(read)-1]; last != totals[0] {
+		t.Errorf("Decoder.OnProgress() last read = %d, want %d", last, totals[0])
+	}
+}
+
+func TestDecoder_Decode_NotAPackage(t *testing.T) {
+	data := []byte("solid cube\nendsolid cube\n")
+	err := NewDecoder(bytes.NewReader(data), int64(len(data))).Decode(new(Model))
+	if !errors.Is(err, ErrNotAPackage) {
+		t.Errorf("Decoder.Decode() error = %v, want %v", err, ErrNotAPackage)
+	}
+	if errors.Unwrap(err) == nil {
+		t.Error("Decoder.Decode() error does not wrap the underlying cause")
+	}
+	d := &Decoder{p: newMockPackage(nil)}
+	if err := d.Decode(new(Model)); err == nil || errors.Is(err, ErrNotAPackage) {
+		t.Errorf("Decoder.Decode() error = %v, want a malformed package error", err)
+	}
+}
+
+type cancelReader struct {
+	r        io.Reader
+	n, at    int64
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func (c *cancelReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	if !c.canceled && c.n >= c.at {
+		c.canceled = true
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestDecoder_Decode_TriangleOutOfRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		vertices string
+		want     int
+	}{
+		{"outOfRange", `<vertices><vertex x="0" y="0" z="0" /><vertex x="1" y="0" z="0" /><vertex x="0" y="1" z="0" /></vertices>`, 1},
+		{"emptyVertices", `<vertices/>`, 0},
+		{"missingVertices", ``, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := `<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02"><resources>
+				<object id="1"><mesh>` + tt.vertices + `
+					<triangles><triangle v1="0" v2="1" v3="2" /><triangle v1="0" v2="1" v3="999" /></triangles>
+				</mesh></object>
+			</resources><build/></model>`
+			want := fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh/triangles/triangle[%d]: %v", tt.want, specerr.ErrIndexOutOfBounds)
+			d := NewDecoder(nil, 0)
+			err := d.UnmarshalModelReader(strings.NewReader(data), new(Model))
+			if err == nil || err.Error() != want {
+				t.Errorf("Decoder.UnmarshalModelReader() strict error = %v, want %s", err, want)
+			}
+			if !errors.Is(err, specerr.ErrIndexOutOfBounds) {
+				t.Errorf("Decoder.UnmarshalModelReader() strict error = %v, want %v", err, specerr.ErrIndexOutOfBounds)
+			}
+			d.Strict = false
+			if err := d.UnmarshalModelReader(strings.NewReader(data), new(Model)); err != nil {
+				t.Errorf("Decoder.UnmarshalModelReader() error = %v", err)
+			}
+			wantWarning := strings.Replace(want, "go3mf: ", "go3mf: Path: /3D/3dmodel.model ", 1)
+			if len(d.Warnings) == 0 || d.Warnings[0].Error() != wantWarning {
+				t.Errorf("Decoder.Warnings = %v, want %s", d.Warnings, wantWarning)
+			}
+			for _, w := range d.Warnings {
+				if !errors.Is(w, specerr.ErrIndexOutOfBounds) {
+					t.Errorf("Decoder.Warnings = %v, want %v", w, specerr.ErrIndexOutOfBounds)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_MalformedTransform(t *testing.T) {
+	data := `<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02"><resources/>
+		<build><item objectid="1" transform="1 0 0 0 1 0 0 0 1 a 0 0" /></build>
+	</model>`
+	err := UnmarshalModel([]byte(data), new(Model))
+	var matrixErr *specerr.ParseMatrixError
+	if !errors.As(err, &matrixErr) || matrixErr.Value != "1 0 0 0 1 0 0 0 1 a 0 0" {
+		t.Errorf("UnmarshalModel() error = %v, want the raw transform", err)
+	}
+	var attrErr *specerr.ParseAttrError
+	if !errors.As(err, &attrErr) || attrErr.Name != "transform" || attrErr.Required {
+		t.Errorf("UnmarshalModel() error = %v, want a transform ParseAttrError", err)
+	}
+}
+
+func TestDecoder_CancelCheckInterval(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString(`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02"><resources>`)
+	for buf.Len() < 1<<20 {
+		buf.WriteString(`<basematerials id="1"><base name="a" displaycolor="#FFFFFF" /></basematerials>`)
+	}
+	buf.WriteString(`</resources><build/></model>`)
+	const cancelAt = 64 << 10
+	tests := []struct {
+		name     string
+		interval int64
+	}{
+		{"endElement", 0},
+		{"small", 8 << 10},
+		{"large", 256 << 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := &cancelReader{r: bytes.NewReader(buf.Bytes()), at: cancelAt, cancel: cancel}
+			d := &Decoder{CancelCheckInterval: tt.interval}
+			err := d.decodeModelFile(ctx, r, new(Model), "", true, new(Units), new([]error))
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Decoder.decodeModelFile() error = %v, want %v", err, context.Canceled)
+			}
+			// The xml decoder reads ahead up to a 4KB buffer.
+			if max := cancelAt + tt.interval + 2*4096; r.n > max {
+				t.Errorf("Decoder.decodeModelFile() read %d bytes after cancel, want at most %d", r.n-cancelAt, max-cancelAt)
+			}
+		})
+	}
+}
+
 func Test_modelFile_Decode(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	checkEveryTokens = 1
 	type args struct {
 		ctx context.Context
 		r   io.Reader
@@ -616,13 +1095,357 @@ func Test_modelFile_Decode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := decodeModelFile(tt.args.ctx, tt.args.r, new(Model), "", true, false); (err != nil) != tt.wantErr {
+			if err := new(Decoder).decodeModelFile(tt.args.ctx, tt.args.r, new(Model), "", true, new(Units), new([]error)); (err != nil) != tt.wantErr {
 				t.Errorf("modelFile.Decode() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
+func Test_modelFile_Decode_MaxBuildItems(t *testing.T) {
+	data := `
+		<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+			<resources>
+				<object id="1"><components><component objectid="2" /></components></object>
+				<object id="2"><components><component objectid="1" /></components></object>
+			</resources>
+			<build>
+				<item objectid="1" />
+				<item objectid="2"><metadatagroup><metadata name="Title">a</metadata></metadatagroup></item>
+				<item objectid="a" />
+				<item objectid="1" />
+			</build>
+			<metadata name="Title">model</metadata>
+		</model>`
+	tests := []struct {
+		name     string
+		maxItems int
+		want     []uint32
+		wantErr  bool
+	}{
+		{"unlimited", 0, []uint32{1, 2, 0, 1}, true},
+		{"high", 10, []uint32{1, 2, 0, 1}, true},
+		{"capped", 2, []uint32{1, 2}, false},
+		{"one", 1, []uint32{1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Decoder{MaxBuildItems: tt.maxItems}
+			model := new(Model)
+			err := d.decodeModelFile(context.Background(), bytes.NewBufferString(data), model, "", true, new(Units), new([]error))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("modelFile.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []uint32
+			for _, item := range model.Build.Items {
+				got = append(got, item.ObjectID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("modelFile.Decode() items = %v, want %v", got, tt.want)
+			}
+			if len(model.Resources.Objects) != 2 || len(model.Metadata) != 1 {
+				t.Errorf("modelFile.Decode() = %d objects %d metadata, want 2 1", len(model.Resources.Objects), len(model.Metadata))
+			}
+		})
+	}
+}
+
+func Test_modelFile_Decode_MaxErrors(t *testing.T) {
+	data := `
+		<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+			<resources>
+				<object id="1">
+					<mesh>
+						<vertices>
+							<vertex x="a" y="0" z="0" />
+							<vertex x="b" y="0" z="0" />
+							<vertex x="c" y="0" z="0" />
+							<vertex x="d" y="0" z="0" />
+						</vertices>
+					</mesh>
+				</object>
+			</resources>
+		</model>`
+	tests := []struct {
+		name      string
+		maxErrors int
+		wantLen   int
+		wantMax   bool
+	}{
+		{"unlimited", 0, 4, false},
+		{"high", 10, 4, false},
+		{"capped", 2, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Decoder{MaxErrors: tt.maxErrors}
+			err := d.decodeModelFile(context.Background(), bytes.NewBufferString(data), new(Model), "", true, new(Units), new([]error))
+			var errs *specerr.List
+			if !errors.As(err, &errs) {
+				t.Fatalf("modelFile.Decode() error = %v, want *errors.List", err)
+			}
+			if got := errs.Len(); got != tt.wantLen {
+				t.Errorf("modelFile.Decode() errors = %d, want %d", got, tt.wantLen)
+			}
+			if got := errors.Is(err, ErrTooManyErrors); got != tt.wantMax {
+				t.Errorf("modelFile.Decode() ErrTooManyErrors = %v, want %v", got, tt.wantMax)
+			}
+		})
+	}
+}
+
+type mapVertexSink map[*Mesh][]Point3D
+
+func (s mapVertexSink) AddVertex(mesh *Mesh, v Point3D) error {
+	if len(s[mesh]) == 2 {
+		return errors.New("sink full")
+	}
+	s[mesh] = append(s[mesh], v)
+	return nil
+}
+
+func (s mapVertexSink) Len(mesh *Mesh) int {
+	return len(s[mesh])
+}
+
+func TestDecoder_VertexSink(t *testing.T) {
+	data := []byte(`
+		<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+			<resources>
+				<object id="1">
+					<mesh>
+						<vertices>
+							<vertex x="1" y="2" z="3" />
+							<vertex x="4" y="5" z="6" />
+							<vertex x="7" y="8" z="9" />
+						</vertices>
+					</mesh>
+				</object>
+			</resources>
+		</model>`)
+	sink := make(mapVertexSink)
+	d := &Decoder{VertexSink: sink}
+	model := new(Model)
+	err := d.processRootModel(context.Background(), &fakePackageFile{data: data}, model)
+	want := "go3mf: XPath: /model/resources/object[0]/mesh/vertices/vertex[2]: sink full"
+	if err == nil || err.Error() != want {
+		t.Errorf("Decoder.VertexSink error = %v, want %s", err, want)
+	}
+	mesh := model.Resources.Objects[0].Mesh
+	if len(mesh.Vertices.Vertex) != 0 {
+		t.Errorf("Decoder.VertexSink mesh vertices = %v, want empty", mesh.Vertices.Vertex)
+	}
+	if got, want := sink[mesh], []Point3D{{1, 2, 3}, {4, 5, 6}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Decoder.VertexSink vertices = %v, want %v", got, want)
+	}
+}
+
+func TestDecoder_SkipGeometry(t *testing.T) {
+	data := []byte(`
+		<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+			<resources>
+				<object id="1" type="support" name="Tetra">
+					<metadatagroup>
+						<metadata name="qm:CustomMetadata">Hello</metadata>
+					</metadatagroup>
+					<mesh>
+						<vertices>
+							<vertex x="1" y="2" z="3" />
+							<vertex x="4" y="5" z="6" />
+							<vertex x="7" y="8" z="9" />
+						</vertices>
+						<triangles>
+							<triangle v1="0" v2="1" v3="2" />
+						</triangles>
+					</mesh>
+				</object>
+			</resources>
+			<build>
+				<item objectid="1" />
+			</build>
+		</model>`)
+	d := &Decoder{SkipGeometry: true}
+	model := new(Model)
+	if err := d.processRootModel(context.Background(), &fakePackageFile{data: data}, model); err != nil {
+		t.Fatalf("Decoder.SkipGeometry error = %v", err)
+	}
+	want := &Object{
+		ID: 1, Type: ObjectTypeSupport, Name: "Tetra", Mesh: new(Mesh),
+		Metadata: MetadataGroup{Metadata: []Metadata{{Name: xml.Name{Local: "qm:CustomMetadata"}, Value: "Hello"}}},
+	}
+	if diff := deep.Equal(model.Resources.Objects, []*Object{want}); diff != nil {
+		t.Errorf("Decoder.SkipGeometry objects = %v", diff)
+	}
+	if len(model.Build.Items) != 1 {
+		t.Errorf("Decoder.SkipGeometry items = %v, want 1", model.Build.Items)
+	}
+}
+
+func TestUnmarshalModel_MetadataCharData(t *testing.T) {
+	data := []byte(`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+		<build/>
+		<metadata name="Title">Nuts &amp; bolts</metadata>
+		<metadata name="Description">first line
+second &lt;line&gt;</metadata>
+		<metadata name="Designer">Jane<!-- comment --> Doe</metadata>
+	</model>`)
+	model := new(Model)
+	if err := UnmarshalModel(data, model); err != nil {
+		t.Fatalf("UnmarshalModel() error = %v", err)
+	}
+	want := []Metadata{
+		{Name: xml.Name{Local: "Title"}, Value: "Nuts & bolts"},
+		{Name: xml.Name{Local: "Description"}, Value: "first line\nsecond <line>"},
+		{Name: xml.Name{Local: "Designer"}, Value: "Jane Doe"},
+	}
+	if diff := deep.Equal(model.Metadata, want); diff != nil {
+		t.Errorf("UnmarshalModel() metadata = %v", diff)
+	}
+}
+
+func TestUnmarshalModel_Entities(t *testing.T) {
+	data := []byte(`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+		<resources>
+			<object id="&#49;">
+				<mesh>
+					<vertices>
+						<vertex x="1&#46;5" y="&#x32;" z="-&#51;.25" />
+						<vertex x="0" y="0" z="0" />
+						<vertex x="1" y="0" z="0" />
+					</vertices>
+					<triangles>
+						<triangle v1="&#48;" v2="1" v3="&#x32;" pid="1&#48;" p1="&#50;" />
+					</triangles>
+				</mesh>
+			</object>
+		</resources>
+		<build/>
+		<metadata name="Title">Nuts <![CDATA[& bolts <v2>]]> &amp; more</metadata>
+		<metadata name="Description"><![CDATA[]]]]></metadata>
+	</model>`)
+	model := new(Model)
+	if err := UnmarshalModel(data, model); err != nil {
+		t.Fatalf("UnmarshalModel() error = %v", err)
+	}
+	if got := model.Resources.Objects; len(got) != 1 || got[0].ID != 1 {
+		t.Fatalf("UnmarshalModel() objects = %v", got)
+	}
+	mesh := model.Resources.Objects[0].Mesh
+	if want := []Point3D{{1.5, 2, -3.25}, {0, 0, 0}, {1, 0, 0}}; !reflect.DeepEqual(mesh.Vertices.Vertex, want) {
+		t.Errorf("UnmarshalModel() vertices = %v, want %v", mesh.Vertices.Vertex, want)
+	}
+	if want := []Triangle{{V1: 0, V2: 1, V3: 2, PID: 10, P1: 2, P2: 2, P3: 2}}; !reflect.DeepEqual(mesh.Triangles.Triangle, want) {
+		t.Errorf("UnmarshalModel() triangles = %v, want %v", mesh.Triangles.Triangle, want)
+	}
+	want := []Metadata{
+		{Name: xml.Name{Local: "Title"}, Value: "Nuts & bolts <v2> & more"},
+		{Name: xml.Name{Local: "Description"}, Value: "]]"},
+	}
+	if diff := deep.Equal(model.Metadata, want); diff != nil {
+		t.Errorf("UnmarshalModel() metadata = %v", diff)
+	}
+}
+
+func TestDecoder_ResourceOrder(t *testing.T) {
+	data := []byte(`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+		<resources>
+			<basematerials id="9"><base name="a" displaycolor="#FF0000" /></basematerials>
+			<object id="3"><components><component objectid="7" /></components></object>
+			<basematerials id="2"><base name="b" displaycolor="#00FF00" /></basematerials>
+			<object id="1"><components><component objectid="7" /></components></object>
+			<basematerials id="5"><base name="c" displaycolor="#0000FF" /></basematerials>
+			<object id="7"><mesh><vertices /><triangles /></mesh></object>
+		</resources>
+		<build><item objectid="3" /><item objectid="1" /></build>
+	</model>`)
+	ids := func(m *Model) (assets, objects []uint32) {
+		for _, a := range m.Resources.Assets {
+			assets = append(assets, a.Identify())
+		}
+		for _, o := range m.Resources.Objects {
+			objects = append(objects, o.ID)
+		}
+		return
+	}
+	wantAssets, wantObjects := []uint32{9, 2, 5}, []uint32{3, 1, 7}
+	model := new(Model)
+	if err := UnmarshalModel(data, model); err != nil {
+		t.Fatalf("UnmarshalModel() error = %v", err)
+	}
+	if assets, objects := ids(model); !reflect.DeepEqual(assets, wantAssets) || !reflect.DeepEqual(objects, wantObjects) {
+		t.Errorf("UnmarshalModel() order = %v %v, want %v %v", assets, objects, wantAssets, wantObjects)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(model); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	got := new(Model)
+	if err := NewDecoder(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if assets, objects := ids(got); !reflect.DeepEqual(assets, wantAssets) || !reflect.DeepEqual(objects, wantObjects) {
+		t.Errorf("Decoder.Decode() order = %v %v, want %v %v", assets, objects, wantAssets, wantObjects)
+	}
+}
+
+func TestUnmarshalModel_Truncated(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+	<!-- generated -->
+	<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" unit="millimeter" xml:lang="en-US">
+		<metadata name="Title" type="xs:string">Truncated &amp; broken</metadata>
+		<resources>
+			<basematerials id="1">
+				<base name="Red" displaycolor="#FF0000"/>
+				<base name="Green" displaycolor="#00FF0080"/>
+			</basematerials>
+			<object id="2" name="Tri" pid="1" pindex="0" type="model">
+				<mesh>
+					<vertices>
+						<vertex x="0" y="0" z="0"/>
+						<vertex x="100.5" y="0" z="-1e-3"/>
+						<vertex x="0" y="100.25" z="0"/>
+						<vertex x="0" y="0" z="100"/>
+					</vertices>
+					<triangles>
+						<triangle v1="0" v2="2" v3="1" pid="1" p1="0" p2="1" p3="0"/>
+						<triangle v1="0" v2="1" v3="3"/>
+						<triangle v1="0" v2="3" v3="2"/>
+						<triangle v1="1" v2="2" v3="3" pid="1" p1="1"/>
+					</triangles>
+				</mesh>
+			</object>
+			<object id="3">
+				<components>
+					<component objectid="2" transform="1 0 0 0 1 0 0 0 1 10 20 30"/>
+				</components>
+			</object>
+		</resources>
+		<build>
+			<item objectid="3" partnumber="p1" transform="1 0 0 0 1 0 0 0 1 -10.5 20 0">
+				<metadatagroup><metadata name="Designer">go3mf</metadata></metadatagroup>
+			</item>
+		</build>
+	</model>`)
+	start := bytes.Index(data, []byte("<model"))
+	end := bytes.LastIndex(data, []byte("</model>")) + len("</model>")
+	if err := UnmarshalModel(data, new(Model)); err != nil {
+		t.Fatalf("UnmarshalModel() unexpected error = %v", err)
+	}
+	for i := 0; i < len(data); i++ {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("UnmarshalModel() panic when truncated at %d: %v", i, r)
+				}
+			}()
+			err := UnmarshalModel(data[:i], new(Model))
+			if i > start && i < end && err == nil {
+				t.Errorf("UnmarshalModel() expected error when truncated at %d", i)
+			}
+		}()
+	}
+}
+
 func TestNewDecoder(t *testing.T) {
 	type args struct {
 		r    io.ReaderAt
@@ -634,8 +1457,10 @@ func TestNewDecoder(t *testing.T) {
 		want *Decoder
 	}{
 		{"base", args{nil, 5}, &Decoder{
-			Strict: true,
-			p:      &opcReader{ra: nil, size: 5},
+			Strict:              true,
+			MaxComponentDepth:   DefaultMaxComponentDepth,
+			CancelCheckInterval: DefaultCancelCheckInterval,
+			p:                   &opcReader{ra: nil, size: 5},
 		}},
 	}
 	for _, tt := range tests {
@@ -657,10 +1482,11 @@ func TestDecoder_processRootModel_warns(t *testing.T) {
 		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("pid", false)),
 		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("pindex", false)),
 		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]: %v", specerr.NewParseAttrError("type", false)),
-		fmt.Sprintf("go3mf: XPath: /model/resources/object[2]/components/component[0]: %v", specerr.NewParseAttrError("transform", false)),
+		fmt.Sprintf("go3mf: XPath: /model/resources/object[2]/components/component[0]: %v", specerr.NewParseMatrixError("0 0 0 1 0 0 0 2 -66.4 -87.1 8.8")),
 		fmt.Sprintf("go3mf: XPath: /model/resources/object[2]/components/component[1]: %v", specerr.NewParseAttrError("objectid", true)),
-		fmt.Sprintf("go3mf: XPath: /model/build/item[0]: %v", specerr.NewParseAttrError("transform", false)),
+		fmt.Sprintf("go3mf: XPath: /model/build/item[0]: %v", specerr.NewParseMatrixError("1 0 0 0 2 0 0 0 3 -66.4 -87.1")),
 		fmt.Sprintf("go3mf: XPath: /model/build/item[3]: %v", specerr.NewParseAttrError("objectid", true)),
+		fmt.Sprintf("go3mf: XPath: /model/build/item[4]: %v", specerr.NewMissingFieldError("objectid")),
 	}
 	got := new(Model)
 	got.Extensions = append(got.Extensions, fakeSpec)
@@ -722,6 +1548,7 @@ func TestDecoder_processRootModel_warns(t *testing.T) {
 			<item objectid="8"/>
 			<item objectid="5"/>
 			<item objectid="a"/>
+			<item partnumber="missing"/>
 		</build>
 		<metadata name="Application">go3mf app</metadata>
 		<metadata name="qm:CustomMetadata1" type="xs:string" preserve="1">CE8A91FB-C44E-4F00-B634-BAA411465F6A</metadata>
@@ -744,6 +1571,40 @@ func TestDecoder_processRootModel_warns(t *testing.T) {
 		t.Errorf("Decoder.processRootModel() = %v", diff)
 		return
 	}
+	wantWarnings := []string{
+		fmt.Sprintf("go3mf: Path: %s XPath: /model/resources/basematerials[0]/base[1]: %v", rootFile.Name(), specerr.NewMissingFieldError("displaycolor")),
+		fmt.Sprintf("go3mf: Path: %s XPath: /model/resources/object[0]/mesh/triangles/triangle[1]: %v", rootFile.Name(), specerr.ErrIndexOutOfBounds),
+	}
+	var warnings []string
+	for _, w := range d.Warnings {
+		warnings = append(warnings, w.Error())
+	}
+	if diff := deep.Equal(warnings, wantWarnings); diff != nil {
+		t.Errorf("Decoder.processRootModel() warnings = %v", diff)
+	}
+}
+
+func TestDecoder_Decode_BaseWithoutDisplayColor(t *testing.T) {
+	data := `<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02"><resources>
+		<basematerials id="1"><base name="a" /><base /></basematerials>
+	</resources><build/></model>`
+	d := NewDecoder(nil, 0)
+	got := new(Model)
+	if err := d.UnmarshalModelReader(strings.NewReader(data), got); err != nil {
+		t.Fatalf("Decoder.UnmarshalModelReader() error = %v", err)
+	}
+	if mats, ok := got.Resources.Assets[0].(*BaseMaterials); !ok || len(mats.Materials) != 2 {
+		t.Errorf("Decoder.UnmarshalModelReader() assets = %v", got.Resources.Assets)
+	}
+	if len(d.Warnings) != 2 {
+		t.Fatalf("Decoder.Warnings = %v, want 2 warnings", d.Warnings)
+	}
+	for _, w := range d.Warnings {
+		var missing *specerr.MissingFieldError
+		if !errors.As(w, &missing) || missing.Name != "displaycolor" {
+			t.Errorf("Decoder.Warnings = %v, want missing displaycolor", w)
+		}
+	}
 }
 
 func TestOpenReader(t *testing.T) {
@@ -789,3 +1650,368 @@ func TestOpenReader(t *testing.T) {
 		return
 	}
 }
+
+func TestUnmarshalModel_CoreNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		ns   string
+		want string
+	}{
+		{"1.x", Namespace, ""},
+		{"draft", NamespaceDraft, NamespaceDraft},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(`<model xmlns="` + tt.ns + `" unit="inch">
+				<resources>
+					<basematerials id="1"><base name="a" displaycolor="#FF0000" /></basematerials>
+					<object id="2"><mesh>
+						<vertices><vertex x="0" y="0" z="0" /><vertex x="1" y="0" z="0" /><vertex x="0" y="1" z="0" /></vertices>
+						<triangles><triangle v1="0" v2="1" v3="2" /></triangles>
+					</mesh></object>
+				</resources>
+				<build><item objectid="2" /></build>
+				<metadata name="Title">cube</metadata>
+			</model>`)
+			model := new(Model)
+			if err := UnmarshalModel(data, model); err != nil {
+				t.Fatalf("UnmarshalModel() error = %v", err)
+			}
+			if model.CoreNamespace != tt.want {
+				t.Errorf("UnmarshalModel() CoreNamespace = %v, want %v", model.CoreNamespace, tt.want)
+			}
+			if model.Units != UnitInch || len(model.Resources.Assets) != 1 || len(model.Resources.Objects) != 1 ||
+				len(model.Resources.Objects[0].Mesh.Triangles.Triangle) != 1 || len(model.Build.Items) != 1 || len(model.Metadata) != 1 {
+				t.Errorf("UnmarshalModel() = %+v", model)
+			}
+		})
+	}
+}
+
+func TestUnmarshalModel_TriangleProperties(t *testing.T) {
+	tests := []struct {
+		name   string
+		object string
+		attrs  string
+		want   Triangle
+	}{
+		{"none", `pid="7" pindex="9"`, ``, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"pid", `pid="7" pindex="9"`, `pid="2"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"p1", `pid="7" pindex="9"`, `p1="3"`, Triangle{PID: 7, P1: 3, P2: 3, P3: 3}},
+		{"p2", `pid="7" pindex="9"`, `p2="4"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"p3", `pid="7" pindex="9"`, `p3="5"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"pid-p1", `pid="7" pindex="9"`, `pid="2" p1="3"`, Triangle{PID: 2, P1: 3, P2: 3, P3: 3}},
+		{"pid-p2", `pid="7" pindex="9"`, `pid="2" p2="4"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"pid-p3", `pid="7" pindex="9"`, `pid="2" p3="5"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"p1-p2", `pid="7" pindex="9"`, `p1="3" p2="4"`, Triangle{PID: 7, P1: 3, P2: 4, P3: 3}},
+		{"p1-p3", `pid="7" pindex="9"`, `p1="3" p3="5"`, Triangle{PID: 7, P1: 3, P2: 3, P3: 5}},
+		{"p2-p3", `pid="7" pindex="9"`, `p2="4" p3="5"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"pid-p1-p2", `pid="7" pindex="9"`, `pid="2" p1="3" p2="4"`, Triangle{PID: 2, P1: 3, P2: 4, P3: 3}},
+		{"pid-p1-p3", `pid="7" pindex="9"`, `pid="2" p1="3" p3="5"`, Triangle{PID: 2, P1: 3, P2: 3, P3: 5}},
+		{"pid-p2-p3", `pid="7" pindex="9"`, `pid="2" p2="4" p3="5"`, Triangle{PID: 7, P1: 9, P2: 9, P3: 9}},
+		{"p1-p2-p3", `pid="7" pindex="9"`, `p1="3" p2="4" p3="5"`, Triangle{PID: 7, P1: 3, P2: 4, P3: 5}},
+		{"all", `pid="7" pindex="9"`, `pid="2" p1="3" p2="4" p3="5"`, Triangle{PID: 2, P1: 3, P2: 4, P3: 5}},
+		{"noObjectPID", ``, `p2="4" p3="5"`, Triangle{}},
+		{"noObjectPID-pid-p1", ``, `pid="2" p1="3"`, Triangle{PID: 2, P1: 3, P2: 3, P3: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(`<model xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02"><resources>
+				<object id="1" ` + tt.object + `><mesh><vertices /><triangles>
+					<triangle v1="0" v2="1" v3="2" ` + tt.attrs + ` />
+				</triangles></mesh></object>
+			</resources></model>`)
+			model := new(Model)
+			if err := UnmarshalModel(data, model); err != nil {
+				t.Fatalf("UnmarshalModel() error = %v", err)
+			}
+			want := tt.want
+			want.V1, want.V2, want.V3 = 0, 1, 2
+			if got := model.Resources.Objects[0].Mesh.Triangles.Triangle[0]; !reflect.DeepEqual(got, want) {
+				t.Errorf("UnmarshalModel() triangle = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecoder_UnmarshalModelReader(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(`<model xmlns="` + Namespace + `" unit="millimeter">
+		<resources>
+			<object id="1"><mesh>
+				<vertices><vertex x="0" y="0" z="0" /><vertex x="1" y="0" z="0" /><vertex x="0" y="1" z="0" /></vertices>
+				<triangles><triangle v1="0" v2="1" v3="2" /></triangles>
+			</mesh></object>
+		</resources>
+		<build><item objectid="1" /></build>
+	</model>`))
+	w.Close()
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	want := &Model{
+		Units: UnitMillimeter,
+		Resources: Resources{Objects: []*Object{{ID: 1, Mesh: &Mesh{
+			Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+			Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+		}}}},
+		Build: Build{Items: []*Item{{ObjectID: 1}}},
+	}
+	got := new(Model)
+	if err := NewDecoder(nil, 0).UnmarshalModelReader(r, got); err != nil {
+		t.Fatalf("Decoder.UnmarshalModelReader() error = %v", err)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.UnmarshalModelReader() = %v", diff)
+	}
+}
+
+func TestDecoder_addAttachment(t *testing.T) {
+	tests := []struct {
+		name          string
+		caseSensitive bool
+		file          string
+		wantPaths     []string
+		wantWarn      bool
+	}{
+		{"new", false, "/b.png", []string{"/a.png", "/b.png"}, false},
+		{"same", false, "/a.png", []string{"/a.png"}, false},
+		{"case", false, "/A.png", []string{"/a.png"}, true},
+		{"caseSensitive", true, "/A.png", []string{"/a.png", "/A.png"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Decoder{CaseSensitivePaths: tt.caseSensitive}
+			m := &Model{Attachments: []Attachment{{Path: "/a.png"}}}
+			d.addAttachment(m, newMockFile(tt.file, nil, nil, false))
+			var paths []string
+			for _, a := range m.Attachments {
+				paths = append(paths, a.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("Decoder.addAttachment() = %v, want %v", paths, tt.wantPaths)
+			}
+			if tt.wantWarn != (len(d.Warnings) == 1 && errors.Is(d.Warnings[0], ErrPathCaseCollision)) {
+				t.Errorf("Decoder.addAttachment() warnings = %v, want collision %v", d.Warnings, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestDecoder_RegisterContentHandler(t *testing.T) {
+	m := &Model{
+		Attachments: []Attachment{
+			{Path: "/3D/extra.bin", ContentType: "application/x-extra", Stream: bytes.NewBufferString("extra")},
+			{Path: "/3D/other.bin", ContentType: "application/x-other", Stream: bytes.NewBufferString("other")},
+		},
+		Relationships: []Relationship{
+			{Path: "/3D/extra.bin", Type: "http://example.com/extra"},
+			{Path: "/3D/other.bin", Type: "http://example.com/other"},
+		},
+	}
+	b, err := NewEncoder(new(bytes.Buffer)).EncodeToMemory(m)
+	if err != nil {
+		t.Fatalf("Encoder.EncodeToMemory() error = %v", err)
+	}
+	var got []string
+	d := NewDecoder(bytes.NewReader(b), int64(len(b)))
+	d.RegisterContentHandler("application/x-extra", func(path string, r io.Reader, model *Model) error {
+		data, err := ioutil.ReadAll(r)
+		got = append(got, path+":"+string(data))
+		return err
+	})
+	decoded := new(Model)
+	if err := d.Decode(decoded); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if want := []string{"/3D/extra.bin:extra"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ContentHandler calls = %v, want %v", got, want)
+	}
+	if att, ok := decoded.FindAttachment("/3D/extra.bin"); !ok || att.Stream.(*bytes.Buffer).String() != "extra" {
+		t.Error("Decoder.Decode() handled attachment not added")
+	}
+
+	wantErr := errors.New("handler error")
+	d.RegisterContentHandler("application/x-other", func(string, io.Reader, *Model) error { return wantErr })
+	if err := d.Decode(new(Model)); err != wantErr {
+		t.Errorf("Decoder.Decode() error = %v, want %v", err, wantErr)
+	}
+	d.RegisterContentHandler("application/x-other", nil)
+	if err := d.Decode(new(Model)); err != nil {
+		t.Errorf("Decoder.Decode() error = %v", err)
+	}
+}
+
+func TestDecoder_MaxAttachmentBytes(t *testing.T) {
+	m := &Model{
+		Attachments: []Attachment{
+			{Path: "/3D/small.bin", ContentType: "application/x-bin", Stream: bytes.NewBufferString("small")},
+			{Path: "/3D/big.bin", ContentType: "application/x-bin", Stream: bytes.NewBufferString("big content")},
+		},
+		Relationships: []Relationship{
+			{Path: "/3D/small.bin", Type: "http://example.com/bin"},
+			{Path: "/3D/big.bin", Type: "http://example.com/bin"},
+		},
+	}
+	b, err := NewEncoder(new(bytes.Buffer)).EncodeToMemory(m)
+	if err != nil {
+		t.Fatalf("Encoder.EncodeToMemory() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		limit     int64
+		strict    bool
+		wantErr   bool
+		wantAtts  []string
+		wantWarns int
+	}{
+		{"unlimited", 0, true, false, []string{"/3D/small.bin", "/3D/big.bin"}, 0},
+		{"exact", 11, true, false, []string{"/3D/small.bin", "/3D/big.bin"}, 0},
+		{"strict", 10, true, true, nil, 0},
+		{"warning", 10, false, false, []string{"/3D/small.bin"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(b), int64(len(b)))
+			d.Strict = tt.strict
+			d.MaxAttachmentBytes = tt.limit
+			got := new(Model)
+			err := d.Decode(got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrAttachmentTooLarge) {
+					t.Errorf("Decoder.Decode() error = %v, want %v", err, ErrAttachmentTooLarge)
+				}
+				return
+			}
+			var paths []string
+			for _, a := range got.Attachments {
+				paths = append(paths, a.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantAtts) {
+				t.Errorf("Decoder.Decode() attachments = %v, want %v", paths, tt.wantAtts)
+			}
+			if len(d.Warnings) != tt.wantWarns {
+				t.Fatalf("Decoder.Decode() warnings = %v, want %d", d.Warnings, tt.wantWarns)
+			}
+			if tt.wantWarns > 0 {
+				var e *specerr.Error
+				if !errors.Is(d.Warnings[0], ErrAttachmentTooLarge) || !errors.As(d.Warnings[0], &e) || e.Path != "/3D/big.bin" {
+					t.Errorf("Decoder.Decode() warning = %v, want %v", d.Warnings[0], ErrAttachmentTooLarge)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoder_Decode_Shallow(t *testing.T) {
+	mesh := &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+	}
+	m := &Model{
+		Resources: Resources{Objects: []*Object{{ID: 1, Mesh: mesh}}},
+		Build:     Build{Items: []*Item{{ObjectID: 1}}},
+		Childs: map[string]*ChildModel{"/3D/other.model": {
+			Resources: Resources{Objects: []*Object{{ID: 1, Mesh: mesh}}},
+		}},
+	}
+	buff := new(bytes.Buffer)
+	if err := NewEncoder(buff).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	d.Shallow = true
+	got := new(Model)
+	if err := d.Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	want := &Model{
+		Path:      DefaultModelPath,
+		Resources: m.Resources,
+		Build:     m.Build,
+		Childs:    map[string]*ChildModel{"/3D/other.model": {}},
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("Decoder.Decode() = %v", diff)
+	}
+}
+
+func TestDecoder_DecodeStream(t *testing.T) {
+	m := &Model{
+		Metadata: []Metadata{{Name: xml.Name{Local: "Title"}, Value: "cube"}},
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, PID: 2, PIndex: 1, Mesh: &Mesh{
+				Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}},
+				Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 3, PID: 2, P1: 0, P2: 0, P3: 0}}},
+			}},
+			{ID: 3, Components: &Components{Component: []*Component{{ObjectID: 1}}}},
+		}},
+		Build: Build{Items: []*Item{{ObjectID: 3}}},
+		Childs: map[string]*ChildModel{"/3D/other.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: &Mesh{
+			Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {2, 0, 0}, {0, 2, 0}}},
+			Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+		}}}}}},
+	}
+	buff := new(bytes.Buffer)
+	if err := NewEncoder(buff).Encode(m); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	var (
+		mu        sync.Mutex
+		objects   []uint32
+		vertices  []Point3D
+		triangles []Triangle
+		metadata  []Metadata
+	)
+	d := NewDecoder(bytes.NewReader(buff.Bytes()), int64(buff.Len()))
+	err := d.DecodeStream(context.Background(), StreamHandlers{
+		OnObject: func(o *Object) {
+			mu.Lock()
+			defer mu.Unlock()
+			if o.Mesh != nil && (len(o.Mesh.Vertices.Vertex) != 0 || len(o.Mesh.Triangles.Triangle) != 0) {
+				t.Errorf("Decoder.DecodeStream() object %d mesh is not empty", o.ID)
+			}
+			objects = append(objects, o.ID)
+		},
+		OnVertex: func(v Point3D) {
+			mu.Lock()
+			defer mu.Unlock()
+			vertices = append(vertices, v)
+		},
+		OnTriangle: func(tr Triangle) {
+			mu.Lock()
+			defer mu.Unlock()
+			triangles = append(triangles, tr)
+		},
+		OnMetadata: func(md Metadata) { metadata = append(metadata, md) },
+	})
+	if err != nil {
+		t.Fatalf("Decoder.DecodeStream() error = %v", err)
+	}
+	wantVertices := append(append([]Point3D{}, m.Childs["/3D/other.model"].Resources.Objects[0].Mesh.Vertices.Vertex...),
+		m.Resources.Objects[0].Mesh.Vertices.Vertex...)
+	wantTriangles := []Triangle{{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 1, V3: 2, PID: 2, P1: 1, P2: 1, P3: 1}, {V1: 0, V2: 1, V3: 3, PID: 2}}
+	if diff := deep.Equal(objects, []uint32{1, 1, 3}); diff != nil {
+		t.Errorf("Decoder.DecodeStream() objects = %v", diff)
+	}
+	if diff := deep.Equal(vertices, wantVertices); diff != nil {
+		t.Errorf("Decoder.DecodeStream() vertices = %v", diff)
+	}
+	if diff := deep.Equal(triangles, wantTriangles); diff != nil {
+		t.Errorf("Decoder.DecodeStream() triangles = %v", diff)
+	}
+	if diff := deep.Equal(metadata, m.Metadata); diff != nil {
+		t.Errorf("Decoder.DecodeStream() metadata = %v", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.DecodeStream(ctx, StreamHandlers{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Decoder.DecodeStream() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,397 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"math"
+)
+
+// RepairOptions configures the steps run by Mesh.Repair.
+// The zero value runs every step welding only identical vertices
+// and filling holes of any size.
+type RepairOptions struct {
+	// SkipWeld disables merging duplicated vertices.
+	SkipWeld bool
+	// WeldTolerance is the maximum distance between two vertices
+	// to be merged. Zero only merges vertices with identical coordinates.
+	WeldTolerance float32
+	// SkipCleanup disables removing degenerate and duplicated triangles.
+	SkipCleanup bool
+	// SkipHoleFilling disables closing the holes of the mesh.
+	SkipHoleFilling bool
+	// MaxHoleEdges is the maximum number of boundary edges of a hole
+	// to be filled. Zero fills holes of any size.
+	MaxHoleEdges int
+	// SkipOrientation disables making the triangles winding
+	// consistent and pointing outwards.
+	SkipOrientation bool
+}
+
+// RepairReport summarizes the changes done by Mesh.Repair.
+type RepairReport struct {
+	WeldedVertices      int
+	DegenerateTriangles int
+	DuplicateTriangles  int
+	FilledHoles         int
+	SkippedHoles        int
+	AddedTriangles      int
+	FlippedTriangles    int
+}
+
+// Repair fixes the most common mesh defects and reports what changed.
+// The steps are run in this order, each one can be disabled in opts:
+//   - Weld: vertices closer than opts.WeldTolerance are merged into the first one.
+//     Vertices not referenced by any triangle are kept.
+//   - Cleanup: triangles with repeated vertices or zero area are removed,
+//     as well as triangles using the same vertices as a previous one.
+//   - Orientation: the winding of each connected patch of triangles is made consistent.
+//   - Hole filling: closed loops of boundary edges are filled with a triangle fan
+//     following the winding of the neighboring triangles.
+//     Holes with more than opts.MaxHoleEdges edges are left open.
+//   - Orientation: the triangles of each connected part are flipped if
+//     they enclose a negative volume, so their normals point outwards.
+//
+// Triangles referencing out of range vertices are always removed
+// and reported as degenerate. Added triangles have no properties.
+// Extension elements referencing vertex indices, such as beams, are not updated.
+func (m *Mesh) Repair(opts RepairOptions) RepairReport {
+	var report RepairReport
+	report.DegenerateTriangles += m.removeOutOfRangeTriangles()
+	if !opts.SkipWeld {
+		report.WeldedVertices = m.weldVertices(opts.WeldTolerance)
+	}
+	if !opts.SkipCleanup {
+		report.DegenerateTriangles += m.removeDegenerateTriangles()
+		report.DuplicateTriangles = m.removeDuplicateTriangles()
+	}
+	var flipped []bool
+	if !opts.SkipOrientation {
+		flipped = m.orientConsistently()
+	}
+	if !opts.SkipHoleFilling {
+		report.FilledHoles, report.SkippedHoles, report.AddedTriangles = m.fillHoles(opts.MaxHoleEdges)
+	}
+	if !opts.SkipOrientation {
+		flipped = append(flipped, make([]bool, len(m.Triangles.Triangle)-len(flipped))...)
+		m.orientOutwards(flipped)
+		for _, f := range flipped {
+			if f {
+				report.FlippedTriangles++
+			}
+		}
+	}
+	return report
+}
+
+func (t *Triangle) flip() {
+	t.V2, t.V3 = t.V3, t.V2
+	t.P2, t.P3 = t.P3, t.P2
+}
+
+func (m *Mesh) filterTriangles(keep func(Triangle) bool) int {
+	triangles := m.Triangles.Triangle[:0]
+	for _, t := range m.Triangles.Triangle {
+		if keep(t) {
+			triangles = append(triangles, t)
+		}
+	}
+	removed := len(m.Triangles.Triangle) - len(triangles)
+	m.Triangles.Triangle = triangles
+	return removed
+}
+
+func (m *Mesh) removeOutOfRangeTriangles() int {
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	return m.filterTriangles(func(t Triangle) bool {
+		return t.V1 < nodeCount && t.V2 < nodeCount && t.V3 < nodeCount
+	})
+}
+
+// WeldVertices merges the vertices closer than tolerance into the first one,
+// updates the triangles to reference the kept vertices and returns
+// the number of removed vertices. It is a no-op if tolerance is not positive.
+//
+// Triangles left with repeated vertices are removed, as well as triangles
+// referencing out of range vertices. The properties of the remaining
+// triangles are kept. Vertices not referenced by any triangle are kept.
+// Extension elements referencing vertex indices, such as beams, are not updated.
+func (m *Mesh) WeldVertices(tolerance float32) int {
+	if tolerance <= 0 {
+		return 0
+	}
+	return m.weld(tolerance)
+}
+
+// weld removes the triangles referencing out of range vertices, merges the
+// vertices as in weldVertices and removes the triangles left with repeated vertices.
+func (m *Mesh) weld(tolerance float32) int {
+	m.removeOutOfRangeTriangles()
+	welded := m.weldVertices(tolerance)
+	if welded > 0 {
+		m.filterTriangles(func(t Triangle) bool {
+			return t.V1 != t.V2 && t.V2 != t.V3 && t.V1 != t.V3
+		})
+	}
+	return welded
+}
+
+// weldVertices merges the vertices closer than tolerance,
+// keeping the first one, and returns the number of removed vertices.
+func (m *Mesh) weldVertices(tolerance float32) int {
+	type cell [3]int64
+	cellOf := func(p Point3D) cell {
+		if tolerance <= 0 {
+			return cell{int64(math.Float32bits(p.X())), int64(math.Float32bits(p.Y())), int64(math.Float32bits(p.Z()))}
+		}
+		tol := float64(tolerance)
+		return cell{
+			int64(math.Floor(float64(p.X()) / tol)),
+			int64(math.Floor(float64(p.Y()) / tol)),
+			int64(math.Floor(float64(p.Z()) / tol)),
+		}
+	}
+	grid := make(map[cell][]uint32)
+	remap := make([]uint32, len(m.Vertices.Vertex))
+	vertices := make([]Point3D, 0, len(m.Vertices.Vertex))
+	find := func(p Point3D) (uint32, bool) {
+		c := cellOf(p)
+		if tolerance <= 0 {
+			if ids, ok := grid[c]; ok {
+				return ids[0], true
+			}
+			return 0, false
+		}
+		pv := newVec3(p)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					for _, id := range grid[cell{c[0] + dx, c[1] + dy, c[2] + dz}] {
+						if newVec3(vertices[id]).sub(pv).len() <= float64(tolerance) {
+							return id, true
+						}
+					}
+				}
+			}
+		}
+		return 0, false
+	}
+	for i, v := range m.Vertices.Vertex {
+		if id, ok := find(v); ok {
+			remap[i] = id
+			continue
+		}
+		id := uint32(len(vertices))
+		remap[i] = id
+		vertices = append(vertices, v)
+		c := cellOf(v)
+		grid[c] = append(grid[c], id)
+	}
+	welded := len(m.Vertices.Vertex) - len(vertices)
+	if welded == 0 {
+		return 0
+	}
+	for i := range m.Triangles.Triangle {
+		t := &m.Triangles.Triangle[i]
+		t.V1, t.V2, t.V3 = remap[t.V1], remap[t.V2], remap[t.V3]
+	}
+	m.Vertices.Vertex = vertices
+	return welded
+}
+
+func (m *Mesh) removeDegenerateTriangles() int {
+	return m.filterTriangles(func(t Triangle) bool {
+		if t.V1 == t.V2 || t.V2 == t.V3 || t.V1 == t.V3 {
+			return false
+		}
+		a := newVec3(m.Vertices.Vertex[t.V1])
+		n := newVec3(m.Vertices.Vertex[t.V2]).sub(a).cross(newVec3(m.Vertices.Vertex[t.V3]).sub(a))
+		return n.len() > 0
+	})
+}
+
+func (m *Mesh) removeDuplicateTriangles() int {
+	seen := make(map[[3]uint32]struct{}, len(m.Triangles.Triangle))
+	return m.filterTriangles(func(t Triangle) bool {
+		key := t.vertices()
+		if key[0] > key[1] {
+			key[0], key[1] = key[1], key[0]
+		}
+		if key[1] > key[2] {
+			key[1], key[2] = key[2], key[1]
+		}
+		if key[0] > key[1] {
+			key[0], key[1] = key[1], key[0]
+		}
+		if _, ok := seen[key]; ok {
+			return false
+		}
+		seen[key] = struct{}{}
+		return true
+	})
+}
+
+type edgeKey [2]uint32
+
+func newEdgeKey(a, b uint32) edgeKey {
+	if a > b {
+		a, b = b, a
+	}
+	return edgeKey{a, b}
+}
+
+func (t Triangle) vertices() [3]uint32 {
+	return [3]uint32{t.V1, t.V2, t.V3}
+}
+
+// forward reports whether t traverses the edge from its lower to its higher vertex.
+func (t Triangle) forward(e edgeKey) bool {
+	v := t.vertices()
+	for j := 0; j < 3; j++ {
+		if v[j] == e[0] && v[(j+1)%3] == e[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// orientConsistently flips triangles so the ones sharing a manifold edge
+// traverse it in opposite directions, and returns which ones were flipped.
+func (m *Mesh) orientConsistently() []bool {
+	triangles := m.Triangles.Triangle
+	edges := make(map[edgeKey][]int)
+	for i, t := range triangles {
+		v := t.vertices()
+		for j := 0; j < 3; j++ {
+			e := newEdgeKey(v[j], v[(j+1)%3])
+			edges[e] = append(edges[e], i)
+		}
+	}
+	flipped := make([]bool, len(triangles))
+	visited := make([]bool, len(triangles))
+	for seed := range triangles {
+		if visited[seed] {
+			continue
+		}
+		visited[seed] = true
+		queue := []int{seed}
+		for len(queue) > 0 {
+			i := queue[0]
+			queue = queue[1:]
+			v := triangles[i].vertices()
+			for j := 0; j < 3; j++ {
+				e := newEdgeKey(v[j], v[(j+1)%3])
+				shared := edges[e]
+				if len(shared) != 2 {
+					continue
+				}
+				n := shared[0]
+				if n == i {
+					n = shared[1]
+				}
+				if visited[n] {
+					continue
+				}
+				visited[n] = true
+				if triangles[i].forward(e) == triangles[n].forward(e) {
+					triangles[n].flip()
+					flipped[n] = true
+				}
+				queue = append(queue, n)
+			}
+		}
+	}
+	return flipped
+}
+
+// fillHoles closes the loops of boundary edges with a triangle fan.
+func (m *Mesh) fillHoles(maxEdges int) (filled, skipped, added int) {
+	directed := make(map[edgeKey]int)
+	for _, t := range m.Triangles.Triangle {
+		v := t.vertices()
+		for j := 0; j < 3; j++ {
+			directed[edgeKey{v[j], v[(j+1)%3]}]++
+		}
+	}
+	var boundary []edgeKey
+	next := make(map[uint32][]uint32)
+	for _, t := range m.Triangles.Triangle {
+		v := t.vertices()
+		for j := 0; j < 3; j++ {
+			a, b := v[j], v[(j+1)%3]
+			if directed[edgeKey{a, b}] == 1 && directed[edgeKey{b, a}] == 0 {
+				boundary = append(boundary, edgeKey{a, b})
+				next[a] = append(next[a], b)
+			}
+		}
+	}
+	used := make(map[edgeKey]bool, len(boundary))
+	for _, start := range boundary {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		loop := []uint32{start[0]}
+		closed := true
+		for cur := start[1]; cur != start[0]; {
+			var to uint32
+			found := false
+			for _, b := range next[cur] {
+				if !used[edgeKey{cur, b}] {
+					to, found = b, true
+					break
+				}
+			}
+			if !found {
+				closed = false
+				break
+			}
+			used[edgeKey{cur, to}] = true
+			loop = append(loop, cur)
+			cur = to
+		}
+		if !closed || len(loop) < 3 || (maxEdges > 0 && len(loop) > maxEdges) {
+			skipped++
+			continue
+		}
+		for i := 1; i < len(loop)-1; i++ {
+			m.Triangles.Triangle = append(m.Triangles.Triangle, Triangle{V1: loop[0], V2: loop[i+1], V3: loop[i]})
+			added++
+		}
+		filled++
+	}
+	return
+}
+
+// orientOutwards flips the connected parts of the mesh enclosing
+// a negative volume, toggling the flipped state of their triangles.
+func (m *Mesh) orientOutwards(flipped []bool) {
+	parent := make([]int, len(m.Vertices.Vertex))
+	for i := range parent {
+		parent[i] = i
+	}
+	root := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	for _, t := range m.Triangles.Triangle {
+		a := root(int(t.V1))
+		parent[root(int(t.V2))] = a
+		parent[root(int(t.V3))] = a
+	}
+	volumes := make(map[int]float64)
+	for _, t := range m.Triangles.Triangle {
+		a, b, c := newVec3(m.Vertices.Vertex[t.V1]), newVec3(m.Vertices.Vertex[t.V2]), newVec3(m.Vertices.Vertex[t.V3])
+		volumes[root(int(t.V1))] += a.dot(b.cross(c))
+	}
+	for i := range m.Triangles.Triangle {
+		t := &m.Triangles.Triangle[i]
+		if volumes[root(int(t.V1))] < 0 {
+			t.flip()
+			flipped[i] = !flipped[i]
+		}
+	}
+}
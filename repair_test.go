@@ -0,0 +1,132 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newBrokenCube returns a unit cube whose triangles do not share vertices,
+// with one missing triangle, one flipped, a degenerate one and a duplicated one.
+func newBrokenCube() *Mesh {
+	cube := newGridCube(1, 1)
+	m := new(Mesh)
+	for i, t := range cube.Triangles.Triangle {
+		if i == 0 {
+			continue
+		}
+		n := uint32(len(m.Vertices.Vertex))
+		m.Vertices.Vertex = append(m.Vertices.Vertex,
+			cube.Vertices.Vertex[t.V1], cube.Vertices.Vertex[t.V2], cube.Vertices.Vertex[t.V3])
+		t.V1, t.V2, t.V3 = n, n+1, n+2
+		if i == 5 {
+			t.flip()
+		}
+		m.Triangles.Triangle = append(m.Triangles.Triangle, t)
+	}
+	m.Triangles.Triangle = append(m.Triangles.Triangle,
+		Triangle{V1: 0, V2: 0, V3: 1}, m.Triangles.Triangle[2])
+	return m
+}
+
+func TestMesh_Repair(t *testing.T) {
+	tests := []struct {
+		name       string
+		m          *Mesh
+		opts       RepairOptions
+		want       RepairReport
+		wantVert   int
+		wantTri    int
+		wantClosed bool
+	}{
+		{"empty", new(Mesh), RepairOptions{}, RepairReport{}, 0, 0, false},
+		{"closed", newGridCube(2, 1), RepairOptions{}, RepairReport{}, 26, 48, true},
+		{"inverted", func() *Mesh { m := newGridCube(2, 1); m.flipWinding(); return m }(), RepairOptions{},
+			RepairReport{FlippedTriangles: 48}, 26, 48, true},
+		{"outOfRange", &Mesh{Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}}}, RepairOptions{},
+			RepairReport{DegenerateTriangles: 1}, 0, 0, false},
+		{"broken", newBrokenCube(), RepairOptions{}, RepairReport{
+			WeldedVertices: 25, DegenerateTriangles: 1, DuplicateTriangles: 1,
+			FilledHoles: 1, AddedTriangles: 1, FlippedTriangles: 1,
+		}, 8, 12, true},
+		{"maxHoleEdges", newBrokenCube(), RepairOptions{MaxHoleEdges: 2}, RepairReport{
+			WeldedVertices: 25, DegenerateTriangles: 1, DuplicateTriangles: 1,
+			SkippedHoles: 1, FlippedTriangles: 1,
+		}, 8, 11, false},
+		{"skipAll", newBrokenCube(), RepairOptions{SkipWeld: true, SkipCleanup: true, SkipHoleFilling: true, SkipOrientation: true},
+			RepairReport{}, 33, 13, false},
+		{"tolerance", func() *Mesh {
+			m := newGridCube(1, 1)
+			m.Vertices.Vertex = append(m.Vertices.Vertex, Point3D{1e-3, 0, 0})
+			m.Triangles.Triangle[0].V1 = uint32(len(m.Vertices.Vertex) - 1)
+			return m
+		}(), RepairOptions{WeldTolerance: 1e-2}, RepairReport{WeldedVertices: 1}, 8, 12, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Repair(tt.opts); got != tt.want {
+				t.Errorf("Mesh.Repair() = %+v, want %+v", got, tt.want)
+			}
+			if len(tt.m.Vertices.Vertex) != tt.wantVert || len(tt.m.Triangles.Triangle) != tt.wantTri {
+				t.Errorf("Mesh.Repair() = %d vertices %d triangles, want %d %d",
+					len(tt.m.Vertices.Vertex), len(tt.m.Triangles.Triangle), tt.wantVert, tt.wantTri)
+			}
+			if err := tt.m.ValidateCoherency(); (err == nil) != tt.wantClosed {
+				t.Errorf("Mesh.Repair() coherency = %v, want closed %v", err, tt.wantClosed)
+			}
+			if tt.wantClosed {
+				var volume float64
+				for _, tri := range tt.m.Triangles.Triangle {
+					a, b, c := newVec3(tt.m.Vertices.Vertex[tri.V1]), newVec3(tt.m.Vertices.Vertex[tri.V2]), newVec3(tt.m.Vertices.Vertex[tri.V3])
+					volume += a.dot(b.cross(c)) / 6
+				}
+				if volume <= 0 {
+					t.Errorf("Mesh.Repair() volume = %v, want positive", volume)
+				}
+			}
+		})
+	}
+}
+
+func TestMesh_WeldVertices(t *testing.T) {
+	newMesh := func() *Mesh {
+		return &Mesh{
+			Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1e-3, 0, 0}, {1, 1e-3, 0}, {5, 5, 5}}},
+			Triangles: Triangles{Triangle: []Triangle{
+				{V1: 0, V2: 2, V3: 1, PID: 1, P1: 0, P2: 1, P3: 2},
+				{V1: 4, V2: 1, V3: 3, PID: 1, P1: 3, P2: 3, P3: 3},
+				{V1: 0, V2: 4, V3: 3},
+				{V1: 1, V2: 5, V3: 9},
+			}},
+		}
+	}
+	tests := []struct {
+		name      string
+		tolerance float32
+		want      int
+		wantMesh  *Mesh
+	}{
+		{"zero", 0, 0, newMesh()},
+		{"negative", -1, 0, newMesh()},
+		{"tolerance", 1e-2, 2, &Mesh{
+			Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {5, 5, 5}}},
+			Triangles: Triangles{Triangle: []Triangle{
+				{V1: 0, V2: 2, V3: 1, PID: 1, P1: 0, P2: 1, P3: 2},
+				{V1: 0, V2: 1, V3: 3, PID: 1, P1: 3, P2: 3, P3: 3},
+			}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMesh()
+			if got := m.WeldVertices(tt.tolerance); got != tt.want {
+				t.Errorf("Mesh.WeldVertices() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(m, tt.wantMesh) {
+				t.Errorf("Mesh.WeldVertices() = %v, want %v", m, tt.wantMesh)
+			}
+		})
+	}
+}
@@ -52,6 +52,17 @@ type Spec interface {
 // Validate and aggregate the resulting erros.
 //
 // model is guaranteed to be a *go3mf.Model
+//
+// A spec is only called for models that declare its namespace in
+// their extensions. Validate is called once with the model as element
+// and the root path as the model Path, which may be empty.
+// Then it is called for each asset and for each object, once the core
+// checks of that element are done, with the path of the model part
+// owning it. Child parts are visited in ascending path order before
+// the root part, and resources are visited in document order.
+//
+// The returned errors are appended to the core errors of the same element,
+// so they end up wrapped with the same XPath.
 type ValidateSpec interface {
 	Spec
 	Validate(model interface{}, path string, element interface{}) error
@@ -76,3 +76,18 @@ func ParseMatrix(s string) ([16]float32, bool) {
 		t[6], t[7], t[8], 0.0,
 		t[9], t[10], t[11], 1.0}, true
 }
+
+// FormatMatrix returns m as the 12 space separated values of a 3MF transform,
+// which is the inverse of ParseMatrix. The values use the shortest
+// representation that parses back to the same float32,
+// so ParseMatrix(FormatMatrix(m)) returns m for any affine matrix.
+func FormatMatrix(m [16]float32) string {
+	var b strings.Builder
+	for i, idx := range [12]int{0, 1, 2, 4, 5, 6, 8, 9, 10, 12, 13, 14} {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.FormatFloat(float64(m[idx]), 'f', -1, 32))
+	}
+	return b.String()
+}
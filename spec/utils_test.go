@@ -89,3 +89,27 @@ func TestParseMatrix(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatMatrix(t *testing.T) {
+	tests := []struct {
+		name string
+		m    [16]float32
+		want string
+	}{
+		{"identity", [16]float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}, "1 0 0 0 1 0 0 0 1 0 0 0"},
+		{"other", [16]float32{0, 1, 2, 0, 10, 11, 12, 0, 20, 21, 22, 0, 30, 31, 32, 1}, "0 1 2 10 11 12 20 21 22 30 31 32"},
+		{"precision", [16]float32{0.70710677, -0.70710677, 0, 0, 0.70710677, 0.70710677, 0, 0, 0, 0, 1, 0, -66.4, 0.0001, 1e-7, 1},
+			"0.70710677 -0.70710677 0 0.70710677 0.70710677 0 0 0 1 -66.4 0.0001 0.0000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatMatrix(tt.m)
+			if got != tt.want {
+				t.Errorf("FormatMatrix() = %v, want %v", got, tt.want)
+			}
+			if m, ok := ParseMatrix(got); !ok || m != tt.m {
+				t.Errorf("ParseMatrix(FormatMatrix()) = %v, want %v", m, tt.m)
+			}
+		})
+	}
+}
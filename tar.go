@@ -0,0 +1,237 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// The tar transport stores the package parts as the entries of a tar stream,
+// named after the part name without the leading slash. The first entry is a
+// manifest with the content type and the relationships of every part and the
+// package relationships, which an OPC package stores in dedicated parts.
+//
+// It is not a standard 3MF packaging, it is only meant to move models between
+// tools that agree on it without zipping and unzipping them.
+const tarManifestName = "[Manifest].xml"
+
+// ErrTarManifest is returned when decoding a tar stream
+// whose first entry is not a valid manifest.
+var ErrTarManifest = errors.New("go3mf: tar stream does not start with a valid manifest")
+
+type tarManifest struct {
+	XMLName       xml.Name          `xml:"Manifest"`
+	Relationships []tarRelationship `xml:"Relationship"`
+	Parts         []tarManifestPart `xml:"Part"`
+}
+
+type tarManifestPart struct {
+	Name          string            `xml:",attr"`
+	ContentType   string            `xml:",attr"`
+	Relationships []tarRelationship `xml:"Relationship"`
+}
+
+type tarRelationship struct {
+	ID     string `xml:"Id,attr,omitempty"`
+	Type   string `xml:",attr"`
+	Target string `xml:",attr"`
+}
+
+func newTarRelationship(r Relationship) tarRelationship {
+	return tarRelationship{ID: r.ID, Type: r.Type, Target: r.Path}
+}
+
+func (r tarRelationship) relationship() Relationship {
+	return Relationship{ID: r.ID, Type: r.Type, Path: r.Target}
+}
+
+func addTarRelationship(rels []tarRelationship, r Relationship) []tarRelationship {
+	for _, ro := range rels {
+		if ro.Type == r.Type && ro.Target == r.Path {
+			return rels
+		}
+	}
+	return append(rels, newTarRelationship(r))
+}
+
+// WriteTar writes m to w using the tar transport instead of an OPC package.
+// The output is not a valid 3MF file, it can only be read by NewTarDecoder.
+// The encoder FloatPrecision, Comment and ModTime are honored.
+func (e *Encoder) WriteTar(w io.Writer, m *Model) error {
+	pw := e.w
+	defer func() { e.w = pw }()
+	modTime := e.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	e.w = &tarWriter{w: w, modTime: modTime}
+	return e.Encode(m)
+}
+
+type tarPart struct {
+	bytes.Buffer
+	manifest tarManifestPart
+}
+
+func (p *tarPart) AddRelationship(r Relationship) {
+	p.manifest.Relationships = addTarRelationship(p.manifest.Relationships, r)
+}
+
+// tarWriter buffers the parts until Close,
+// as the manifest has to be written first.
+type tarWriter struct {
+	w        io.Writer
+	modTime  time.Time
+	manifest tarManifest
+	parts    []*tarPart
+}
+
+func (t *tarWriter) Create(name, contentType string) (packagePart, error) {
+	name = normalizePartName(name)
+	for _, p := range t.parts {
+		if strings.EqualFold(p.manifest.Name, name) {
+			return nil, errors.New("go3mf: duplicated part name " + name)
+		}
+	}
+	p := &tarPart{manifest: tarManifestPart{Name: name, ContentType: contentType}}
+	t.parts = append(t.parts, p)
+	return p, nil
+}
+
+func (t *tarWriter) AddRelationship(r Relationship) {
+	t.manifest.Relationships = addTarRelationship(t.manifest.Relationships, r)
+}
+
+func (t *tarWriter) Close() error {
+	for _, p := range t.parts {
+		t.manifest.Parts = append(t.manifest.Parts, p.manifest)
+	}
+	manifest, err := xml.Marshal(&t.manifest)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(t.w)
+	if err := t.writeEntry(tw, tarManifestName, append([]byte(xml.Header), manifest...)); err != nil {
+		return err
+	}
+	for _, p := range t.parts {
+		if err := t.writeEntry(tw, strings.TrimPrefix(p.manifest.Name, "/"), p.Bytes()); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func (t *tarWriter) writeEntry(tw *tar.Writer, name string, b []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg, Name: name, Mode: 0644, Size: int64(len(b)), ModTime: t.modTime,
+	})
+	if err == nil {
+		_, err = tw.Write(b)
+	}
+	return err
+}
+
+// NewTarDecoder returns a new Decoder reading a model
+// written with Encoder.WriteTar from r.
+// The whole stream is read into memory when decoding.
+func NewTarDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		p:                   &tarReader{r: r},
+		Strict:              true,
+		MaxComponentDepth:   DefaultMaxComponentDepth,
+		CancelCheckInterval: DefaultCancelCheckInterval,
+	}
+}
+
+type tarReader struct {
+	r        io.Reader
+	manifest tarManifest
+	data     map[string][]byte
+}
+
+func (t *tarReader) Open(func(r io.Reader) io.ReadCloser) error {
+	tr := tar.NewReader(t.r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return err
+	}
+	if hdr.Name != tarManifestName {
+		return ErrTarManifest
+	}
+	if err = xml.NewDecoder(tr).Decode(&t.manifest); err != nil {
+		return ErrTarManifest
+	}
+	t.data = make(map[string][]byte, len(t.manifest.Parts))
+	for {
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		t.data[normalizePartName(hdr.Name)] = b
+	}
+}
+
+func (t *tarReader) Relationships() []Relationship {
+	return newTarRelationships(t.manifest.Relationships)
+}
+
+func (t *tarReader) FindFileFromName(name string) (packageFile, bool) {
+	return t.find(resolveRelationship("/", name))
+}
+
+func (t *tarReader) find(name string) (packageFile, bool) {
+	name = normalizePartName(name)
+	for i := range t.manifest.Parts {
+		p := &t.manifest.Parts[i]
+		if p.Name == name {
+			if _, ok := t.data[name]; ok {
+				return &tarFile{r: t, part: p}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func newTarRelationships(rels []tarRelationship) []Relationship {
+	pr := make([]Relationship, len(rels))
+	for i, r := range rels {
+		pr[i] = r.relationship()
+	}
+	return pr
+}
+
+type tarFile struct {
+	r    *tarReader
+	part *tarManifestPart
+}
+
+func (f *tarFile) Name() string        { return f.part.Name }
+func (f *tarFile) ContentType() string { return f.part.ContentType }
+
+func (f *tarFile) FindFileFromName(name string) (packageFile, bool) {
+	return f.r.find(resolveRelationship(f.part.Name, name))
+}
+
+func (f *tarFile) Relationships() []Relationship {
+	return newTarRelationships(f.part.Relationships)
+}
+
+func (f *tarFile) Open() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(f.r.data[f.part.Name])), nil
+}
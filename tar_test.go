@@ -0,0 +1,90 @@
+// © Copyright 2021 HP Development Company, L.P.
+// SPDX-License Identifier: BSD-2-Clause
+
+package go3mf
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestEncoder_WriteTar(t *testing.T) {
+	newModel := func() *Model {
+		return &Model{
+			Path:  "/3D/root.model",
+			Units: UnitInch,
+			Resources: Resources{Objects: []*Object{{ID: 1, Mesh: &Mesh{
+				Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+				Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+			}}}},
+			Build: Build{Items: []*Item{{ObjectID: 1}}},
+			RootRelationships: []Relationship{
+				{Path: "/Metadata/thumbnail.png", Type: RelTypeThumbnail, ID: "1"},
+			},
+			Relationships: []Relationship{{Path: "/3D/Metadata/pt.xml", Type: RelTypePrintTicket, ID: "2"}},
+			Attachments: []Attachment{
+				{ContentType: "image/png", Path: "/Metadata/thumbnail.png", Stream: bytes.NewBufferString("fake")},
+				{ContentType: ContentTypePrintTicket, Path: "/3D/Metadata/pt.xml", Stream: bytes.NewBufferString("other")},
+			},
+			Childs: map[string]*ChildModel{"/3D/other.model": {Resources: Resources{Objects: []*Object{{ID: 1, Mesh: &Mesh{
+				Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+				Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+			}}}}}},
+		}
+	}
+	zipBuff := new(bytes.Buffer)
+	if err := NewEncoder(zipBuff).Encode(newModel()); err != nil {
+		t.Fatalf("Encoder.Encode() error = %v", err)
+	}
+	want := new(Model)
+	if err := NewDecoder(bytes.NewReader(zipBuff.Bytes()), int64(zipBuff.Len())).Decode(want); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+
+	tarBuff := new(bytes.Buffer)
+	e := NewEncoder(nil)
+	e.ModTime = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := e.WriteTar(tarBuff, newModel()); err != nil {
+		t.Fatalf("Encoder.WriteTar() error = %v", err)
+	}
+	tr := tar.NewReader(bytes.NewReader(tarBuff.Bytes()))
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+		if !hdr.ModTime.Equal(e.ModTime) {
+			t.Errorf("Encoder.WriteTar() %s ModTime = %v, want %v", hdr.Name, hdr.ModTime, e.ModTime)
+		}
+	}
+	wantNames := []string{tarManifestName, "Metadata/thumbnail.png", "3D/Metadata/pt.xml", "3D/root.model", "3D/other.model"}
+	if diff := deep.Equal(names, wantNames); diff != nil {
+		t.Errorf("Encoder.WriteTar() entries = %v", diff)
+	}
+
+	got := new(Model)
+	if err := NewTarDecoder(bytes.NewReader(tarBuff.Bytes())).Decode(got); err != nil {
+		t.Fatalf("Decoder.Decode() error = %v", err)
+	}
+	if diff := deep.Equal(got, want); diff != nil {
+		t.Errorf("NewTarDecoder() = %v", diff)
+	}
+}
+
+func TestNewTarDecoder_Error(t *testing.T) {
+	buff := new(bytes.Buffer)
+	tw := tar.NewWriter(buff)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "3D/3dmodel.model", Size: 0})
+	tw.Close()
+	err := NewTarDecoder(bytes.NewReader(buff.Bytes())).Decode(new(Model))
+	if !errors.Is(err, ErrTarManifest) || !errors.Is(err, ErrNotAPackage) {
+		t.Errorf("Decoder.Decode() error = %v, want %v", err, ErrTarManifest)
+	}
+}
@@ -33,8 +33,14 @@ func SetRand(r io.Reader) {
 // The strength of the UUIDs is based on the strength of the crypto/rand
 // package.
 func New() string {
+	return NewFromReader(rander)
+}
+
+// NewFromReader returns a Random (Version 4) UUID whose random bits
+// are read from r. It panics if r.Read returns an error.
+func NewFromReader(r io.Reader) string {
 	var uuid [16]byte
-	_, err := io.ReadFull(rander, uuid[:])
+	_, err := io.ReadFull(r, uuid[:])
 	if err != nil {
 		panic(err)
 	}
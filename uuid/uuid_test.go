@@ -4,6 +4,7 @@
 package uuid
 
 import (
+	"bytes"
 	"crypto/rand"
 	"io"
 	"testing"
@@ -25,6 +26,13 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewFromReader(t *testing.T) {
+	r := bytes.NewReader(bytes.Repeat([]byte{0xff}, 16))
+	if got, want := NewFromReader(r), "ffffffff-ffff-4fff-bfff-ffffffffffff"; got != want {
+		t.Errorf("NewFromReader() = %s, want %s", got, want)
+	}
+}
+
 type test struct {
 	in     string
 	isuuid bool
@@ -5,6 +5,7 @@ package go3mf
 
 import (
 	"encoding/xml"
+	"fmt"
 	"image/color"
 	"sort"
 	"strings"
@@ -27,6 +28,13 @@ func (m *Model) sortedChilds() []string {
 }
 
 // Validate checks that the model is conformant with the 3MF specs.
+// The registered specs implementing spec.ValidateSpec are also called,
+// see spec.ValidateSpec for the call order.
+//
+// The core mesh checks report errors.ErrInsufficientVertices and
+// errors.ErrInsufficientTriangles for solid objects and errors.ErrIndexOutOfBounds
+// wrapped with the triangle index. Manifoldness is not checked,
+// use ValidateCoherency for that.
 func (m *Model) Validate() error {
 	var errs error
 	errs = errors.Append(errs, validateRelationship(m, m.RootRelationships, ""))
@@ -115,9 +123,10 @@ func (m *Metadata) validate(model *Model) error {
 			errs = errors.Append(errs, errors.ErrMetadataName)
 		}
 	} else {
+		// The decoder sets the declared prefix as the name space.
 		var hasExt bool
 		for _, ext := range model.Extensions {
-			if ext.Namespace == m.Name.Space {
+			if ext.Namespace == m.Name.Space || (ext.LocalName != "" && ext.LocalName == m.Name.Space) {
 				hasExt = true
 				break
 			}
@@ -219,7 +228,7 @@ func (r *Object) Validate(m *Model, path string) error {
 			if a, ok := res.FindAsset(r.PID); ok {
 				if a, ok := a.(spec.PropertyGroup); ok {
 					if int(r.PIndex) >= a.Len() {
-						errs = errors.Append(errs, errors.ErrIndexOutOfBounds)
+						errs = errors.Append(errs, &errors.PIndexError{ObjectID: r.ID, PID: r.PID, PIndex: r.PIndex, Len: a.Len()})
 					}
 				}
 			} else {
@@ -326,7 +335,7 @@ func validateRelationship(m *Model, rels []Relationship, path string) error {
 		if r.Path == "" || r.Path[0] != '/' || strings.Contains(r.Path, "/.") {
 			errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCPartName, "relationship", i))
 		} else {
-			if _, ok := findAttachment(m.Attachments, r.Path); !ok {
+			if _, ok := m.FindAttachment(r.Path); !ok {
 				errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCRelTarget, "relationship", i))
 			}
 			if _, ok := visitedParts[partrel{r.Path, r.Type}]; ok {
@@ -336,7 +345,7 @@ func validateRelationship(m *Model, rels []Relationship, path string) error {
 		}
 		switch r.Type {
 		case RelTypePrintTicket:
-			if a, ok := findAttachment(m.Attachments, r.Path); ok {
+			if a, ok := m.FindAttachment(r.Path); ok {
 				if a.ContentType != ContentTypePrintTicket {
 					errs = errors.Append(errs, errors.WrapIndex(errors.ErrOPCContentType, "relationship", i))
 				}
@@ -357,13 +366,144 @@ func validateRelationship(m *Model, rels []Relationship, path string) error {
 	return errs
 }
 
-func findAttachment(att []Attachment, path string) (*Attachment, bool) {
-	for _, a := range att {
-		if strings.EqualFold(a.Path, path) {
-			return &a, true
+// ValidateTransforms checks that the transforms of the build items and
+// components are rigid as defined by Matrix.IsRigid, reporting
+// errors.ErrNonRigidTransform otherwise.
+// It is not part of Validate, as the specs allow any affine transform,
+// but some printers do not handle sheared or mirrored parts.
+func (m *Model) ValidateTransforms() error {
+	var errs error
+	for _, path := range m.sortedChilds() {
+		if err := m.Childs[path].Resources.validateTransforms(); err != nil {
+			errs = errors.Append(errs, errors.WrapPath(err, attrResources, path))
+		}
+	}
+	if err := m.Resources.validateTransforms(); err != nil {
+		errs = errors.Append(errs, errors.Wrap(err, attrResources))
+	}
+	var buildErrs error
+	for i, item := range m.Build.Items {
+		if !item.Transform.IsRigid() {
+			buildErrs = errors.Append(buildErrs, errors.WrapIndex(errors.ErrNonRigidTransform, attrItem, i))
+		}
+	}
+	if buildErrs != nil {
+		errs = errors.Append(errs, errors.Wrap(buildErrs, attrBuild))
+	}
+	if errs != nil {
+		return errors.Wrap(errs, attrModel)
+	}
+	return nil
+}
+
+func (res *Resources) validateTransforms() error {
+	var errs error
+	for i, r := range res.Objects {
+		if r.Components == nil {
+			continue
+		}
+		var cErrs error
+		for j, c := range r.Components.Component {
+			if !c.Transform.IsRigid() {
+				cErrs = errors.Append(cErrs, errors.WrapIndex(errors.ErrNonRigidTransform, attrComponent, j))
+			}
+		}
+		if cErrs != nil {
+			errs = errors.Append(errs, errors.WrapIndex(errors.Wrap(cErrs, attrComponents), attrObject, i))
+		}
+	}
+	return errs
+}
+
+// ValidateOPC checks that the path of the root model, the child models and the
+// attachments are valid OPC part names, reporting an *errors.PartNameError
+// wrapping errors.ErrOPCPartName for each invalid one.
+//
+// Paths are checked as written, so characters that have to be percent-encoded,
+// such as spaces, are reported even if the encoder would escape them,
+// as some consumers do not unescape part names.
+// It is not part of Validate, as the decoder already normalizes the part names it reads.
+func (m *Model) ValidateOPC() error {
+	var errs error
+	if err := validatePartName(m.PathOrDefault()); err != nil {
+		errs = errors.Append(errs, errors.Wrap(err, attrModel))
+	}
+	for _, path := range m.sortedChilds() {
+		if err := validatePartName(path); err != nil {
+			errs = errors.Append(errs, errors.WrapPath(err, attrModel, path))
+		}
+	}
+	for i, a := range m.Attachments {
+		if err := validatePartName(a.Path); err != nil {
+			errs = errors.Append(errs, errors.WrapIndex(err, "attachment", i))
+		}
+	}
+	return errs
+}
+
+// validatePartName checks name against the part name rules
+// of the OPC specification.
+func validatePartName(name string) error {
+	if name == "" {
+		return &errors.PartNameError{Name: name, Reason: "is empty"}
+	}
+	if name[0] != '/' {
+		return &errors.PartNameError{Name: name, Reason: "does not start with a forward slash"}
+	}
+	for _, segment := range strings.Split(name[1:], "/") {
+		if reason := partSegmentError(segment); reason != "" {
+			return &errors.PartNameError{Name: name, Reason: reason}
+		}
+	}
+	return nil
+}
+
+func partSegmentError(segment string) string {
+	if segment == "" {
+		return "has an empty segment"
+	}
+	if segment[len(segment)-1] == '.' {
+		return "has a segment ending with a dot"
+	}
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch {
+		case c == '%':
+			if i+2 >= len(segment) || !isHex(segment[i+1]) || !isHex(segment[i+2]) {
+				return "has an invalid percent-encoding"
+			}
+			decoded := unhex(segment[i+1])<<4 | unhex(segment[i+2])
+			if decoded == '/' || decoded == '\\' || isUnreserved(decoded) {
+				return "percent-encodes a slash, a backslash or an unreserved character"
+			}
+			i += 2
+		case c >= 0x80, isUnreserved(c), strings.IndexByte("!$&'()*+,;=:@", c) >= 0:
+		case c == '\\':
+			return "contains a backslash"
+		default:
+			return fmt.Sprintf("contains the invalid character %q", c)
 		}
 	}
-	return nil, false
+	return ""
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
 }
 
 // ValidateCoherency checks that all the mesh are non-empty, manifold and oriented.
@@ -417,6 +557,28 @@ func isSolidObject(r *Object) bool {
 	return r.Mesh != nil && (r.Type == ObjectTypeModel || r.Type == ObjectTypeSolidSupport)
 }
 
+// ValidateDegenerate checks that the triangles with distinct vertex indices
+// have an area bigger than epsilon, as collinear vertices do not define a normal.
+// The area is computed in the mesh units without applying any transform.
+// Triangles with repeated or out of range indices are not checked,
+// as they are reported by Model.Validate.
+func (m *Mesh) ValidateDegenerate(epsilon float64) error {
+	var errs error
+	nodeCount := uint32(len(m.Vertices.Vertex))
+	for i, t := range m.Triangles.Triangle {
+		if t.V1 == t.V2 || t.V1 == t.V3 || t.V2 == t.V3 ||
+			t.V1 >= nodeCount || t.V2 >= nodeCount || t.V3 >= nodeCount {
+			continue
+		}
+		a := newVec3(m.Vertices.Vertex[t.V1])
+		n := newVec3(m.Vertices.Vertex[t.V2]).sub(a).cross(newVec3(m.Vertices.Vertex[t.V3]).sub(a))
+		if n.len()/2 <= epsilon {
+			errs = errors.Append(errs, errors.WrapIndex(errors.ErrDegenerateTriangle, attrTriangle, i))
+		}
+	}
+	return errs
+}
+
 // ValidateCoherency checks that the mesh is non-empty, manifold and oriented.
 func (m *Mesh) ValidateCoherency() error {
 	if len(m.Vertices.Vertex) < 3 {
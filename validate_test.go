@@ -41,7 +41,7 @@ func TestValidate(t *testing.T) {
 			fmt.Sprintf("go3mf: XPath: /model: %v", errors.ErrRequiredExt),
 		}},
 		{"metadata", &Model{Extensions: []Extension{{Namespace: "fake", LocalName: "f"}}, Metadata: []Metadata{
-			{Name: xml.Name{Space: "fake", Local: "issue"}}, {Name: xml.Name{Space: "f", Local: "issue"}}, {Name: xml.Name{Space: "fake", Local: "issue"}}, {Name: xml.Name{Local: "issue"}}, {},
+			{Name: xml.Name{Space: "fake", Local: "issue"}}, {Name: xml.Name{Space: "g", Local: "issue"}}, {Name: xml.Name{Space: "fake", Local: "issue"}}, {Name: xml.Name{Local: "issue"}}, {}, {Name: xml.Name{Space: "f", Local: "prefixed"}},
 		}}, []string{
 			fmt.Sprintf("go3mf: XPath: /model/metadata[1]: %v", errors.ErrMetadataNamespace),
 			fmt.Sprintf("go3mf: XPath: /model/metadata[2]: %v", errors.ErrMetadataDuplicated),
@@ -123,7 +123,7 @@ func TestValidate(t *testing.T) {
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[4]/mesh/triangle[0]: %v", errors.ErrDuplicatedIndices),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[4]/mesh/triangle[1]: %v", errors.ErrDuplicatedIndices),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[4]/mesh/triangle[2]: %v", errors.ErrDuplicatedIndices),
-			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]: %v", errors.ErrIndexOutOfBounds),
+			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]: %v", &errors.PIndexError{ObjectID: 6, PID: 5, PIndex: 2, Len: 2}),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]/mesh/triangle[0]: %v", errors.ErrIndexOutOfBounds),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]/mesh/triangle[1]: %v", errors.ErrIndexOutOfBounds),
 			fmt.Sprintf("go3mf: XPath: /model/resources/object[5]/mesh/triangle[3]: %v", errors.ErrMissingResource),
@@ -186,6 +186,43 @@ func TestObject_ValidateMesh(t *testing.T) {
 	}
 }
 
+func TestMesh_ValidateDegenerate(t *testing.T) {
+	m := &Mesh{Vertices: Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {2, 0, 0}, {0, 1, 0}, {0, 0.001, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{
+			{V1: 0, V2: 1, V3: 3}, {V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 0, V3: 1},
+			{V1: 0, V2: 1, V3: 9}, {V1: 0, V2: 1, V3: 4},
+		}}}
+	tests := []struct {
+		name    string
+		epsilon float64
+		want    []string
+	}{
+		{"zero", 0, []string{fmt.Sprintf("go3mf: XPath: /triangle[1]: %v", errors.ErrDegenerateTriangle)}},
+		{"epsilon", 1e-3, []string{
+			fmt.Sprintf("go3mf: XPath: /triangle[1]: %v", errors.ErrDegenerateTriangle),
+			fmt.Sprintf("go3mf: XPath: /triangle[4]: %v", errors.ErrDegenerateTriangle),
+		}},
+		{"big", 1, []string{
+			fmt.Sprintf("go3mf: XPath: /triangle[0]: %v", errors.ErrDegenerateTriangle),
+			fmt.Sprintf("go3mf: XPath: /triangle[1]: %v", errors.ErrDegenerateTriangle),
+			fmt.Sprintf("go3mf: XPath: /triangle[4]: %v", errors.ErrDegenerateTriangle),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []string
+			if err := m.ValidateDegenerate(tt.epsilon); err != nil {
+				for _, err := range err.(*errors.List).Errors {
+					errs = append(errs, err.Error())
+				}
+			}
+			if diff := deep.Equal(errs, tt.want); diff != nil {
+				t.Errorf("Mesh.ValidateDegenerate() = %v", diff)
+			}
+		})
+	}
+}
+
 func TestModel_ValidateCoherency(t *testing.T) {
 	validMesh := &Mesh{Vertices: Vertices{Vertex: []Point3D{{}, {}, {}, {}}}, Triangles: Triangles{Triangle: []Triangle{
 		{V1: 0, V2: 1, V3: 2}, {V1: 0, V2: 3, V3: 1},
@@ -239,3 +276,168 @@ func TestModel_ValidateCoherency(t *testing.T) {
 		})
 	}
 }
+
+type recorderSpec struct {
+	calls []string
+}
+
+func (*recorderSpec) NewAttrGroup(xml.Name) spec.AttrGroup { return nil }
+
+func (*recorderSpec) NewElementDecoder(xml.Name) spec.GetterElementDecoder { return nil }
+
+func (r *recorderSpec) Validate(_ interface{}, path string, element interface{}) error {
+	var call string
+	switch e := element.(type) {
+	case *Model:
+		call = "model"
+	case Asset:
+		call = fmt.Sprintf("asset %d", e.Identify())
+	case *Object:
+		call = fmt.Sprintf("object %d", e.ID)
+	}
+	r.calls = append(r.calls, fmt.Sprintf("%s %s", call, path))
+	if _, ok := element.(*Object); ok {
+		return fmt.Errorf("recorder")
+	}
+	return nil
+}
+
+func TestModel_Validate_Spec(t *testing.T) {
+	recorder := new(recorderSpec)
+	spec.Register("http://dummy.com/recorder", recorder)
+	mesh := &Mesh{
+		Vertices:  Vertices{Vertex: []Point3D{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}},
+		Triangles: Triangles{Triangle: []Triangle{{V1: 0, V2: 1, V3: 2}}},
+	}
+	model := &Model{
+		Extensions: []Extension{{Namespace: "http://dummy.com/recorder", LocalName: "r"}},
+		Resources: Resources{
+			Assets:  []Asset{&BaseMaterials{ID: 1, Materials: []Base{{Name: "a", Color: color.RGBA{A: 255}}}}},
+			Objects: []*Object{{ID: 2, Mesh: mesh}},
+		},
+		Childs: map[string]*ChildModel{
+			"/b.model": {Resources: Resources{Objects: []*Object{{ID: 4, Mesh: mesh}}}},
+			"/a.model": {Resources: Resources{Objects: []*Object{{ID: 3, Mesh: mesh}}}},
+		},
+	}
+	err := model.Validate()
+	want := []string{"model ", "object 3 /a.model", "object 4 /b.model", "asset 1 /3D/3dmodel.model", "object 2 /3D/3dmodel.model"}
+	if diff := deep.Equal(recorder.calls, want); diff != nil {
+		t.Errorf("Model.Validate() calls = %v", diff)
+	}
+	var errs []string
+	if err != nil {
+		for _, err := range err.(*errors.List).Errors {
+			errs = append(errs, err.Error())
+		}
+	}
+	wantErrs := []string{
+		fmt.Sprintf("go3mf: Path: /a.model XPath: /model/resources/object[0]/mesh: %v", errors.ErrInsufficientTriangles),
+		"go3mf: Path: /a.model XPath: /model/resources/object[0]: recorder",
+		fmt.Sprintf("go3mf: Path: /b.model XPath: /model/resources/object[0]/mesh: %v", errors.ErrInsufficientTriangles),
+		"go3mf: Path: /b.model XPath: /model/resources/object[0]: recorder",
+		fmt.Sprintf("go3mf: XPath: /model/resources/object[0]/mesh: %v", errors.ErrInsufficientTriangles),
+		"go3mf: XPath: /model/resources/object[0]: recorder",
+	}
+	if diff := deep.Equal(errs, wantErrs); diff != nil {
+		t.Errorf("Model.Validate() = %v", diff)
+	}
+}
+
+func Test_validatePartName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"/3D/3dmodel.model", false},
+		{"/Metadata/thumbnail%20image.png", false},
+		{"/3D/Texture/ñ.png", false},
+		{"/a/b!$&'()*+,;=:@-_~.c", false},
+		{"", true},
+		{"3D/3dmodel.model", true},
+		{"/3D/", true},
+		{"/3D//a.model", true},
+		{"/3D/./a.model", true},
+		{"/3D/../a.model", true},
+		{"/3D/a.", true},
+		{"/3D/my texture.png", true},
+		{"/3D\\Texture\\a.png", true},
+		{"/3D/a%2Fb.png", true},
+		{"/3D/a%5cb.png", true},
+		{"/3D/a%41.png", true},
+		{"/3D/a%2.png", true},
+		{"/3D/a%zz.png", true},
+		{"/3D/a?b.png", true},
+		{"/3D/a#b.png", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePartName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validatePartName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(*errors.PartNameError); err != nil && !ok {
+				t.Errorf("validatePartName() error = %v, want *errors.PartNameError", err)
+			}
+		})
+	}
+}
+
+func TestModel_ValidateOPC(t *testing.T) {
+	model := &Model{
+		Path:        "/3D/model.model",
+		Childs:      map[string]*ChildModel{"/3D/other.model": {}, "/3D/bad.model.": {}},
+		Attachments: []Attachment{{Path: "/Metadata/thumbnail.png"}, {Path: "/Metadata/my thumbnail.png"}},
+	}
+	want := []string{
+		`go3mf: Path: /3D/bad.model. XPath: /model: part name "/3D/bad.model." has a segment ending with a dot`,
+		`go3mf: XPath: /attachment[1]: part name "/Metadata/my thumbnail.png" contains the invalid character ' '`,
+	}
+	err := model.ValidateOPC()
+	if err == nil {
+		t.Fatal("Model.ValidateOPC() err nil")
+	}
+	var errs []string
+	for _, err := range err.(*errors.List).Errors {
+		errs = append(errs, err.Error())
+	}
+	if diff := deep.Equal(errs, want); diff != nil {
+		t.Errorf("Model.ValidateOPC() = %v", diff)
+	}
+	if err := new(Model).ValidateOPC(); err != nil {
+		t.Errorf("Model.ValidateOPC() = %v", err)
+	}
+}
+
+func TestModel_ValidateTransforms(t *testing.T) {
+	shear := Matrix{1, 0, 0, 0, 0.5, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	model := &Model{
+		Resources: Resources{Objects: []*Object{
+			{ID: 1, Mesh: new(Mesh)},
+			{ID: 2, Components: &Components{Component: []*Component{{ObjectID: 1}, {ObjectID: 1, Transform: shear}}}},
+		}},
+		Build: Build{Items: []*Item{{ObjectID: 2}, {ObjectID: 2, Transform: shear}, {ObjectID: 1, Transform: Identity().Translate(1, 0, 0)}}},
+		Childs: map[string]*ChildModel{"/other.model": {Resources: Resources{Objects: []*Object{
+			{ID: 1, Components: &Components{Component: []*Component{{ObjectID: 1, Transform: shear}}}},
+		}}}},
+	}
+	want := []string{
+		fmt.Sprintf("go3mf: Path: /other.model XPath: /model/resources/object[0]/components/component[0]: %v", errors.ErrNonRigidTransform),
+		fmt.Sprintf("go3mf: XPath: /model/resources/object[1]/components/component[1]: %v", errors.ErrNonRigidTransform),
+		fmt.Sprintf("go3mf: XPath: /model/build/item[1]: %v", errors.ErrNonRigidTransform),
+	}
+	err := model.ValidateTransforms()
+	if err == nil {
+		t.Fatal("Model.ValidateTransforms() err nil")
+	}
+	var errs []string
+	for _, err := range err.(*errors.List).Errors {
+		errs = append(errs, err.Error())
+	}
+	if diff := deep.Equal(errs, want); diff != nil {
+		t.Errorf("Model.ValidateTransforms() = %v", diff)
+	}
+	if err := new(Model).ValidateTransforms(); err != nil {
+		t.Errorf("Model.ValidateTransforms() = %v", err)
+	}
+}